@@ -0,0 +1,591 @@
+// Package analyze guesses the layout of undocumented QDataStream blobs.
+//
+// Plausible decodings are tried at every offset: QString lengths followed by valid UTF-16,
+// QVariant metatype ids followed by a null flag and a valid value, Julian day dates,
+// container counts followed by their elements and so on. Every decoding is scored
+// by the evidence it gives, and the sequence of decodings covering the whole blob
+// with the best total score is proposed as its layout:
+//
+//	layout, err := analyze.Analyze(data, analyze.Options{Version: cutestream.VersionQt5_12})
+//	for _, f := range layout.Fields {
+//	    fmt.Println(f.Offset, f.Type, f.Value)
+//	}
+//	scanner := cutestream.NewRecordScanner(&reader, layout.Schema().Decode)
+//
+// Every offset is probed and the analysis time grows linearly with the size of the blob,
+// from about a second to several seconds per megabyte.
+package analyze
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"math"
+	"sort"
+	"unicode"
+	"unicode/utf16"
+
+	"github.com/race-engineering-center/cutestream"
+)
+
+// Options describe the stream the blob is expected to be written with
+type Options struct {
+	Version         int              // QDataStream version, cutestream.VersionQt5_13 if 0
+	ByteOrder       binary.ByteOrder // Big endian if nil
+	DoublePrecision bool             // Whether floating point values are written in double precision
+	MaxDepth        int              // Maximum nesting of QVariant containers, 8 if 0
+}
+
+// Candidate is a plausible decoding of a value at an offset of the blob
+type Candidate struct {
+	Offset int
+	Size   int
+	Type   cutestream.QMetaType // QMetaTypeQVariant for values wrapped into a QVariant, QMetaTypeUChar for unexplained bytes
+	Value  interface{}
+	Score  float64
+}
+
+// Layout is the proposed decoding of a blob
+type Layout struct {
+	Fields []Candidate
+	Score  float64
+}
+
+// Schema returns the layout as a schema for cutestream.Schema.Decode.
+// Fields are named after their offsets, e.g. "offset16"
+func (l Layout) Schema() cutestream.Schema {
+	schema := make(cutestream.Schema, len(l.Fields))
+	for i, f := range l.Fields {
+		schema[i] = cutestream.Field{Name: fmt.Sprintf("offset%d", f.Offset), Type: f.Type}
+	}
+	return schema
+}
+
+// Scores of the decodings. Values which any 4 bytes can be decoded to are weak evidence,
+// values with a constrained encoding like strings, dates and QVariants are strong evidence
+const (
+	scoreUnexplained = -1.0 // a byte no decoding explains
+	scoreBool        = 0.1
+	scoreInt         = 0.25
+	scoreSmallInt    = 0.5 // magnitude up to smallInt
+	scoreLongLong    = 0.2
+	scoreTimestamp   = 1.0 // qint64 milliseconds since epoch in 2001-2100
+	scoreReal        = 1.0 // floating point value of a plausible magnitude
+	scoreEmpty       = 0.3 // empty or null strings, byte arrays and containers
+	scoreByteArray   = 0.5
+	scoreString      = 1.0
+	scoreChar        = 0.5 // per printable character of a string
+	scoreTime        = 0.3
+	scoreDate        = 3.0 // Julian day in 1900-2100
+	scoreDateTime    = 6.0
+	scoreContainer   = 1.0
+	scoreVariant     = 2.0
+	scoreInvalid     = 1.0 // invalid QVariant
+)
+
+const (
+	smallInt       = 1000000
+	julianDay1900  = 2415021
+	julianDay2100  = 2488434
+	timestamp2001  = 978307200000
+	timestamp2100  = 4102444800000
+	msecsPerDay    = 86400000
+	maxUTCOffset   = 14 * 3600
+	printableRatio = 0.9
+	// unprintable characters a prefix of a string may have beyond the printable ratio
+	unprintableSlack = 8
+)
+
+// topLevelTypes are the metatypes probed at every offset. Other metatypes are only
+// accepted inside QVariants, where the metatype is known: the bytes of an unsigned or
+// single precision value are as plausible as those of a signed or double precision one,
+// and any zero or one byte is a bool
+var topLevelTypes = []cutestream.QMetaType{
+	cutestream.QMetaTypeQVariant,
+	cutestream.QMetaTypeQDateTime,
+	cutestream.QMetaTypeQDate,
+	cutestream.QMetaTypeQString,
+	cutestream.QMetaTypeQStringList,
+	cutestream.QMetaTypeQVariantList,
+	cutestream.QMetaTypeQVariantMap,
+	cutestream.QMetaTypeQByteArray,
+	cutestream.QMetaTypeDouble,
+	cutestream.QMetaTypeLongLong,
+	cutestream.QMetaTypeInt,
+}
+
+// variantTypes are the metatypes accepted as QVariant values
+var variantTypes = map[cutestream.QMetaType]bool{
+	cutestream.QMetaTypeUnknown:      true,
+	cutestream.QMetaTypeBool:         true,
+	cutestream.QMetaTypeInt:          true,
+	cutestream.QMetaTypeUInt:         true,
+	cutestream.QMetaTypeLongLong:     true,
+	cutestream.QMetaTypeULongLong:    true,
+	cutestream.QMetaTypeDouble:       true,
+	cutestream.QMetaTypeFloat:        true,
+	cutestream.QMetaTypeQString:      true,
+	cutestream.QMetaTypeQByteArray:   true,
+	cutestream.QMetaTypeQStringList:  true,
+	cutestream.QMetaTypeQDate:        true,
+	cutestream.QMetaTypeQTime:        true,
+	cutestream.QMetaTypeQDateTime:    true,
+	cutestream.QMetaTypeQVariantList: true,
+	cutestream.QMetaTypeQVariantMap:  true,
+	cutestream.QMetaTypeQVariantHash: true,
+}
+
+// Analyze proposes the layout of a blob: the sequence of decodings covering it
+// with the best total score. Bytes no decoding explains are proposed as QMetaTypeUChar
+func Analyze(data []byte, opts Options) (Layout, error) {
+	p, err := newProber(data, opts)
+	if err != nil {
+		return Layout{}, err
+	}
+	// best[i] is the best score of a layout of data[i:], reached by choice[i] at offset i
+	best := make([]float64, len(data)+1)
+	choice := make([]Candidate, len(data))
+	for i := len(data) - 1; i >= 0; i-- {
+		choice[i] = p.unexplained(i)
+		best[i] = choice[i].Score + best[i+1]
+		for _, c := range p.candidates(i) {
+			if score := c.Score + best[i+c.Size]; score > best[i] {
+				best[i], choice[i] = score, c
+			}
+		}
+	}
+	layout := Layout{Score: best[0]}
+	for i := 0; i < len(data); i += choice[i].Size {
+		layout.Fields = append(layout.Fields, choice[i])
+	}
+	return layout, nil
+}
+
+// Candidates returns the plausible decodings of the value at an offset, the best scored first
+func Candidates(data []byte, offset int, opts Options) ([]Candidate, error) {
+	p, err := newProber(data, opts)
+	if err != nil {
+		return nil, err
+	}
+	if offset < 0 || offset >= len(data) {
+		return nil, fmt.Errorf("offset %d is out of range", offset)
+	}
+	candidates := p.candidates(offset)
+	sort.SliceStable(candidates, func(i, j int) bool {
+		return candidates[i].Score > candidates[j].Score
+	})
+	return candidates, nil
+}
+
+// prober tries decodings of the blob with the Reader primitives.
+// Lengths and counts are checked against the remaining data before reading,
+// so that garbage doesn't lead to large allocations
+type prober struct {
+	data   []byte
+	opts   Options
+	probed map[probeKey]probeResult // decodings of container elements
+}
+
+// probeKey identifies a decoding of an element: containers at different offsets share their elements
+type probeKey struct {
+	offset int
+	t      cutestream.QMetaType
+	depth  int
+}
+
+type probeResult struct {
+	c  Candidate
+	ok bool
+}
+
+func newProber(data []byte, opts Options) (*prober, error) {
+	if opts.Version == 0 {
+		opts.Version = cutestream.VersionQt5_13
+	}
+	if opts.ByteOrder == nil {
+		opts.ByteOrder = binary.BigEndian
+	}
+	if opts.MaxDepth == 0 {
+		opts.MaxDepth = 8
+	}
+	// validate the version once, readers are created for every probe
+	if _, err := cutestream.NewReaderWithVersion(nil, opts.Version); err != nil {
+		return nil, err
+	}
+	return &prober{data: data, opts: opts, probed: make(map[probeKey]probeResult)}, nil
+}
+
+func (p *prober) candidates(offset int) []Candidate {
+	var candidates []Candidate
+	for _, t := range topLevelTypes {
+		if c, ok := p.probe(offset, t, 0); ok {
+			candidates = append(candidates, c)
+		}
+	}
+	return candidates
+}
+
+func (p *prober) unexplained(offset int) Candidate {
+	return Candidate{Offset: offset, Size: 1, Type: cutestream.QMetaTypeUChar, Value: p.data[offset], Score: scoreUnexplained}
+}
+
+// reader returns a Reader over the data starting at offset and the underlying reader
+// to find out how many bytes were read
+func (p *prober) reader(offset int) (*cutestream.Reader, *bytes.Reader) {
+	src := bytes.NewReader(p.data[offset:])
+	r, _ := cutestream.NewReaderWithVersion(src, p.opts.Version)
+	r.ByteOrder = p.opts.ByteOrder
+	r.DoublePrecision = p.opts.DoublePrecision
+	return &r, src
+}
+
+// uint32 returns the 32-bit value at offset if there is one
+func (p *prober) uint32(offset int) (uint32, bool) {
+	if offset < 0 || len(p.data)-offset < 4 {
+		return 0, false
+	}
+	return p.opts.ByteOrder.Uint32(p.data[offset:]), true
+}
+
+// read decodes a value with a Reader primitive and returns it as a candidate with the specified score
+func read[T any](p *prober, offset int, t cutestream.QMetaType, readValue func(*cutestream.Reader) (T, error)) (Candidate, bool) {
+	r, src := p.reader(offset)
+	v, err := readValue(r)
+	if err != nil {
+		return Candidate{}, false
+	}
+	return Candidate{Offset: offset, Size: len(p.data) - offset - src.Len(), Type: t, Value: v}, true
+}
+
+// probe tries to decode a value of the metatype at offset
+func (p *prober) probe(offset int, t cutestream.QMetaType, depth int) (Candidate, bool) {
+	// top-level values are probed once per offset, only container elements are worth memoizing
+	if depth == 0 {
+		return p.decode(offset, t, depth)
+	}
+	key := probeKey{offset, t, depth}
+	if r, ok := p.probed[key]; ok {
+		return r.c, r.ok
+	}
+	c, ok := p.decode(offset, t, depth)
+	p.probed[key] = probeResult{c, ok}
+	return c, ok
+}
+
+// decode decodes a value of the metatype at offset without memoizing it
+func (p *prober) decode(offset int, t cutestream.QMetaType, depth int) (Candidate, bool) {
+	if offset >= len(p.data) && t != cutestream.QMetaTypeUnknown {
+		return Candidate{}, false
+	}
+	switch t {
+	case cutestream.QMetaTypeUnknown:
+		return Candidate{Offset: offset, Type: t}, true
+	case cutestream.QMetaTypeBool:
+		if p.data[offset] > 1 {
+			return Candidate{}, false
+		}
+		c, ok := read(p, offset, t, (*cutestream.Reader).ReadBool)
+		c.Score = scoreBool
+		return c, ok
+	case cutestream.QMetaTypeInt:
+		c, ok := read(p, offset, t, (*cutestream.Reader).ReadInt32)
+		c.Score = scoreInt
+		if v, _ := c.Value.(int32); v >= -smallInt && v <= smallInt {
+			c.Score = scoreSmallInt
+		}
+		return c, ok
+	case cutestream.QMetaTypeUInt:
+		c, ok := read(p, offset, t, (*cutestream.Reader).ReadUint32)
+		c.Score = scoreInt
+		if v, _ := c.Value.(uint32); v <= smallInt {
+			c.Score = scoreSmallInt
+		}
+		return c, ok
+	case cutestream.QMetaTypeLongLong:
+		c, ok := read(p, offset, t, (*cutestream.Reader).ReadInt64)
+		c.Score = scoreLongLong
+		if v, _ := c.Value.(int64); v >= timestamp2001 && v < timestamp2100 {
+			c.Score = scoreTimestamp
+		}
+		return c, ok
+	case cutestream.QMetaTypeULongLong:
+		c, ok := read(p, offset, t, (*cutestream.Reader).ReadUint64)
+		c.Score = scoreLongLong
+		return c, ok
+	case cutestream.QMetaTypeDouble:
+		c, ok := read(p, offset, t, (*cutestream.Reader).ReadDouble)
+		if !ok || !plausibleReal(c.Value.(float64)) {
+			return Candidate{}, false
+		}
+		c.Score = scoreReal
+		return c, true
+	case cutestream.QMetaTypeFloat:
+		c, ok := read(p, offset, t, (*cutestream.Reader).ReadFloat)
+		if !ok || !plausibleReal(float64(c.Value.(float32))) {
+			return Candidate{}, false
+		}
+		c.Score = scoreReal
+		return c, true
+	case cutestream.QMetaTypeQString:
+		return p.probeString(offset)
+	case cutestream.QMetaTypeQByteArray:
+		return p.probeByteArray(offset)
+	case cutestream.QMetaTypeQDate:
+		c, ok := read(p, offset, t, (*cutestream.Reader).ReadQDate)
+		if !ok || !p.plausibleDate(offset) {
+			return Candidate{}, false
+		}
+		c.Score = scoreDate
+		return c, true
+	case cutestream.QMetaTypeQTime:
+		if ms, ok := p.uint32(offset); !ok || ms >= msecsPerDay {
+			return Candidate{}, false
+		}
+		c, ok := read(p, offset, t, (*cutestream.Reader).ReadQTime)
+		c.Score = scoreTime
+		return c, ok
+	case cutestream.QMetaTypeQDateTime:
+		return p.probeDateTime(offset)
+	case cutestream.QMetaTypeQStringList:
+		return p.probeList(offset, t, depth, 4, func(offset int) (Candidate, bool) {
+			return p.probe(offset, cutestream.QMetaTypeQString, depth)
+		})
+	case cutestream.QMetaTypeQVariantList:
+		if depth >= p.opts.MaxDepth {
+			return Candidate{}, false
+		}
+		return p.probeList(offset, t, depth, 5, func(offset int) (Candidate, bool) {
+			return p.probe(offset, cutestream.QMetaTypeQVariant, depth+1)
+		})
+	case cutestream.QMetaTypeQVariantMap, cutestream.QMetaTypeQVariantHash:
+		if depth >= p.opts.MaxDepth {
+			return Candidate{}, false
+		}
+		return p.probeMap(offset, depth)
+	case cutestream.QMetaTypeQVariant:
+		if depth >= p.opts.MaxDepth {
+			return Candidate{}, false
+		}
+		return p.probeVariant(offset, depth)
+	default:
+		return Candidate{}, false
+	}
+}
+
+func plausibleReal(v float64) bool {
+	a := math.Abs(v)
+	return a >= 1e-4 && a <= 1e9
+}
+
+func (p *prober) plausibleDate(offset int) bool {
+	var jd int64
+	if p.opts.Version < cutestream.VersionQt5_0 {
+		v, _ := p.uint32(offset)
+		jd = int64(v)
+	} else {
+		jd = int64(p.opts.ByteOrder.Uint64(p.data[offset:]))
+	}
+	return jd >= julianDay1900 && jd <= julianDay2100
+}
+
+func (p *prober) probeString(offset int) (Candidate, bool) {
+	n, ok := p.uint32(offset)
+	if !ok {
+		return Candidate{}, false
+	}
+	if n == 0xFFFFFFFF || n == 0 {
+		c, ok := read(p, offset, cutestream.QMetaTypeQString, (*cutestream.Reader).ReadQString)
+		c.Score = scoreEmpty
+		return c, ok
+	}
+	if n%2 != 0 || uint64(n) > uint64(len(p.data)-offset-4) {
+		return Candidate{}, false
+	}
+	// the characters are checked in place and every prefix has to be mostly printable,
+	// so that a garbage length is rejected early rather than after decoding the whole string
+	units := p.data[offset+4 : offset+4+int(n)]
+	total, printable := 0, 0
+	for i := 0; i < len(units); i += 2 {
+		r := rune(p.opts.ByteOrder.Uint16(units[i:]))
+		if utf16.IsSurrogate(r) && i+4 <= len(units) {
+			r = utf16.DecodeRune(r, rune(p.opts.ByteOrder.Uint16(units[i+2:])))
+			i += 2
+		}
+		// unpaired surrogates are decoded as replacement characters
+		if r == unicode.ReplacementChar || utf16.IsSurrogate(r) {
+			return Candidate{}, false
+		}
+		total++
+		if unicode.IsPrint(r) || r == '\t' || r == '\n' || r == '\r' {
+			printable++
+		} else if float64(total-printable) > unprintableSlack+float64(total)*(1-printableRatio) {
+			return Candidate{}, false
+		}
+	}
+	ratio := float64(printable) / float64(total)
+	if ratio < printableRatio {
+		return Candidate{}, false
+	}
+	c, ok := read(p, offset, cutestream.QMetaTypeQString, (*cutestream.Reader).ReadQString)
+	if !ok {
+		return Candidate{}, false
+	}
+	c.Score = scoreString + scoreChar*float64(printable)*ratio
+	return c, true
+}
+
+func (p *prober) probeByteArray(offset int) (Candidate, bool) {
+	n, ok := p.uint32(offset)
+	if !ok || n != 0xFFFFFFFF && uint64(n) > uint64(len(p.data)-offset-4) {
+		return Candidate{}, false
+	}
+	if n == 0 || n == 0xFFFFFFFF {
+		c, ok := read(p, offset, cutestream.QMetaTypeQByteArray, (*cutestream.Reader).ReadQByteArray)
+		c.Score = scoreEmpty
+		return c, ok
+	}
+	// any bytes are a valid byte array, the value refers to the blob instead of copying it at every offset
+	end := offset + 4 + int(n)
+	return Candidate{Offset: offset, Size: 4 + int(n), Type: cutestream.QMetaTypeQByteArray, Value: p.data[offset+4 : end : end], Score: scoreByteArray}, true
+}
+
+func (p *prober) probeDateTime(offset int) (Candidate, bool) {
+	date, ok := p.probe(offset, cutestream.QMetaTypeQDate, 0)
+	if !ok {
+		return Candidate{}, false
+	}
+	if _, ok := p.probe(offset+date.Size, cutestream.QMetaTypeQTime, 0); !ok {
+		return Candidate{}, false
+	}
+	specOffset := offset + date.Size + 4
+	if specOffset >= len(p.data) {
+		return Candidate{}, false
+	}
+	switch spec := p.data[specOffset]; {
+	case spec > 2:
+		// time zones are not supported by ReadQDateTime
+		return Candidate{}, false
+	case spec == 2 && p.opts.Version >= cutestream.VersionQt5_2:
+		utcOffset, ok := p.uint32(specOffset + 1)
+		if !ok || int32(utcOffset) < -maxUTCOffset || int32(utcOffset) > maxUTCOffset {
+			return Candidate{}, false
+		}
+	}
+	c, ok := read(p, offset, cutestream.QMetaTypeQDateTime, (*cutestream.Reader).ReadQDateTime)
+	c.Score = scoreDateTime
+	return c, ok
+}
+
+// probeList decodes a count followed by elements of at least minSize bytes
+func (p *prober) probeList(offset int, t cutestream.QMetaType, depth, minSize int, probeElement func(offset int) (Candidate, bool)) (Candidate, bool) {
+	n, ok := p.uint32(offset)
+	if !ok || uint64(n)*uint64(minSize) > uint64(len(p.data)-offset-4) {
+		return Candidate{}, false
+	}
+	c := Candidate{Offset: offset, Size: 4, Type: t, Score: scoreContainer}
+	if n == 0 {
+		c.Score = scoreEmpty
+	}
+	var strings []string
+	var variants []interface{}
+	for i := uint32(0); i < n; i++ {
+		e, ok := probeElement(offset + c.Size)
+		if !ok {
+			return Candidate{}, false
+		}
+		c.Size += e.Size
+		c.Score += e.Score
+		if t == cutestream.QMetaTypeQStringList {
+			strings = append(strings, e.Value.(string))
+		} else {
+			variants = append(variants, e.Value)
+		}
+	}
+	if t == cutestream.QMetaTypeQStringList {
+		c.Value = strings
+	} else {
+		c.Value = variants
+	}
+	return c, true
+}
+
+// probeMap decodes a QVariantMap, which is reported as QVariantHash if the keys
+// are not in the order QMap streams them
+func (p *prober) probeMap(offset, depth int) (Candidate, bool) {
+	n, ok := p.uint32(offset)
+	if !ok || uint64(n)*9 > uint64(len(p.data)-offset-4) {
+		return Candidate{}, false
+	}
+	c := Candidate{Offset: offset, Size: 4, Type: cutestream.QMetaTypeQVariantMap, Score: scoreContainer}
+	if n == 0 {
+		c.Score = scoreEmpty
+	}
+	var keys []string
+	var values []interface{}
+	for i := uint32(0); i < n; i++ {
+		k, ok := p.probe(offset+c.Size, cutestream.QMetaTypeQString, depth)
+		if !ok {
+			return Candidate{}, false
+		}
+		v, ok := p.probe(offset+c.Size+k.Size, cutestream.QMetaTypeQVariant, depth+1)
+		if !ok {
+			return Candidate{}, false
+		}
+		c.Size += k.Size + v.Size
+		c.Score += k.Score + v.Score
+		keys = append(keys, k.Value.(string))
+		values = append(values, v.Value)
+	}
+	// the map is built once the entries are decoded, a garbage count doesn't allocate it
+	m := make(map[string]interface{}, len(keys))
+	for i, k := range keys {
+		m[k] = values[i]
+	}
+	// QMap streams its keys in descending order before Qt 6 and in ascending order since then
+	ordered := sort.SliceIsSorted(keys, func(i, j int) bool {
+		if p.opts.Version < cutestream.VersionQt6_0 {
+			return keys[i] > keys[j]
+		}
+		return keys[i] < keys[j]
+	})
+	if !ordered {
+		c.Type = cutestream.QMetaTypeQVariantHash
+	}
+	c.Value = m
+	return c, true
+}
+
+func (p *prober) probeVariant(offset, depth int) (Candidate, bool) {
+	id, ok := p.uint32(offset)
+	// user types are not probed, their ids are 127, 1024 and 65536
+	if !ok || id >= 127 {
+		return Candidate{}, false
+	}
+	r, src := p.reader(offset)
+	h, err := r.ReadQVariantHeader()
+	if err != nil || !variantTypes[h.Type] {
+		return Candidate{}, false
+	}
+	headerSize := len(p.data) - offset - src.Len()
+	if headerSize == 5 && p.data[offset+4] > 1 {
+		return Candidate{}, false
+	}
+	value, ok := p.probe(offset+headerSize, h.Type, depth)
+	if !ok {
+		return Candidate{}, false
+	}
+	c := Candidate{
+		Offset: offset,
+		Size:   headerSize + value.Size,
+		Type:   cutestream.QMetaTypeQVariant,
+		Value:  cutestream.Variant{Type: h.Type, Value: value.Value},
+		Score:  scoreVariant + value.Score,
+	}
+	if h.Null {
+		c.Value = cutestream.Variant{Type: h.Type}
+	}
+	if h.Type == cutestream.QMetaTypeUnknown {
+		c.Score = scoreInvalid
+	}
+	return c, true
+}
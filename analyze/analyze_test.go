@@ -0,0 +1,136 @@
+package analyze
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/race-engineering-center/cutestream"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAnalyze(t *testing.T) {
+	start := time.Date(2023, 7, 30, 15, 0, 0, 0, time.UTC)
+	var buf bytes.Buffer
+	w, _ := cutestream.NewWriterWithVersion(&buf, cutestream.VersionQt5_12)
+	w.DoublePrecision = true
+	assert.Nil(t, w.WriteInt32(3))
+	assert.Nil(t, w.WriteQString("Spa-Francorchamps"))
+	assert.Nil(t, w.WriteQDateTime(start))
+	assert.Nil(t, w.WriteDouble(81.234))
+	assert.Nil(t, w.WriteQStringQStringList([]string{"Verstappen", "Hamilton"}))
+	assert.Nil(t, w.WriteQVariant(cutestream.QMetaTypeQVariantMap, map[string]interface{}{
+		"lap":    int32(12),
+		"driver": "Leclerc",
+	}))
+	data := buf.Bytes()
+
+	layout, err := Analyze(data, Options{Version: cutestream.VersionQt5_12, DoublePrecision: true})
+	assert.Nil(t, err)
+	var types []cutestream.QMetaType
+	for _, f := range layout.Fields {
+		types = append(types, f.Type)
+	}
+	assert.Equal(t, []cutestream.QMetaType{
+		cutestream.QMetaTypeInt,
+		cutestream.QMetaTypeQString,
+		cutestream.QMetaTypeQDateTime,
+		cutestream.QMetaTypeDouble,
+		cutestream.QMetaTypeQStringList,
+		cutestream.QMetaTypeQVariant,
+	}, types)
+	assert.Equal(t, 0, layout.Fields[0].Offset)
+	assert.Equal(t, 4, layout.Fields[1].Offset)
+
+	// the proposed schema decodes the blob
+	r, _ := cutestream.NewReaderWithVersion(bytes.NewReader(data), cutestream.VersionQt5_12)
+	r.DoublePrecision = true
+	record, err := layout.Schema().Decode(&r)
+	assert.Nil(t, err)
+	assert.Equal(t, map[string]interface{}{
+		"offset0":  int32(3),
+		"offset4":  "Spa-Francorchamps",
+		"offset42": start,
+		"offset55": 81.234,
+		"offset63": []string{"Verstappen", "Hamilton"},
+		"offset111": cutestream.Variant{Type: cutestream.QMetaTypeQVariantMap, Value: map[string]interface{}{
+			"lap":    int32(12),
+			"driver": "Leclerc",
+		}},
+	}, record)
+}
+
+func TestAnalyzeUnexplained(t *testing.T) {
+	layout, err := Analyze([]byte{0, 0, 0x10, 0, 0x7f, 0x7e}, Options{})
+	assert.Nil(t, err)
+	assert.Equal(t, []Candidate{
+		{Offset: 0, Size: 4, Type: cutestream.QMetaTypeInt, Value: int32(4096), Score: scoreSmallInt},
+		{Offset: 4, Size: 1, Type: cutestream.QMetaTypeUChar, Value: uint8(0x7f), Score: scoreUnexplained},
+		{Offset: 5, Size: 1, Type: cutestream.QMetaTypeUChar, Value: uint8(0x7e), Score: scoreUnexplained},
+	}, layout.Fields)
+	assert.Equal(t, cutestream.Schema{
+		{Name: "offset0", Type: cutestream.QMetaTypeInt},
+		{Name: "offset4", Type: cutestream.QMetaTypeUChar},
+		{Name: "offset5", Type: cutestream.QMetaTypeUChar},
+	}, layout.Schema())
+}
+
+func TestCandidates(t *testing.T) {
+	// QVariant(int(7)) with Qt 5
+	data := []byte{0, 0, 0, 2, 0, 0, 0, 0, 7}
+	candidates, err := Candidates(data, 0, Options{})
+	assert.Nil(t, err)
+	assert.Equal(t, cutestream.QMetaTypeQVariant, candidates[0].Type)
+	assert.Equal(t, cutestream.Variant{Type: cutestream.QMetaTypeInt, Value: int32(7)}, candidates[0].Value)
+	assert.Equal(t, 9, candidates[0].Size)
+
+	_, err = Candidates(data, len(data), Options{})
+	assert.NotNil(t, err)
+	_, err = Analyze(data, Options{Version: 1})
+	assert.NotNil(t, err)
+}
+
+func TestAnalyzeGarbage(t *testing.T) {
+	// huge counts and lengths must not allocate or take long
+	data := bytes.Repeat([]byte{0x7f, 0xff, 0xff, 0xf0}, 256)
+	layout, err := Analyze(data, Options{})
+	assert.Nil(t, err)
+	size := 0
+	for _, f := range layout.Fields {
+		size += f.Size
+	}
+	assert.Equal(t, len(data), size)
+}
+
+func TestAnalyzeStructured(t *testing.T) {
+	// QVariant(QVariantList) headers and counts at every offset must not take quadratic time
+	data := bytes.Repeat([]byte{0, 0, 0, 9, 0, 0, 0, 0, 1}, 64<<10/9)
+	layout, err := Analyze(data, Options{})
+	assert.Nil(t, err)
+	size := 0
+	for _, f := range layout.Fields {
+		size += f.Size
+	}
+	assert.Equal(t, len(data), size)
+}
+
+func TestCandidatesString(t *testing.T) {
+	// QString("a😀") with a surrogate pair
+	data := []byte{0, 0, 0, 6, 0, 'a', 0xd8, 0x3d, 0xde, 0x00}
+	candidates, err := Candidates(data, 0, Options{})
+	assert.Nil(t, err)
+	assert.Equal(t, cutestream.QMetaTypeQString, candidates[0].Type)
+	assert.Equal(t, "a😀", candidates[0].Value)
+
+	// an unpaired surrogate and a string starting with unprintable characters are rejected
+	for _, data := range [][]byte{
+		{0, 0, 0, 4, 0, 'a', 0xd8, 0x3d},
+		append([]byte{0, 0, 0, 200}, append(make([]byte, 20), bytes.Repeat([]byte{0, 'a'}, 90)...)...),
+	} {
+		candidates, err = Candidates(data, 0, Options{})
+		assert.Nil(t, err)
+		for _, c := range candidates {
+			assert.NotEqual(t, cutestream.QMetaTypeQString, c.Type)
+		}
+	}
+}
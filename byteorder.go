@@ -0,0 +1,82 @@
+package cutestream
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+)
+
+// ErrUnknownByteOrder is returned when the byte order of a stream can't be detected
+var ErrUnknownByteOrder = errors.New("can't detect byte order")
+
+// ByteOrderDetector detects the byte order of a stream from its first 4 bytes
+type ByteOrderDetector func(header []byte) (binary.ByteOrder, error)
+
+// MagicByteOrder detects the byte order of a stream starting with the specified 32-bit magic number
+func MagicByteOrder(magic uint32) ByteOrderDetector {
+	return func(header []byte) (binary.ByteOrder, error) {
+		if len(header) < 4 {
+			return nil, ErrUnknownByteOrder
+		}
+		switch magic {
+		case binary.BigEndian.Uint32(header):
+			return binary.BigEndian, nil
+		case binary.LittleEndian.Uint32(header):
+			return binary.LittleEndian, nil
+		}
+		return nil, fmt.Errorf("%w: magic %08x not found", ErrUnknownByteOrder, magic)
+	}
+}
+
+// LengthByteOrder detects the byte order of a stream starting with a 32-bit length or count,
+// like a QString, a QByteArray or a container. The byte order giving a length that fits
+// into size bytes of the stream is chosen, or the one giving the smaller length if both fit
+// or size is negative (unknown). A length field with the same value in both byte orders is ambiguous
+func LengthByteOrder(size int64) ByteOrderDetector {
+	return func(header []byte) (binary.ByteOrder, error) {
+		if len(header) < 4 {
+			return nil, ErrUnknownByteOrder
+		}
+		big, little := binary.BigEndian.Uint32(header), binary.LittleEndian.Uint32(header)
+		if big == little {
+			return nil, fmt.Errorf("%w: length %08x is symmetric", ErrUnknownByteOrder, big)
+		}
+		fits := func(n uint32) bool {
+			return size < 0 || n == 0xFFFFFFFF || int64(n) <= size-4
+		}
+		switch {
+		case fits(big) && !fits(little):
+			return binary.BigEndian, nil
+		case fits(little) && !fits(big):
+			return binary.LittleEndian, nil
+		case !fits(big) && !fits(little):
+			return nil, fmt.Errorf("%w: length doesn't fit into %d bytes", ErrUnknownByteOrder, size)
+		}
+		// null markers aside, the smaller length is more plausible
+		if little == 0xFFFFFFFF || big < little && big != 0xFFFFFFFF {
+			return binary.BigEndian, nil
+		}
+		return binary.LittleEndian, nil
+	}
+}
+
+// DetectByteOrder sets the byte order of the reader from the first bytes of the stream
+// without consuming them. The underlying reader is wrapped into a bufio.Reader unless it is one
+func (r *Reader) DetectByteOrder(detect ByteOrderDetector) error {
+	buffered, ok := r.Reader.(*bufio.Reader)
+	if !ok {
+		buffered = bufio.NewReader(r.Reader)
+		r.Reader = buffered
+	}
+	header, err := buffered.Peek(4)
+	if err != nil && len(header) == 0 {
+		return err
+	}
+	order, err := detect(header)
+	if err != nil {
+		return err
+	}
+	r.ByteOrder = order
+	return nil
+}
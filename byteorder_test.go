@@ -0,0 +1,119 @@
+package cutestream
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"io"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLittleEndian(t *testing.T) {
+	spa := "06000000" + "530070006100" // "Spa"
+	for _, test := range []struct {
+		t     QMetaType
+		data  string
+		value interface{}
+	}{
+		{QMetaTypeQString, spa, "Spa"},
+		{QMetaTypeQString, "04000000" + "3cd8cedf", "\U0001f3ce"},
+		{QMetaTypeQChar, "ac20", QChar('€')},
+		{QMetaTypeQByteArray, "03000000" + "0001ff", []byte{0, 1, 0xff}},
+		{QMetaTypeQBitArray, "0a000000" + "0502", []bool{true, false, true, false, false, false, false, false, false, true}},
+		{QMetaTypeQStringList, "02000000" + spa + "00000000", []string{"Spa", ""}},
+		{QMetaTypeQByteArrayList, "02000000" + "0100000061" + "ffffffff", [][]byte{[]byte("a"), nil}},
+		{QMetaTypeQVariantList, "02000000" + "0a00000000" + spa + "0200000000" + "feffffff",
+			[]interface{}{"Spa", int32(-2)}},
+		{QMetaTypeQVariantMap, "01000000" + "06000000" + "6c0061007000" + "0200000000" + "03000000",
+			map[string]interface{}{"lap": int32(3)}},
+		{QMetaTypeQVariantHash, "01000000" + "06000000" + "6c0061007000" + "0a00000000" + spa,
+			map[string]interface{}{"lap": "Spa"}},
+		{QMetaTypeQUuid, "0b77c867" + "f144" + "0a41" + "ab9af9b5446f13ee",
+			QUuid{0x67, 0xc8, 0x77, 0x0b, 0x44, 0xf1, 0x41, 0x0a, 0xab, 0x9a, 0xf9, 0xb5, 0x44, 0x6f, 0x13, 0xee}},
+	} {
+		data, _ := hex.DecodeString(test.data)
+		reader := NewReader(bytes.NewReader(data))
+		reader.ByteOrder = binary.LittleEndian
+		v, err := reader.ReadValue(test.t)
+		assert.Nil(t, err, test.t)
+		assert.Equal(t, test.value, v, test.t)
+
+		var buf bytes.Buffer
+		writer := NewWriter(&buf)
+		writer.ByteOrder = binary.LittleEndian
+		assert.Nil(t, writer.WriteValue(test.t, v), test.t)
+		assert.Equal(t, data, buf.Bytes(), test.t)
+	}
+}
+
+func TestLittleEndianCString(t *testing.T) {
+	data, _ := hex.DecodeString("04000000" + hex.EncodeToString([]byte("Spa\x00")))
+	reader := NewReader(bytes.NewReader(data))
+	reader.ByteOrder = binary.LittleEndian
+	s, err := reader.ReadCString()
+	assert.Nil(t, err)
+	assert.Equal(t, "Spa\x00", s)
+
+	var buf bytes.Buffer
+	writer := NewWriter(&buf)
+	writer.ByteOrder = binary.LittleEndian
+	assert.Nil(t, writer.WriteCString(s))
+	assert.Equal(t, data, buf.Bytes())
+}
+
+func TestMagicByteOrder(t *testing.T) {
+	detect := MagicByteOrder(0xa0b0c0d0)
+	order, err := detect([]byte{0xa0, 0xb0, 0xc0, 0xd0})
+	assert.Nil(t, err)
+	assert.Equal(t, binary.BigEndian, order)
+	order, err = detect([]byte{0xd0, 0xc0, 0xb0, 0xa0})
+	assert.Nil(t, err)
+	assert.Equal(t, binary.LittleEndian, order)
+	_, err = detect([]byte{0, 0, 0, 0})
+	assert.True(t, errors.Is(err, ErrUnknownByteOrder))
+	_, err = detect([]byte{0xa0})
+	assert.True(t, errors.Is(err, ErrUnknownByteOrder))
+}
+
+func TestLengthByteOrder(t *testing.T) {
+	for _, test := range []struct {
+		header string
+		size   int64
+		order  binary.ByteOrder
+	}{
+		{"00000006", 10, binary.BigEndian},
+		{"06000000", 10, binary.LittleEndian},
+		{"00000006", -1, binary.BigEndian},
+		{"06000000", -1, binary.LittleEndian},
+		{"00000100", 0x10000, binary.BigEndian},
+		{"00010000", 0x10000, binary.LittleEndian},
+		{"ffffffff", 4, nil},
+		{"01000001", 100, nil},
+		{"01020304", 100, nil},
+	} {
+		header, _ := hex.DecodeString(test.header)
+		order, err := LengthByteOrder(test.size)(header)
+		if test.order == nil {
+			assert.True(t, errors.Is(err, ErrUnknownByteOrder), test.header)
+			continue
+		}
+		assert.Nil(t, err, test.header)
+		assert.Equal(t, test.order, order, test.header)
+	}
+}
+
+func TestDetectByteOrder(t *testing.T) {
+	data, _ := hex.DecodeString("06000000" + "530070006100")
+	reader := NewReader(bytes.NewReader(data))
+	assert.Nil(t, reader.DetectByteOrder(LengthByteOrder(int64(len(data)))))
+	assert.Equal(t, binary.LittleEndian, reader.ByteOrder)
+	s, err := reader.ReadQString()
+	assert.Nil(t, err)
+	assert.Equal(t, "Spa", s)
+
+	reader = NewReader(bytes.NewReader(nil))
+	assert.Equal(t, io.EOF, reader.DetectByteOrder(LengthByteOrder(-1)))
+}
@@ -0,0 +1,93 @@
+// Command json2qds converts JSON produced by qds2json back to a stream of QVariant values in QDataStream format.
+//
+// Usage:
+//
+//	json2qds [-plain] [-version 19] [-single] [-o output.qds] [input.json]
+//
+// By default the input is a typed document, which keeps the stream version and precision.
+// With -plain the input is a sequence of natural JSON values, the metatypes are deduced
+// from the JSON types and the stream is written with -version and -single
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/race-engineering-center/cutestream"
+	"github.com/race-engineering-center/cutestream/qjson"
+)
+
+func main() {
+	plain := flag.Bool("plain", false, "read natural JSON values without metatypes")
+	version := flag.Int("version", cutestream.VersionQt5_13, "QDataStream version of the output in plain mode")
+	single := flag.Bool("single", false, "write floating point values in single precision in plain mode")
+	output := flag.String("o", "", "output file, stdout by default")
+	flag.Parse()
+
+	if err := run(flag.Arg(0), *output, *version, !*single, *plain); err != nil {
+		fmt.Fprintln(os.Stderr, "json2qds:", err)
+		os.Exit(1)
+	}
+}
+
+func run(input, output string, version int, doublePrecision, plain bool) error {
+	in := io.Reader(os.Stdin)
+	if input != "" {
+		f, err := os.Open(input)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		in = f
+	}
+	out := os.Stdout
+	if output != "" {
+		f, err := os.Create(output)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		out = f
+	}
+	buffered := bufio.NewWriter(out)
+
+	decoder := json.NewDecoder(in)
+	if err := convert(decoder, buffered, version, doublePrecision, plain); err != nil {
+		return err
+	}
+	return buffered.Flush()
+}
+
+func convert(decoder *json.Decoder, out io.Writer, version int, doublePrecision, plain bool) error {
+	if !plain {
+		var d qjson.Document
+		if err := decoder.Decode(&d); err != nil {
+			return err
+		}
+		return qjson.WriteDocument(out, &d)
+	}
+
+	writer, err := cutestream.NewWriterWithVersion(out, version)
+	if err != nil {
+		return err
+	}
+	writer.DoublePrecision = doublePrecision
+	decoder.UseNumber()
+	for i := 0; ; i++ {
+		var v interface{}
+		err := decoder.Decode(&v)
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("value %d: %w", i, err)
+		}
+		if err := qjson.WritePlain(&writer, v); err != nil {
+			return fmt.Errorf("value %d: %w", i, err)
+		}
+	}
+}
@@ -0,0 +1,83 @@
+// Command qds2json converts a stream of QVariant values in QDataStream format to JSON.
+//
+// Usage:
+//
+//	qds2json [-version 19] [-single] [-plain] [-compact] [-o output.json] [input.qds]
+//
+// By default the typed representation is written, which json2qds converts back to the same stream.
+// With -plain every QVariant is written as a natural JSON value, one per line with -compact
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/race-engineering-center/cutestream"
+	"github.com/race-engineering-center/cutestream/qjson"
+)
+
+func main() {
+	version := flag.Int("version", cutestream.VersionQt5_13, "QDataStream version of the input")
+	single := flag.Bool("single", false, "floating point values are in single precision (QDataStream::SinglePrecision)")
+	plain := flag.Bool("plain", false, "write natural JSON values without metatypes")
+	compact := flag.Bool("compact", false, "don't indent the output")
+	output := flag.String("o", "", "output file, stdout by default")
+	flag.Parse()
+
+	if err := run(flag.Arg(0), *output, *version, !*single, *plain, *compact); err != nil {
+		fmt.Fprintln(os.Stderr, "qds2json:", err)
+		os.Exit(1)
+	}
+}
+
+func run(input, output string, version int, doublePrecision, plain, compact bool) error {
+	in := io.Reader(os.Stdin)
+	if input != "" {
+		f, err := os.Open(input)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		in = f
+	}
+	out := io.Writer(os.Stdout)
+	if output != "" {
+		f, err := os.Create(output)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		out = f
+	}
+
+	reader, err := cutestream.NewReaderWithVersion(bufio.NewReader(in), version)
+	if err != nil {
+		return err
+	}
+	reader.DoublePrecision = doublePrecision
+
+	encoder := json.NewEncoder(out)
+	if !compact {
+		encoder.SetIndent("", "  ")
+	}
+	if !plain {
+		d, err := qjson.ReadDocument(&reader)
+		if err != nil {
+			return err
+		}
+		return encoder.Encode(d)
+	}
+	s := cutestream.NewRecordScanner(&reader, func(r *cutestream.Reader) (interface{}, error) {
+		return qjson.ReadPlain(r)
+	})
+	for s.Next() {
+		if err := encoder.Encode(s.Record()); err != nil {
+			return err
+		}
+	}
+	return s.Err()
+}
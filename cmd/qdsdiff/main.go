@@ -0,0 +1,98 @@
+// Command qdsdiff reports semantic differences between two QDataStream files.
+//
+// Usage:
+//
+//	qdsdiff [-version 19] [-single] [-version-b 20] [-single-b=false] [-tolerance 1e-6] [-schema lap:int,time:double] a.qds b.qds
+//
+// The files are read as sequences of QVariants, or of records described by -schema.
+// -version-b and -single-b set the version and the precision of the second file if they differ from the first one.
+// Every difference is printed on its own line. The exit status is 0 if the files
+// decode to equal values, even if they are re-encoded, 1 if they differ and 2 on errors
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/race-engineering-center/cutestream"
+	"github.com/race-engineering-center/cutestream/diff"
+)
+
+func main() {
+	version := flag.Int("version", cutestream.VersionQt5_13, "QDataStream version of the files")
+	single := flag.Bool("single", false, "floating point values are in single precision (QDataStream::SinglePrecision)")
+	versionB := flag.Int("version-b", 0, "QDataStream version of the second file, -version if 0")
+	singleB := flag.Bool("single-b", false, "floating point values of the second file are in single precision, -single if not set")
+	tolerance := flag.Float64("tolerance", 0, "largest absolute difference of equal floating point values")
+	schema := flag.String("schema", "", "comma separated name:type fields of the records, QVariants by default")
+	flag.Parse()
+	if flag.NArg() != 2 {
+		fmt.Fprintln(os.Stderr, "usage: qdsdiff [flags] a.qds b.qds")
+		flag.PrintDefaults()
+		os.Exit(2)
+	}
+
+	opts := diff.Options{
+		Version:         *version,
+		DoublePrecision: !*single,
+		VersionB:        *versionB,
+		Tolerance:       *tolerance,
+	}
+	flag.Visit(func(f *flag.Flag) {
+		if f.Name == "single-b" {
+			doublePrecisionB := !*singleB
+			opts.DoublePrecisionB = &doublePrecisionB
+		}
+	})
+	differences, err := run(flag.Arg(0), flag.Arg(1), *schema, opts)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "qdsdiff:", err)
+		os.Exit(2)
+	}
+	status := 0
+	for _, d := range differences {
+		fmt.Println(d)
+		if d.Kind != diff.Reencoded {
+			status = 1
+		}
+	}
+	os.Exit(status)
+}
+
+func run(pathA, pathB, schema string, opts diff.Options) ([]diff.Difference, error) {
+	if schema != "" {
+		s, err := parseSchema(schema)
+		if err != nil {
+			return nil, err
+		}
+		opts.Decode = s.Decode
+	}
+	a, err := os.ReadFile(pathA)
+	if err != nil {
+		return nil, err
+	}
+	b, err := os.ReadFile(pathB)
+	if err != nil {
+		return nil, err
+	}
+	return diff.Bytes(a, b, opts)
+}
+
+// parseSchema parses fields like "lap:int,time:double", types are Qt metatype names
+func parseSchema(s string) (cutestream.Schema, error) {
+	var schema cutestream.Schema
+	for _, field := range strings.Split(s, ",") {
+		i := strings.LastIndex(field, ":")
+		if i < 0 {
+			return nil, fmt.Errorf("field %q has no type", field)
+		}
+		t, ok := cutestream.MetaTypeByName(field[i+1:])
+		if !ok {
+			return nil, fmt.Errorf("unknown type %q", field[i+1:])
+		}
+		schema = append(schema, cutestream.Field{Name: field[:i], Type: t})
+	}
+	return schema, nil
+}
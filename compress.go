@@ -0,0 +1,100 @@
+package cutestream
+
+import (
+	"bytes"
+	"compress/zlib"
+	"encoding/binary"
+	"fmt"
+	"io"
+)
+
+// QUncompress decompresses data produced by qCompress(): a 4-byte big endian uncompressed size
+// followed by a zlib stream. The uncompressed size is only a hint, like in qUncompress().
+// Data larger than maxSize bytes is rejected with ErrAllocLimit, 0 means no limit
+func QUncompress(data []byte, maxSize uint64) ([]byte, error) {
+	if len(data) <= 4 {
+		if len(data) < 4 || binary.BigEndian.Uint32(data) != 0 {
+			return nil, fmt.Errorf("corrupted qCompress data")
+		}
+		return []byte{}, nil
+	}
+	size := uint64(binary.BigEndian.Uint32(data))
+	if maxSize > 0 && size > maxSize {
+		return nil, fmt.Errorf("%w: uncompressed size %d", ErrAllocLimit, size)
+	}
+	zr, err := zlib.NewReader(bytes.NewReader(data[4:]))
+	if err != nil {
+		return nil, err
+	}
+	defer zr.Close()
+
+	var buf bytes.Buffer
+	if size < maxPrealloc {
+		buf.Grow(int(size))
+	}
+	src := io.Reader(zr)
+	if maxSize > 0 {
+		src = io.LimitReader(zr, int64(maxSize)+1)
+	}
+	if _, err := buf.ReadFrom(src); err != nil {
+		return nil, err
+	}
+	if maxSize > 0 && uint64(buf.Len()) > maxSize {
+		return nil, fmt.Errorf("%w: uncompressed data exceeds %d bytes", ErrAllocLimit, maxSize)
+	}
+	return buf.Bytes(), nil
+}
+
+// QCompress compresses data like qCompress() does. Level is a zlib compression level,
+// zlib.DefaultCompression (-1) is the default of qCompress()
+func QCompress(data []byte, level int) ([]byte, error) {
+	var buf bytes.Buffer
+	size := make([]byte, 4)
+	binary.BigEndian.PutUint32(size, uint32(len(data)))
+	buf.Write(size)
+	if len(data) == 0 {
+		return buf.Bytes(), nil
+	}
+	zw, err := zlib.NewWriterLevel(&buf, level)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := zw.Write(data); err != nil {
+		return nil, err
+	}
+	if err := zw.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// ReadQCompressed reads a byte array holding qCompress() data and returns a Reader over
+// the decompressed data with the byte order, version and options of the reader.
+// The returned Reader keeps the context but doesn't report progress, which counts compressed bytes
+func (r *Reader) ReadQCompressed() (Reader, error) {
+	data, err := r.ReadQByteArray()
+	if err != nil {
+		return Reader{}, err
+	}
+	data, err = QUncompress(data, r.MaxAllocSize)
+	if err != nil {
+		return Reader{}, err
+	}
+	return r.subReader(data), nil
+}
+
+// WriteQCompressed encodes data with the specified function using the byte order, version and
+// precision of the writer and writes it as a byte array compressed like qCompress() does
+func (w *Writer) WriteQCompressed(encode func(w *Writer) error) error {
+	var buf bytes.Buffer
+	uncompressed := *w
+	uncompressed.Writer = &buf
+	if err := encode(&uncompressed); err != nil {
+		return err
+	}
+	data, err := QCompress(buf.Bytes(), zlib.DefaultCompression)
+	if err != nil {
+		return err
+	}
+	return w.WriteQByteArray(data)
+}
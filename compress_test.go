@@ -0,0 +1,74 @@
+package cutestream
+
+import (
+	"bytes"
+	"encoding/hex"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// QByteArray holding qCompress() of a stream with QString("Spa") and qint32(3)
+const qCompressedHex = "0000001a" + "0000000e" + "789c636060606308662860486460606006000833012e"
+
+func TestReadQCompressed(t *testing.T) {
+	data, _ := hex.DecodeString(qCompressedHex)
+	reader, err := NewReaderWithVersion(bytes.NewReader(data), VersionQt6_0)
+	assert.Nil(t, err)
+	reader.DoublePrecision = true
+	uncompressed, err := reader.ReadQCompressed()
+	assert.Nil(t, err)
+	assert.Equal(t, VersionQt6_0, uncompressed.Version())
+	assert.True(t, uncompressed.DoublePrecision)
+	s, err := uncompressed.ReadQString()
+	assert.Nil(t, err)
+	assert.Equal(t, "Spa", s)
+	i, err := uncompressed.ReadInt32()
+	assert.Nil(t, err)
+	assert.Equal(t, int32(3), i)
+
+	var buf bytes.Buffer
+	writer := NewWriter(&buf)
+	assert.Nil(t, writer.WriteQCompressed(func(w *Writer) error {
+		if err := w.WriteQString("Spa"); err != nil {
+			return err
+		}
+		return w.WriteInt32(3)
+	}))
+	reader = NewReader(&buf)
+	uncompressed, err = reader.ReadQCompressed()
+	assert.Nil(t, err)
+	s, err = uncompressed.ReadQString()
+	assert.Nil(t, err)
+	assert.Equal(t, "Spa", s)
+}
+
+func TestQUncompressLimit(t *testing.T) {
+	data, _ := hex.DecodeString(qCompressedHex)
+	reader := NewReader(bytes.NewReader(data))
+	reader.MaxAllocSize = 13
+	_, err := reader.ReadQCompressed()
+	assert.True(t, errors.Is(err, ErrAllocLimit))
+
+	// the declared size is understated, the limit applies to the actual data
+	compressed, _ := QCompress(make([]byte, 100), -1)
+	compressed[3] = 1
+	_, err = QUncompress(compressed, 50)
+	assert.True(t, errors.Is(err, ErrAllocLimit))
+	uncompressed, err := QUncompress(compressed, 0)
+	assert.Nil(t, err)
+	assert.Equal(t, make([]byte, 100), uncompressed)
+}
+
+func TestQCompressEmpty(t *testing.T) {
+	compressed, err := QCompress(nil, -1)
+	assert.Nil(t, err)
+	assert.Equal(t, []byte{0, 0, 0, 0}, compressed)
+	uncompressed, err := QUncompress(compressed, 0)
+	assert.Nil(t, err)
+	assert.Equal(t, []byte{}, uncompressed)
+
+	_, err = QUncompress([]byte{0, 0, 1}, 0)
+	assert.NotNil(t, err)
+}
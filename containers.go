@@ -0,0 +1,99 @@
+package cutestream
+
+import "sort"
+
+// ReadList reads a sequential container (QList, QVector, QSet, std::list etc.)
+// using the specified function to read the elements. When reading stops at a value
+// of an unknown type kept by KeepUnknownType, the elements read so far are returned, e.g.
+//
+//	sizes, err := ReadList(&r, (*Reader).ReadInt32)
+func ReadList[T any](reader *Reader, readElement func(*Reader) (T, error)) ([]T, error) {
+	n, err := reader.ReadUint32()
+	if err != nil {
+		return nil, err
+	}
+	var list []T
+	for i := uint32(0); i < n; i++ {
+		if err := reader.checkpoint(); err != nil {
+			return nil, err
+		}
+		v, err := readElement(reader)
+		if err != nil {
+			if reader.keepPartial(err) {
+				return append(list, v), err
+			}
+			return nil, err
+		}
+		list = append(list, v)
+	}
+	return list, nil
+}
+
+// WriteList writes a sequential container using the specified function to write the elements
+func WriteList[T any](writer *Writer, list []T, writeElement func(*Writer, T) error) error {
+	if err := writer.WriteUint32(uint32(len(list))); err != nil {
+		return err
+	}
+	for _, v := range list {
+		if err := writeElement(writer, v); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ReadMap reads an associative container (QMap, QHash etc.)
+// using the specified functions to read the keys and the values.
+// For multi-maps only the last read value of a key is kept
+func ReadMap[K comparable, V any](reader *Reader, readKey func(*Reader) (K, error), readValue func(*Reader) (V, error)) (map[K]V, error) {
+	n, err := reader.ReadUint32()
+	if err != nil {
+		return nil, err
+	}
+	m := map[K]V{}
+	for i := uint32(0); i < n; i++ {
+		if err := reader.checkpoint(); err != nil {
+			return m, err
+		}
+		k, err := readKey(reader)
+		if err != nil {
+			return m, err
+		}
+		v, err := readValue(reader)
+		if err != nil {
+			if reader.keepPartial(err) {
+				m[k] = v
+			}
+			return m, err
+		}
+		m[k] = v
+	}
+	return m, nil
+}
+
+// WriteMap writes a QMap using the specified functions to write the keys and the values.
+// Keys are written in the QMap order defined by less:
+// descending for Qt 5 streams and ascending since Qt 6
+func WriteMap[K comparable, V any](writer *Writer, m map[K]V, less func(a, b K) bool,
+	writeKey func(*Writer, K) error, writeValue func(*Writer, V) error) error {
+	if err := writer.WriteUint32(uint32(len(m))); err != nil {
+		return err
+	}
+	keys := make([]K, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	descending := writer.version < VersionQt6_0
+	sort.Slice(keys, func(i, j int) bool {
+		return less(keys[i], keys[j]) != descending
+	})
+	for _, k := range keys {
+		if err := writeKey(writer, k); err != nil {
+			return err
+		}
+		if err := writeValue(writer, m[k]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,281 @@
+package cutestream
+
+import (
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf16"
+)
+
+// QChar is a UTF-16 code unit
+type QChar uint16
+
+// Rune returns the character as a rune. Surrogate halves can't be represented
+// on their own and are returned as unicode.ReplacementChar
+func (c QChar) Rune() rune {
+	if utf16.IsSurrogate(rune(c)) {
+		return unicode.ReplacementChar
+	}
+	return rune(c)
+}
+
+// NullQString is a QString keeping Qt's distinction between null and empty strings,
+// e.g. for protocols using a null string for "unset" and an empty one for "cleared".
+// The zero value is a null QString
+type NullQString struct {
+	String string
+	Valid  bool // false for a null QString
+}
+
+// QUuid is a universally unique identifier, bytes are in the big endian order
+// of its string representation
+type QUuid [16]byte
+
+// ParseQUuid parses a UUID from its hex digits. Dashes and braces are allowed,
+// so both "{6ba7b810-9dad-11d1-80b4-00c04fd430c8}" and "6ba7b8109dad11d180b400c04fd430c8" are accepted
+func ParseQUuid(s string) (QUuid, error) {
+	digits := make([]byte, 0, 32)
+	for i := 0; i < len(s); i++ {
+		switch s[i] {
+		case '{', '}', '-':
+		default:
+			digits = append(digits, s[i])
+		}
+	}
+	var u QUuid
+	if len(digits) != 2*len(u) {
+		return QUuid{}, fmt.Errorf("invalid uuid %q", s)
+	}
+	if _, err := hex.Decode(u[:], digits); err != nil {
+		return QUuid{}, fmt.Errorf("invalid uuid %q: %w", s, err)
+	}
+	return u, nil
+}
+
+// String returns the UUID in the braced format of QUuid::toString()
+func (u QUuid) String() string {
+	h := u.Hex()
+	return "{" + h[:8] + "-" + h[8:12] + "-" + h[12:16] + "-" + h[16:20] + "-" + h[20:] + "}"
+}
+
+// MarshalText implements encoding.TextMarshaler using the braced format
+func (u QUuid) MarshalText() ([]byte, error) {
+	return []byte(u.String()), nil
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler accepting the formats of ParseQUuid
+func (u *QUuid) UnmarshalText(text []byte) error {
+	v, err := ParseQUuid(string(text))
+	if err != nil {
+		return err
+	}
+	*u = v
+	return nil
+}
+
+// Hex returns the 32 hex digits of the UUID
+func (u QUuid) Hex() string {
+	return hex.EncodeToString(u[:])
+}
+
+// reorder converts the data1, data2 and data3 fields of the UUID from one byte order to another.
+// Qt streams them in the stream byte order, the remaining 8 bytes as they are
+func (u QUuid) reorder(from, to binary.ByteOrder) QUuid {
+	to.PutUint32(u[0:], from.Uint32(u[0:]))
+	to.PutUint16(u[4:], from.Uint16(u[4:]))
+	to.PutUint16(u[6:], from.Uint16(u[6:]))
+	return u
+}
+
+// QVersionNumber is a version made of numeric segments, e.g. {5, 15, 2}
+type QVersionNumber []int32
+
+// String returns the segments separated by dots, like QVersionNumber::toString()
+func (v QVersionNumber) String() string {
+	segments := make([]string, len(v))
+	for i, s := range v {
+		segments[i] = strconv.Itoa(int(s))
+	}
+	return strings.Join(segments, ".")
+}
+
+// Special time zone ids used by QTimeZone streams
+const (
+	timeZoneInvalidID     = "-No Time Zone Specified!"
+	timeZoneOffsetFromUTC = "OffsetFromUtc" // followed by the details of the zone
+	timeZoneAheadOfUTC    = "AheadOfUtcBy"  // followed by the offset, since Qt 6.5
+)
+
+// UTCOffsetZone describes a time zone with a fixed offset from UTC
+type UTCOffsetZone struct {
+	Offset       int32 // Seconds ahead of UTC
+	Name         string
+	Abbreviation string
+	Territory    int32 // QLocale::Territory
+	Comment      string
+}
+
+// QTimeZone is a time zone. ID is empty for an invalid time zone, an IANA id like "Europe/Berlin"
+// for system time zones, or an id like "UTC+02:00" for zones with a fixed offset, which have UTC set.
+// Lightweight offset zones of Qt 6.5 and later have no id and only UTC.Offset set
+type QTimeZone struct {
+	ID  string
+	UTC *UTCOffsetZone
+}
+
+// Location returns the Go location of the time zone.
+// System time zones are loaded from the time zone database
+func (tz QTimeZone) Location() (*time.Location, error) {
+	switch {
+	case tz.UTC != nil:
+		return time.FixedZone(tz.ID, int(tz.UTC.Offset)), nil
+	case tz.ID == "":
+		return nil, fmt.Errorf("invalid time zone")
+	case tz.ID == "UTC":
+		return time.UTC, nil
+	default:
+		return time.LoadLocation(tz.ID)
+	}
+}
+
+// Names of the user types supported in QVariant streams
+const (
+	userTypeQVersionNumber = "QVersionNumber"
+	userTypeQTimeZone      = "QTimeZone"
+)
+
+// userTypeName returns the Qt type name used to stream a value of a user type
+func userTypeName(v interface{}) (string, error) {
+	switch v.(type) {
+	case QVersionNumber:
+		return userTypeQVersionNumber, nil
+	case QTimeZone:
+		return userTypeQTimeZone, nil
+	default:
+		return "", fmt.Errorf("%T is not a user type", v)
+	}
+}
+
+func (r *Reader) ReadQChar() (QChar, error) {
+	c, err := r.ReadUint16()
+	return QChar(c), err
+}
+
+func (r *Reader) ReadQByteArrayList() ([][]byte, error) {
+	return ReadList(r, (*Reader).ReadQByteArray)
+}
+
+func (r *Reader) ReadQVersionNumber() (QVersionNumber, error) {
+	return ReadList(r, (*Reader).ReadInt32)
+}
+
+func (r *Reader) ReadQTimeZone() (QTimeZone, error) {
+	id, err := r.ReadQString()
+	if err != nil {
+		return QTimeZone{}, err
+	}
+	switch id {
+	case timeZoneInvalidID:
+		return QTimeZone{}, nil
+	case timeZoneAheadOfUTC:
+		offset, err := r.ReadInt32()
+		if err != nil {
+			return QTimeZone{}, err
+		}
+		return QTimeZone{UTC: &UTCOffsetZone{Offset: offset}}, nil
+	case timeZoneOffsetFromUTC:
+	default:
+		return QTimeZone{ID: id}, nil
+	}
+	tz := QTimeZone{UTC: &UTCOffsetZone{}}
+	if tz.ID, err = r.ReadQString(); err != nil {
+		return QTimeZone{}, err
+	}
+	if tz.UTC.Offset, err = r.ReadInt32(); err != nil {
+		return QTimeZone{}, err
+	}
+	if tz.UTC.Name, err = r.ReadQString(); err != nil {
+		return QTimeZone{}, err
+	}
+	if tz.UTC.Abbreviation, err = r.ReadQString(); err != nil {
+		return QTimeZone{}, err
+	}
+	if tz.UTC.Territory, err = r.ReadInt32(); err != nil {
+		return QTimeZone{}, err
+	}
+	if tz.UTC.Comment, err = r.ReadQString(); err != nil {
+		return QTimeZone{}, err
+	}
+	return tz, nil
+}
+
+// readUserType reads the value of a user type streamed with its type name
+func (r *Reader) readUserType(name string) (interface{}, error) {
+	switch name {
+	case userTypeQVersionNumber:
+		return r.ReadQVersionNumber()
+	case userTypeQTimeZone:
+		return r.ReadQTimeZone()
+	default:
+		return r.unknownType(QMetaTypeUser, name)
+	}
+}
+
+func (w *Writer) WriteQChar(v QChar) error {
+	return w.WriteUint16(uint16(v))
+}
+
+func (w *Writer) WriteQByteArrayList(v [][]byte) error {
+	return WriteList(w, v, (*Writer).WriteQByteArray)
+}
+
+func (w *Writer) WriteQVersionNumber(v QVersionNumber) error {
+	return WriteList(w, v, (*Writer).WriteInt32)
+}
+
+func (w *Writer) WriteQTimeZone(v QTimeZone) error {
+	switch {
+	case v.UTC == nil && v.ID == "":
+		return w.WriteQString(timeZoneInvalidID)
+	case v.UTC == nil:
+		return w.WriteQString(v.ID)
+	case v.ID == "":
+		if err := w.WriteQString(timeZoneAheadOfUTC); err != nil {
+			return err
+		}
+		return w.WriteInt32(v.UTC.Offset)
+	}
+	for _, s := range []string{timeZoneOffsetFromUTC, v.ID} {
+		if err := w.WriteQString(s); err != nil {
+			return err
+		}
+	}
+	if err := w.WriteInt32(v.UTC.Offset); err != nil {
+		return err
+	}
+	for _, s := range []string{v.UTC.Name, v.UTC.Abbreviation} {
+		if err := w.WriteQString(s); err != nil {
+			return err
+		}
+	}
+	if err := w.WriteInt32(v.UTC.Territory); err != nil {
+		return err
+	}
+	return w.WriteQString(v.UTC.Comment)
+}
+
+// writeUserType writes the value of a user type without its type name
+func (w *Writer) writeUserType(v interface{}) error {
+	switch v := v.(type) {
+	case QVersionNumber:
+		return w.WriteQVersionNumber(v)
+	case QTimeZone:
+		return w.WriteQTimeZone(v)
+	default:
+		return fmt.Errorf("%T is not a user type", v)
+	}
+}
@@ -0,0 +1,230 @@
+package cutestream
+
+import (
+	"bytes"
+	"encoding/hex"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestReadQChar(t *testing.T) {
+	// QVariant(QChar(u'é')) and QVariant(QChar(u'€')) with Qt 5.15
+	data, _ := hex.DecodeString("0000000700" + "00e9" + "0000000700" + "20ac")
+	reader := NewReader(bytes.NewReader(data))
+	for _, expected := range []rune{'é', '€'} {
+		readType, v, err := reader.ReadQVariant()
+		assert.Nil(t, err)
+		assert.Equal(t, QMetaTypeQChar, readType)
+		assert.Equal(t, expected, v.(QChar).Rune())
+	}
+	assert.Equal(t, '�', QChar(0xd83c).Rune())
+}
+
+func TestReadQVersionNumber(t *testing.T) {
+	// QVariant(QVersionNumber(5, 15, 2)) with Qt 5.15 and Qt 6, user types had the id 127 in Qt 4 streams
+	name := "0000000f" + hex.EncodeToString([]byte("QVersionNumber\x00"))
+	value := "00000003" + "00000005" + "0000000f" + "00000002"
+	for _, test := range []struct {
+		version int
+		data    string
+	}{
+		{VersionQt4_6, "0000007f00" + name + value},
+		{VersionQt5_13, "0000040000" + name + value},
+		{VersionQt6_0, "0001000000" + name + value},
+	} {
+		data, _ := hex.DecodeString(test.data)
+		reader, err := NewReaderWithVersion(bytes.NewReader(data), test.version)
+		assert.Nil(t, err)
+		readType, v, err := reader.ReadQVariant()
+		assert.Nil(t, err)
+		assert.Equal(t, QMetaTypeUser, readType)
+		assert.Equal(t, QVersionNumber{5, 15, 2}, v)
+		assert.Equal(t, "5.15.2", v.(QVersionNumber).String())
+
+		var buf bytes.Buffer
+		writer, err := NewWriterWithVersion(&buf, test.version)
+		assert.Nil(t, err)
+		assert.Nil(t, writer.WriteQVariant(QMetaTypeUser, v))
+		assert.Equal(t, data, buf.Bytes())
+	}
+}
+
+func TestReadUnknownUserType(t *testing.T) {
+	data, _ := hex.DecodeString("0000040000" + "00000004" + hex.EncodeToString([]byte("Foo\x00")))
+	reader := NewReader(bytes.NewReader(data))
+	_, _, err := reader.ReadQVariant()
+	assert.NotNil(t, err)
+
+	writer := NewWriter(&bytes.Buffer{})
+	assert.NotNil(t, writer.WriteQVariant(QMetaTypeUser, nil))
+	assert.NotNil(t, writer.WriteQVariant(QMetaTypeUser, int32(1)))
+}
+
+func TestQTimeZone(t *testing.T) {
+	// QTimeZone::fromSecondsAheadOfUtc(7200) with Qt 5.15
+	data, _ := hex.DecodeString("0000001a" + "004f0066006600730065007400460072006f006d005500740063" +
+		"00000012" + "005500540043002b00300032003a00300030" +
+		"00001c20" +
+		"00000012" + "005500540043002b00300032003a00300030" +
+		"00000012" + "005500540043002b00300032003a00300030" +
+		"00000000" + "00000000")
+	reader := NewReader(bytes.NewReader(data))
+	tz, err := reader.ReadQTimeZone()
+	assert.Nil(t, err)
+	assert.Equal(t, QTimeZone{ID: "UTC+02:00", UTC: &UTCOffsetZone{
+		Offset: 7200, Name: "UTC+02:00", Abbreviation: "UTC+02:00", Comment: "",
+	}}, tz)
+	loc, err := tz.Location()
+	assert.Nil(t, err)
+	_, offset := time.Now().In(loc).Zone()
+	assert.Equal(t, 7200, offset)
+
+	zones := []QTimeZone{{}, {ID: "Europe/Berlin"}, tz, {UTC: &UTCOffsetZone{Offset: -3600}}}
+	var buf bytes.Buffer
+	writer := NewWriter(&buf)
+	for _, z := range zones {
+		assert.Nil(t, writer.WriteQVariant(QMetaTypeUser, z))
+	}
+	reader = NewReader(&buf)
+	for _, z := range zones {
+		readType, v, err := reader.ReadQVariant()
+		assert.Nil(t, err)
+		assert.Equal(t, QMetaTypeUser, readType)
+		assert.Equal(t, z, v)
+	}
+	assert.Equal(t, 0, buf.Len())
+
+	_, err = QTimeZone{}.Location()
+	assert.NotNil(t, err)
+}
+
+func TestQDateTimeTimeZone(t *testing.T) {
+	// QDateTime(QDate(2024, 3, 1), QTime(12, 0), QTimeZone("Europe/Berlin")) with Qt 5.15
+	data, _ := hex.DecodeString("0000000000258ad3" + "02932e00" + "03" +
+		"0000001a" + "004500750072006f00700065002f004200650072006c0069006e")
+	reader := NewReader(bytes.NewReader(data))
+	datetime, err := reader.ReadQDateTime()
+	assert.Nil(t, err)
+	assert.Equal(t, "Europe/Berlin", datetime.Location().String())
+	assert.Equal(t, time.Date(2024, 3, 1, 11, 0, 0, 0, time.UTC), datetime.UTC())
+
+	var buf bytes.Buffer
+	writer := NewWriter(&buf)
+	assert.Nil(t, writer.WriteQDateTime(datetime))
+	assert.Equal(t, data, buf.Bytes())
+
+	// Qt::TimeSpec has no value 4
+	data[12] = 4
+	reader = NewReader(bytes.NewReader(data))
+	_, err = reader.ReadQDateTime()
+	assert.NotNil(t, err)
+}
+
+func TestQUuid(t *testing.T) {
+	u, err := ParseQUuid("{6ba7b810-9dad-11d1-80b4-00c04fd430c8}")
+	assert.Nil(t, err)
+	assert.Equal(t, "{6ba7b810-9dad-11d1-80b4-00c04fd430c8}", u.String())
+	assert.Equal(t, "6ba7b8109dad11d180b400c04fd430c8", u.Hex())
+	_, err = ParseQUuid("6ba7b810")
+	assert.NotNil(t, err)
+	_, err = ParseQUuid("6ba7b8109dad11d180b400c04fd430zz")
+	assert.NotNil(t, err)
+
+	reader := NewReader(bytes.NewReader(u[:8]))
+	_, err = reader.ReadQUuid()
+	assert.NotNil(t, err)
+}
+
+func TestCoreTypesRoundTrip(t *testing.T) {
+	values := []Variant{
+		{QMetaTypeQChar, QChar('ß')},
+		{QMetaTypeChar16, QChar(0xd83c)},
+		{QMetaTypeChar32, '🏁'},
+		{QMetaTypeNullptr, nil},
+		{QMetaTypeQByteArrayList, [][]byte{[]byte("a"), {}, []byte("bc")}},
+		{QMetaTypeQVariantList, []interface{}{QChar('x'), [][]byte{[]byte("d")}, QVersionNumber{6, 7}}},
+	}
+	var buf bytes.Buffer
+	writer, err := NewWriterWithVersion(&buf, VersionQt6_7)
+	assert.Nil(t, err)
+	for _, v := range values {
+		assert.Nil(t, writer.WriteVariant(v))
+	}
+	reader, err := NewReaderWithVersion(&buf, VersionQt6_7)
+	assert.Nil(t, err)
+	for _, v := range values {
+		read, err := reader.ReadVariant()
+		assert.Nil(t, err)
+		assert.Equal(t, v, read)
+	}
+	assert.Equal(t, 0, buf.Len())
+}
+
+func TestNullAndEmpty(t *testing.T) {
+	// QString(), QString(""), QByteArray(), QByteArray(""), QUrl(), empty encoded QUrl
+	data, _ := hex.DecodeString("ffffffff" + "00000000" + "ffffffff" + "00000000" + "ffffffff" + "00000000")
+	reader := NewReader(bytes.NewReader(data))
+	null, err := reader.ReadNullQString()
+	assert.Nil(t, err)
+	assert.Equal(t, NullQString{}, null)
+	empty, err := reader.ReadNullQString()
+	assert.Nil(t, err)
+	assert.Equal(t, NullQString{Valid: true}, empty)
+	nullBytes, err := reader.ReadQByteArray()
+	assert.Nil(t, err)
+	assert.Nil(t, nullBytes)
+	emptyBytes, err := reader.ReadQByteArray()
+	assert.Nil(t, err)
+	assert.Equal(t, []byte{}, emptyBytes)
+	nullURL, err := reader.ReadQUrl()
+	assert.Nil(t, err)
+	assert.Nil(t, nullURL)
+	emptyURL, err := reader.ReadQUrl()
+	assert.Nil(t, err)
+	assert.NotNil(t, emptyURL)
+	assert.Equal(t, "", emptyURL.String())
+
+	var buf bytes.Buffer
+	w := NewWriter(&buf)
+	assert.Nil(t, w.WriteNullQString(null))
+	assert.Nil(t, w.WriteNullQString(empty))
+	assert.Nil(t, w.WriteQByteArray(nullBytes))
+	assert.Nil(t, w.WriteQByteArray(emptyBytes))
+	assert.Nil(t, w.WriteQUrl(nullURL))
+	assert.Nil(t, w.WriteQUrl(emptyURL))
+	assert.Equal(t, data, buf.Bytes())
+}
+
+func TestNullStringVariants(t *testing.T) {
+	// QVariant(QString()), QVariant(QString("")) and QVariant(QString("a")) with Qt 6,
+	// where null strings aren't null variants
+	data, _ := hex.DecodeString("0000000a00" + "ffffffff" + "0000000a00" + "00000000" + "0000000a00" + "00000002" + "0061")
+	reader, _ := NewReaderWithVersion(bytes.NewReader(data), VersionQt6_0)
+	reader.NullStrings = true
+	var variants []Variant
+	for i := 0; i < 3; i++ {
+		v, err := reader.ReadVariant()
+		assert.Nil(t, err)
+		variants = append(variants, v)
+	}
+	assert.Equal(t, []Variant{
+		{Type: QMetaTypeQString, Value: NullQString{}},
+		{Type: QMetaTypeQString, Value: NullQString{Valid: true}},
+		{Type: QMetaTypeQString, Value: NullQString{String: "a", Valid: true}},
+	}, variants)
+
+	var buf bytes.Buffer
+	w, _ := NewWriterWithVersion(&buf, VersionQt6_0)
+	for _, v := range variants {
+		assert.Nil(t, w.WriteVariant(v))
+	}
+	assert.Equal(t, data, buf.Bytes())
+
+	// a null QVariant of QString type holds a null QString, Qt 5
+	buf.Reset()
+	w = NewWriter(&buf)
+	assert.Nil(t, w.WriteQVariant(QMetaTypeQString, nil))
+	assert.Equal(t, "0000000a01"+"ffffffff", hex.EncodeToString(buf.Bytes()))
+}
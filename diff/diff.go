@@ -0,0 +1,296 @@
+// Package diff reports semantic differences between QDataStream files,
+// e.g. to verify that a migration or a Qt upgrade doesn't change saved data.
+//
+// Both files are decoded as sequences of records, either QVariants or records of a Schema,
+// and the decoded values are compared: changed values, added and removed map keys,
+// list length changes and metatype changes are reported with the path of the value.
+// Floating point values are compared with a tolerance. Records encoded with different bytes
+// which decode to equal values are reported as re-encoded.
+package diff
+
+import (
+	"bytes"
+	"fmt"
+	"math"
+	"net/url"
+	"reflect"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/race-engineering-center/cutestream"
+)
+
+// Kind is the kind of a difference
+type Kind int
+
+const (
+	Changed       Kind = iota // The value differs
+	Added                     // The map key is only present in the second value
+	Removed                   // The map key is only present in the first value
+	LengthChanged             // The list or the sequence of records has a different length, A and B are the lengths
+	TypeChanged               // The QVariant metatype or the Go type differs
+	Reencoded                 // The record decodes to equal values but its bytes differ
+)
+
+func (k Kind) String() string {
+	switch k {
+	case Changed:
+		return "changed"
+	case Added:
+		return "added"
+	case Removed:
+		return "removed"
+	case LengthChanged:
+		return "length changed"
+	case TypeChanged:
+		return "type changed"
+	case Reencoded:
+		return "re-encoded"
+	default:
+		return "kind " + strconv.Itoa(int(k))
+	}
+}
+
+// Difference is a difference between the values at Path, e.g. `[2]["laps"][3]`
+// for the fourth element of the "laps" key of the third record
+type Difference struct {
+	Path string
+	Kind Kind
+	A, B interface{}
+}
+
+func (d Difference) String() string {
+	path := d.Path
+	if path == "" {
+		path = "."
+	}
+	switch d.Kind {
+	case Added:
+		return fmt.Sprintf("%s: added %v", path, d.B)
+	case Removed:
+		return fmt.Sprintf("%s: removed %v", path, d.A)
+	case Reencoded:
+		return fmt.Sprintf("%s: re-encoded", path)
+	default:
+		return fmt.Sprintf("%s: %s %v -> %v", path, d.Kind, d.A, d.B)
+	}
+}
+
+// Options configure how files are decoded and compared.
+// The second stream uses the settings of the first one unless VersionB or DoublePrecisionB are set,
+// e.g. to compare a file written by Qt 5 with its Qt 6 counterpart
+type Options struct {
+	Version          int                      // QDataStream version, cutestream.VersionQt5_13 if 0
+	DoublePrecision  bool                     // Whether floating point values are in double precision
+	VersionB         int                      // QDataStream version of the second stream, Version if 0
+	DoublePrecisionB *bool                    // Precision of the second stream, DoublePrecision if nil
+	Decode           cutestream.RecordDecoder // Decoder of the records, QVariants if nil
+	Tolerance        float64                  // Largest absolute difference of equal floating point values
+}
+
+// DecodeVariant decodes a QVariant record keeping its metatype
+func DecodeVariant(r *cutestream.Reader) (interface{}, error) {
+	return r.ReadVariant()
+}
+
+type record struct {
+	value interface{}
+	data  []byte
+}
+
+func decodeRecords(data []byte, version int, doublePrecision bool, opts Options) ([]record, error) {
+	r, err := cutestream.NewReaderWithVersion(bytes.NewReader(data), version)
+	if err != nil {
+		return nil, err
+	}
+	r.DoublePrecision = doublePrecision
+	decode := opts.Decode
+	if decode == nil {
+		decode = DecodeVariant
+	}
+	var records []record
+	s := cutestream.NewRecordScanner(&r, decode)
+	for start := s.Offset(); s.Next(); start = s.Offset() {
+		records = append(records, record{value: s.Record(), data: data[start:s.Offset()]})
+	}
+	return records, s.Err()
+}
+
+// Bytes decodes two streams and returns the differences of their records
+func Bytes(a, b []byte, opts Options) ([]Difference, error) {
+	if opts.Version == 0 {
+		opts.Version = cutestream.VersionQt5_13
+	}
+	versionB, doublePrecisionB := opts.VersionB, opts.DoublePrecision
+	if versionB == 0 {
+		versionB = opts.Version
+	}
+	if opts.DoublePrecisionB != nil {
+		doublePrecisionB = *opts.DoublePrecisionB
+	}
+	recordsA, err := decodeRecords(a, opts.Version, opts.DoublePrecision, opts)
+	if err != nil {
+		return nil, fmt.Errorf("first stream: %w", err)
+	}
+	recordsB, err := decodeRecords(b, versionB, doublePrecisionB, opts)
+	if err != nil {
+		return nil, fmt.Errorf("second stream: %w", err)
+	}
+
+	c := comparer{tolerance: opts.Tolerance}
+	if len(recordsA) != len(recordsB) {
+		c.add("", LengthChanged, len(recordsA), len(recordsB))
+	}
+	for i := 0; i < len(recordsA) && i < len(recordsB); i++ {
+		path := "[" + strconv.Itoa(i) + "]"
+		n := len(c.differences)
+		c.compare(path, reflect.ValueOf(recordsA[i].value), reflect.ValueOf(recordsB[i].value))
+		if len(c.differences) == n && !bytes.Equal(recordsA[i].data, recordsB[i].data) {
+			c.add(path, Reencoded, recordsA[i].data, recordsB[i].data)
+		}
+	}
+	return c.differences, nil
+}
+
+// Values returns the differences of two decoded values
+func Values(a, b interface{}, tolerance float64) []Difference {
+	c := comparer{tolerance: tolerance}
+	c.compare("", reflect.ValueOf(a), reflect.ValueOf(b))
+	return c.differences
+}
+
+type comparer struct {
+	tolerance   float64
+	differences []Difference
+}
+
+func (c *comparer) add(path string, kind Kind, a, b interface{}) {
+	c.differences = append(c.differences, Difference{Path: path, Kind: kind, A: a, B: b})
+}
+
+func interfaceOf(v reflect.Value) interface{} {
+	if !v.IsValid() {
+		return nil
+	}
+	return v.Interface()
+}
+
+// compare walks both values. Values of interfaces are compared by their dynamic types
+func (c *comparer) compare(path string, a, b reflect.Value) {
+	for a.IsValid() && a.Kind() == reflect.Interface {
+		a = a.Elem()
+	}
+	for b.IsValid() && b.Kind() == reflect.Interface {
+		b = b.Elem()
+	}
+	if !a.IsValid() || !b.IsValid() {
+		if a.IsValid() != b.IsValid() {
+			c.add(path, Changed, interfaceOf(a), interfaceOf(b))
+		}
+		return
+	}
+	if a.Type() != b.Type() {
+		c.add(path, TypeChanged, a.Type().String(), b.Type().String())
+		return
+	}
+
+	switch x := a.Interface().(type) {
+	case cutestream.Variant:
+		y := b.Interface().(cutestream.Variant)
+		if x.Type != y.Type {
+			c.add(path, TypeChanged, x.Type, y.Type)
+			return
+		}
+		c.compare(path, reflect.ValueOf(x.Value), reflect.ValueOf(y.Value))
+		return
+	case time.Time:
+		y := b.Interface().(time.Time)
+		_, offsetA := x.Zone()
+		_, offsetB := y.Zone()
+		if !x.Equal(y) || offsetA != offsetB || (x.Location() == time.Local) != (y.Location() == time.Local) {
+			c.add(path, Changed, x, y)
+		}
+		return
+	case *url.URL:
+		y := b.Interface().(*url.URL)
+		if (x == nil) != (y == nil) || x != nil && x.String() != y.String() {
+			c.add(path, Changed, x, y)
+		}
+		return
+	}
+
+	switch a.Kind() {
+	case reflect.Float32, reflect.Float64:
+		x, y := a.Float(), b.Float()
+		if !(math.IsNaN(x) && math.IsNaN(y)) && !(x == y || math.Abs(x-y) <= c.tolerance) {
+			c.add(path, Changed, a.Interface(), b.Interface())
+		}
+	case reflect.Slice, reflect.Array:
+		if a.Kind() == reflect.Slice && a.Type().Elem().Kind() == reflect.Uint8 {
+			// byte arrays are compared as a whole
+			if !bytes.Equal(a.Bytes(), b.Bytes()) || a.IsNil() != b.IsNil() {
+				c.add(path, Changed, a.Interface(), b.Interface())
+			}
+			return
+		}
+		if a.Len() != b.Len() {
+			c.add(path, LengthChanged, a.Len(), b.Len())
+		}
+		for i := 0; i < a.Len() && i < b.Len(); i++ {
+			c.compare(path+"["+strconv.Itoa(i)+"]", a.Index(i), b.Index(i))
+		}
+	case reflect.Map:
+		keys := map[interface{}]reflect.Value{}
+		for _, k := range a.MapKeys() {
+			keys[k.Interface()] = k
+		}
+		for _, k := range b.MapKeys() {
+			keys[k.Interface()] = k
+		}
+		sorted := make([]reflect.Value, 0, len(keys))
+		for _, k := range keys {
+			sorted = append(sorted, k)
+		}
+		sort.Slice(sorted, func(i, j int) bool {
+			return fmt.Sprint(sorted[i].Interface()) < fmt.Sprint(sorted[j].Interface())
+		})
+		for _, k := range sorted {
+			keyPath := path + "[" + formatKey(k) + "]"
+			x, y := a.MapIndex(k), b.MapIndex(k)
+			switch {
+			case !y.IsValid():
+				c.add(keyPath, Removed, x.Interface(), nil)
+			case !x.IsValid():
+				c.add(keyPath, Added, nil, y.Interface())
+			default:
+				c.compare(keyPath, x, y)
+			}
+		}
+	case reflect.Struct:
+		for i := 0; i < a.NumField(); i++ {
+			if a.Type().Field(i).IsExported() {
+				c.compare(path+"."+a.Type().Field(i).Name, a.Field(i), b.Field(i))
+			}
+		}
+	case reflect.Ptr:
+		if a.IsNil() || b.IsNil() {
+			if a.IsNil() != b.IsNil() {
+				c.add(path, Changed, a.Interface(), b.Interface())
+			}
+			return
+		}
+		c.compare(path, a.Elem(), b.Elem())
+	default:
+		if !reflect.DeepEqual(a.Interface(), b.Interface()) {
+			c.add(path, Changed, a.Interface(), b.Interface())
+		}
+	}
+}
+
+func formatKey(k reflect.Value) string {
+	if k.Kind() == reflect.String {
+		return strconv.Quote(k.String())
+	}
+	return fmt.Sprint(k.Interface())
+}
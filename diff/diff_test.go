@@ -0,0 +1,115 @@
+package diff
+
+import (
+	"bytes"
+	"encoding/hex"
+	"testing"
+
+	"github.com/race-engineering-center/cutestream"
+	"github.com/stretchr/testify/assert"
+)
+
+func encode(t *testing.T, values ...cutestream.Variant) []byte {
+	var buf bytes.Buffer
+	w := cutestream.NewWriter(&buf)
+	w.DoublePrecision = true
+	for _, v := range values {
+		assert.Nil(t, w.WriteQVariant(v.Type, v.Value))
+	}
+	return buf.Bytes()
+}
+
+func TestBytes(t *testing.T) {
+	a := encode(t,
+		cutestream.Variant{Type: cutestream.QMetaTypeQVariantMap, Value: map[string]interface{}{
+			"driver": "Verstappen",
+			"laps":   []interface{}{81.234, 80.9, 80.7},
+			"pits":   int32(2),
+		}},
+		cutestream.Variant{Type: cutestream.QMetaTypeInt, Value: int32(1)},
+	)
+	b := encode(t,
+		cutestream.Variant{Type: cutestream.QMetaTypeQVariantMap, Value: map[string]interface{}{
+			"driver": "Verstappen",
+			"laps":   []interface{}{81.2340001, 80.8},
+			"team":   "Red Bull",
+		}},
+		cutestream.Variant{Type: cutestream.QMetaTypeUInt, Value: uint32(1)},
+		cutestream.Variant{Type: cutestream.QMetaTypeInt, Value: int32(2)},
+	)
+	differences, err := Bytes(a, b, Options{DoublePrecision: true, Tolerance: 1e-6})
+	assert.Nil(t, err)
+	assert.Equal(t, []Difference{
+		{Path: "", Kind: LengthChanged, A: 2, B: 3},
+		{Path: `[0]["laps"]`, Kind: LengthChanged, A: 3, B: 2},
+		{Path: `[0]["laps"][1]`, Kind: Changed, A: 80.9, B: 80.8},
+		{Path: `[0]["pits"]`, Kind: Removed, A: int32(2)},
+		{Path: `[0]["team"]`, Kind: Added, B: "Red Bull"},
+		{Path: "[1]", Kind: TypeChanged, A: cutestream.QMetaTypeInt, B: cutestream.QMetaTypeUInt},
+	}, differences)
+	assert.Equal(t, `[0]["laps"][1]: changed 80.9 -> 80.8`, differences[2].String())
+	assert.Equal(t, `[0]["team"]: added Red Bull`, differences[4].String())
+}
+
+func TestReencoded(t *testing.T) {
+	// QVariantHash keys in different orders, Qt 5
+	a, _ := hex.DecodeString("0000001c00" + "00000002" +
+		"0000000200610000000200" + "00000001" +
+		"0000000200620000000200" + "00000002")
+	b, _ := hex.DecodeString("0000001c00" + "00000002" +
+		"0000000200620000000200" + "00000002" +
+		"0000000200610000000200" + "00000001")
+	differences, err := Bytes(a, b, Options{})
+	assert.Nil(t, err)
+	assert.Equal(t, []Difference{{Path: "[0]", Kind: Reencoded, A: a, B: b}}, differences)
+
+	differences, err = Bytes(a, a, Options{})
+	assert.Nil(t, err)
+	assert.Empty(t, differences)
+}
+
+func TestVersions(t *testing.T) {
+	// QVariantList{QColor(Qt::red), 1.5f} with Qt 5 in double precision and Qt 6 in single precision
+	a, _ := hex.DecodeString("0000000900" + "00000002" +
+		"0000004300" + "01" + "ffff" + "ffff" + "0000" + "0000" + "0000" +
+		"0000002600" + "3ff8000000000000")
+	b, _ := hex.DecodeString("0000000900" + "00000002" +
+		"0000100300" + "01" + "ffff" + "ffff" + "0000" + "0000" + "0000" +
+		"0000002600" + "3fc00000")
+	single := false
+	differences, err := Bytes(a, b, Options{
+		Version: cutestream.VersionQt5_13, DoublePrecision: true,
+		VersionB: cutestream.VersionQt6_0, DoublePrecisionB: &single,
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, []Difference{{Path: "[0]", Kind: Reencoded, A: a, B: b}}, differences)
+
+	// the Qt 6 metatype id of QColor is an unknown type in Qt 5 streams
+	_, err = Bytes(a, b, Options{Version: cutestream.VersionQt5_13, DoublePrecision: true})
+	assert.NotNil(t, err)
+}
+
+func TestSchema(t *testing.T) {
+	var a, b bytes.Buffer
+	wa, wb := cutestream.NewWriter(&a), cutestream.NewWriter(&b)
+	for i := int32(0); i < 3; i++ {
+		assert.Nil(t, wa.WriteInt32(i))
+		assert.Nil(t, wa.WriteQString("Spa"))
+		assert.Nil(t, wb.WriteInt32(i))
+		assert.Nil(t, wb.WriteQString(map[bool]string{true: "Monza", false: "Spa"}[i == 2]))
+	}
+	schema := cutestream.Schema{{Name: "lap", Type: cutestream.QMetaTypeInt}, {Name: "track", Type: cutestream.QMetaTypeQString}}
+	differences, err := Bytes(a.Bytes(), b.Bytes(), Options{Decode: schema.Decode})
+	assert.Nil(t, err)
+	assert.Equal(t, []Difference{{Path: `[2]["track"]`, Kind: Changed, A: "Spa", B: "Monza"}}, differences)
+
+	_, err = Bytes(a.Bytes()[:5], b.Bytes(), Options{Decode: schema.Decode})
+	assert.NotNil(t, err)
+}
+
+func TestValues(t *testing.T) {
+	assert.Empty(t, Values(cutestream.QPointF{X: 1, Y: 2}, cutestream.QPointF{X: 1.05, Y: 2}, 0.1))
+	assert.Equal(t, []Difference{{Path: ".Y", Kind: Changed, A: 2.0, B: 3.0}},
+		Values(cutestream.QPointF{X: 1, Y: 2}, cutestream.QPointF{X: 1, Y: 3}, 0.1))
+	assert.Equal(t, []Difference{{Path: "", Kind: TypeChanged, A: "int32", B: "string"}}, Values(int32(1), "1", 0))
+}
@@ -0,0 +1,166 @@
+package cutestream
+
+// EasingType is the type of an easing curve (QEasingCurve::Type)
+type EasingType uint8
+
+const (
+	EasingLinear EasingType = iota
+	EasingInQuad
+	EasingOutQuad
+	EasingInOutQuad
+	EasingOutInQuad
+	EasingInCubic
+	EasingOutCubic
+	EasingInOutCubic
+	EasingOutInCubic
+	EasingInQuart
+	EasingOutQuart
+	EasingInOutQuart
+	EasingOutInQuart
+	EasingInQuint
+	EasingOutQuint
+	EasingInOutQuint
+	EasingOutInQuint
+	EasingInSine
+	EasingOutSine
+	EasingInOutSine
+	EasingOutInSine
+	EasingInExpo
+	EasingOutExpo
+	EasingInOutExpo
+	EasingOutInExpo
+	EasingInCirc
+	EasingOutCirc
+	EasingInOutCirc
+	EasingOutInCirc
+	EasingInElastic
+	EasingOutElastic
+	EasingInOutElastic
+	EasingOutInElastic
+	EasingInBack
+	EasingOutBack
+	EasingInOutBack
+	EasingOutInBack
+	EasingInBounce
+	EasingOutBounce
+	EasingInOutBounce
+	EasingOutInBounce
+	EasingInCurve
+	EasingOutCurve
+	EasingSineCurve
+	EasingCosineCurve
+	EasingBezierSpline
+	EasingTCBSpline
+	EasingCustom
+)
+
+// TCBPoint is a point of a TCB spline with its tension, continuity and bias
+type TCBPoint struct {
+	Point QPointF
+	T     float64
+	C     float64
+	B     float64
+}
+
+// EasingCurveConfig holds the parameters of an easing curve
+type EasingCurveConfig struct {
+	Period    float64
+	Amplitude float64
+	Overshoot float64
+	// Control points of a bezier spline and points of a TCB spline, since Qt 5.13
+	BezierCurve []QPointF
+	TCBPoints   []TCBPoint
+}
+
+// QEasingCurve is an easing curve for animations. Config is nil for curves
+// with default parameters
+type QEasingCurve struct {
+	Type     EasingType
+	Function uint64 // Address of the custom easing function, meaningless outside of the writing process
+	Config   *EasingCurveConfig
+}
+
+func (r *Reader) readTCBPoint() (TCBPoint, error) {
+	var p TCBPoint
+	var err error
+	if p.Point, err = r.ReadQPointF(); err != nil {
+		return TCBPoint{}, err
+	}
+	for _, v := range []*float64{&p.T, &p.C, &p.B} {
+		if *v, err = r.ReadDouble(); err != nil {
+			return TCBPoint{}, err
+		}
+	}
+	return p, nil
+}
+
+func (r *Reader) ReadQEasingCurve() (QEasingCurve, error) {
+	var c QEasingCurve
+	t, err := r.ReadUint8()
+	if err != nil {
+		return QEasingCurve{}, err
+	}
+	c.Type = EasingType(t)
+	if c.Function, err = r.ReadUint64(); err != nil {
+		return QEasingCurve{}, err
+	}
+	hasConfig, err := r.ReadBool()
+	if err != nil || !hasConfig {
+		return c, err
+	}
+	config := &EasingCurveConfig{}
+	for _, v := range []*float64{&config.Period, &config.Amplitude, &config.Overshoot} {
+		if *v, err = r.ReadDouble(); err != nil {
+			return QEasingCurve{}, err
+		}
+	}
+	if r.version >= VersionQt5_13 {
+		if config.BezierCurve, err = ReadList(r, (*Reader).ReadQPointF); err != nil {
+			return QEasingCurve{}, err
+		}
+		if config.TCBPoints, err = ReadList(r, (*Reader).readTCBPoint); err != nil {
+			return QEasingCurve{}, err
+		}
+	}
+	c.Config = config
+	return c, nil
+}
+
+func (w *Writer) writeTCBPoint(v TCBPoint) error {
+	if err := w.WriteQPointF(v.Point); err != nil {
+		return err
+	}
+	for _, d := range []float64{v.T, v.C, v.B} {
+		if err := w.WriteDouble(d); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (w *Writer) WriteQEasingCurve(v QEasingCurve) error {
+	if err := w.WriteUint8(uint8(v.Type)); err != nil {
+		return err
+	}
+	if err := w.WriteUint64(v.Function); err != nil {
+		return err
+	}
+	if err := w.WriteBool(v.Config != nil); err != nil {
+		return err
+	}
+	if v.Config == nil {
+		return nil
+	}
+	for _, d := range []float64{v.Config.Period, v.Config.Amplitude, v.Config.Overshoot} {
+		if err := w.WriteDouble(d); err != nil {
+			return err
+		}
+	}
+	if w.version >= VersionQt5_13 {
+		if err := WriteList(w, v.Config.BezierCurve, (*Writer).WriteQPointF); err != nil {
+			return err
+		}
+		return WriteList(w, v.Config.TCBPoints, (*Writer).writeTCBPoint)
+	}
+	return nil
+}
@@ -0,0 +1,60 @@
+package cutestream
+
+import (
+	"bytes"
+	"encoding/hex"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestReadQEasingCurve(t *testing.T) {
+	// QVariant(QEasingCurve(QEasingCurve::OutQuad)) with Qt 5.15
+	data, _ := hex.DecodeString("0000001d00" + "02" + "0000000000000000" + "00")
+	reader := NewReader(bytes.NewReader(data))
+	readType, v, err := reader.ReadQVariant()
+	assert.Nil(t, err)
+	assert.Equal(t, QMetaTypeQEasingCurve, readType)
+	assert.Equal(t, QEasingCurve{Type: EasingOutQuad}, v)
+}
+
+func TestQEasingCurveRoundTrip(t *testing.T) {
+	curves := []QEasingCurve{
+		{Type: EasingLinear},
+		{Type: EasingOutElastic, Config: &EasingCurveConfig{Period: 0.3, Amplitude: 1, Overshoot: 1.70158}},
+		{Type: EasingBezierSpline, Config: &EasingCurveConfig{
+			Period: 0.3, Amplitude: 1, Overshoot: 1.70158,
+			BezierCurve: []QPointF{{0.25, 0.1}, {0.25, 1}, {1, 1}},
+		}},
+		{Type: EasingTCBSpline, Config: &EasingCurveConfig{
+			Period: 0.3, Amplitude: 1, Overshoot: 1.70158,
+			TCBPoints: []TCBPoint{{QPointF{0, 0}, 0, 0, 0}, {QPointF{1, 1}, 0.5, -0.5, 0.25}},
+		}},
+	}
+	for _, version := range []int{VersionQt5_12, VersionQt5_13} {
+		var buf bytes.Buffer
+		writer, err := NewWriterWithVersion(&buf, version)
+		assert.Nil(t, err)
+		writer.DoublePrecision = true
+		for _, c := range curves {
+			assert.Nil(t, writer.WriteQVariant(QMetaTypeQEasingCurve, c))
+		}
+		reader, err := NewReaderWithVersion(&buf, version)
+		assert.Nil(t, err)
+		reader.DoublePrecision = true
+		for _, c := range curves {
+			_, v, err := reader.ReadQVariant()
+			assert.Nil(t, err)
+			curve := v.(QEasingCurve)
+			if version < VersionQt5_13 && c.Config != nil {
+				// splines are not serialized before Qt 5.13
+				assert.Nil(t, curve.Config.BezierCurve)
+				assert.Nil(t, curve.Config.TCBPoints)
+				curve.Config.BezierCurve = c.Config.BezierCurve
+				curve.Config.TCBPoints = c.Config.TCBPoints
+			}
+			assert.Equal(t, c, curve)
+		}
+		assert.Equal(t, 0, buf.Len())
+	}
+}
@@ -0,0 +1,279 @@
+package cutestream
+
+// FontStyle is the slant of a font (QFont::Style)
+type FontStyle uint8
+
+const (
+	FontStyleNormal  FontStyle = 0
+	FontStyleItalic  FontStyle = 1
+	FontStyleOblique FontStyle = 2
+)
+
+// Font flags, from qfont.cpp
+const (
+	fontBitItalic     = 0x01
+	fontBitUnderline  = 0x02
+	fontBitStrikeOut  = 0x04
+	fontBitFixedPitch = 0x08
+	fontBitKerning    = 0x10
+	fontBitOverline   = 0x40
+	fontBitOblique    = 0x80
+
+	fontBitIgnorePitch           = 0x01
+	fontBitLetterSpacingAbsolute = 0x02
+)
+
+// QFont is a font request. The meaning of some fields depends on the stream version,
+// e.g. Weight is a Qt 5 weight (0 - 99) before Qt 6 and an OpenType weight (1 - 1000) since
+type QFont struct {
+	Family                string
+	StyleName             string  // Since Qt 5.4
+	PointSize             float64 // -1 if the pixel size is set
+	PixelSize             int32   // -1 if the point size is set
+	StyleHint             uint8   // QFont::StyleHint
+	StyleStrategy         uint16  // QFont::StyleStrategy, 8 bits before Qt 5.4
+	Weight                uint16
+	Style                 FontStyle
+	Underline             bool
+	Overline              bool
+	StrikeOut             bool
+	FixedPitch            bool
+	Kerning               bool
+	Stretch               uint16             // Since Qt 4.3
+	IgnorePitch           bool               // Since Qt 4.4
+	LetterSpacingAbsolute bool               // Since Qt 4.4
+	LetterSpacing         int32              // In 1/64 of a pixel or a percent, since Qt 4.5
+	WordSpacing           int32              // In 1/64 of a pixel, since Qt 4.5
+	HintingPreference     uint8              // QFont::HintingPreference, since Qt 5.4
+	Capitalization        uint8              // QFont::Capitalization, since Qt 5.6
+	Families              []string           // Since Qt 5.13
+	Features              map[uint32]uint32  // OpenType feature tags and values, since Qt 6.6
+	VariableAxes          map[uint32]float32 // OpenType variable axis tags and values, since Qt 6.7
+}
+
+func (r *Reader) ReadQFont() (QFont, error) {
+	var f QFont
+	var err error
+	if f.Family, err = r.ReadQString(); err != nil {
+		return QFont{}, err
+	}
+	if r.version >= VersionQt5_4 {
+		if f.StyleName, err = r.ReadQString(); err != nil {
+			return QFont{}, err
+		}
+	}
+	if f.PointSize, err = r.ReadDouble(); err != nil {
+		return QFont{}, err
+	}
+	if f.PixelSize, err = r.ReadInt32(); err != nil {
+		return QFont{}, err
+	}
+	if f.StyleHint, err = r.ReadUint8(); err != nil {
+		return QFont{}, err
+	}
+	if r.version >= VersionQt5_4 {
+		if f.StyleStrategy, err = r.ReadUint16(); err != nil {
+			return QFont{}, err
+		}
+	} else {
+		strategy, err := r.ReadUint8()
+		if err != nil {
+			return QFont{}, err
+		}
+		f.StyleStrategy = uint16(strategy)
+	}
+	if r.version >= VersionQt6_0 {
+		if f.Weight, err = r.ReadUint16(); err != nil {
+			return QFont{}, err
+		}
+	} else {
+		// the first byte used to be the charset
+		if _, err = r.ReadUint8(); err != nil {
+			return QFont{}, err
+		}
+		weight, err := r.ReadUint8()
+		if err != nil {
+			return QFont{}, err
+		}
+		f.Weight = uint16(weight)
+	}
+	bits, err := r.ReadUint8()
+	if err != nil {
+		return QFont{}, err
+	}
+	switch {
+	case bits&fontBitItalic != 0:
+		f.Style = FontStyleItalic
+	case bits&fontBitOblique != 0:
+		f.Style = FontStyleOblique
+	}
+	f.Underline = bits&fontBitUnderline != 0
+	f.Overline = bits&fontBitOverline != 0
+	f.StrikeOut = bits&fontBitStrikeOut != 0
+	f.FixedPitch = bits&fontBitFixedPitch != 0
+	f.Kerning = bits&fontBitKerning != 0
+	if r.version >= VersionQt4_3 {
+		if f.Stretch, err = r.ReadUint16(); err != nil {
+			return QFont{}, err
+		}
+	}
+	if r.version >= VersionQt4_4 {
+		bits, err := r.ReadUint8()
+		if err != nil {
+			return QFont{}, err
+		}
+		f.IgnorePitch = bits&fontBitIgnorePitch != 0
+		f.LetterSpacingAbsolute = bits&fontBitLetterSpacingAbsolute != 0
+	}
+	if r.version >= VersionQt4_5 {
+		if f.LetterSpacing, err = r.ReadInt32(); err != nil {
+			return QFont{}, err
+		}
+		if f.WordSpacing, err = r.ReadInt32(); err != nil {
+			return QFont{}, err
+		}
+	}
+	if r.version >= VersionQt5_4 {
+		if f.HintingPreference, err = r.ReadUint8(); err != nil {
+			return QFont{}, err
+		}
+	}
+	if r.version >= VersionQt5_6 {
+		if f.Capitalization, err = r.ReadUint8(); err != nil {
+			return QFont{}, err
+		}
+	}
+	if r.version >= VersionQt5_13 {
+		if f.Families, err = r.ReadQStringQStringList(); err != nil {
+			return QFont{}, err
+		}
+	}
+	if r.version >= VersionQt6_6 {
+		if f.Features, err = ReadMap(r, (*Reader).ReadUint32, (*Reader).ReadUint32); err != nil {
+			return QFont{}, err
+		}
+	}
+	if r.version >= VersionQt6_7 {
+		if f.VariableAxes, err = ReadMap(r, (*Reader).ReadUint32, (*Reader).ReadFloat); err != nil {
+			return QFont{}, err
+		}
+	}
+	return f, nil
+}
+
+func (w *Writer) WriteQFont(v QFont) error {
+	if err := w.WriteQString(v.Family); err != nil {
+		return err
+	}
+	if w.version >= VersionQt5_4 {
+		if err := w.WriteQString(v.StyleName); err != nil {
+			return err
+		}
+	}
+	if err := w.WriteDouble(v.PointSize); err != nil {
+		return err
+	}
+	if err := w.WriteInt32(v.PixelSize); err != nil {
+		return err
+	}
+	if err := w.WriteUint8(v.StyleHint); err != nil {
+		return err
+	}
+	if w.version >= VersionQt5_4 {
+		if err := w.WriteUint16(v.StyleStrategy); err != nil {
+			return err
+		}
+	} else {
+		if err := w.WriteUint8(uint8(v.StyleStrategy)); err != nil {
+			return err
+		}
+	}
+	if w.version >= VersionQt6_0 {
+		if err := w.WriteUint16(v.Weight); err != nil {
+			return err
+		}
+	} else {
+		if err := w.WriteUint8(0); err != nil {
+			return err
+		}
+		if err := w.WriteUint8(uint8(v.Weight)); err != nil {
+			return err
+		}
+	}
+	var bits uint8
+	switch v.Style {
+	case FontStyleItalic:
+		bits |= fontBitItalic
+	case FontStyleOblique:
+		bits |= fontBitOblique
+	}
+	for _, f := range []struct {
+		set bool
+		bit uint8
+	}{
+		{v.Underline, fontBitUnderline},
+		{v.Overline, fontBitOverline},
+		{v.StrikeOut, fontBitStrikeOut},
+		{v.FixedPitch, fontBitFixedPitch},
+		{v.Kerning, fontBitKerning},
+	} {
+		if f.set {
+			bits |= f.bit
+		}
+	}
+	if err := w.WriteUint8(bits); err != nil {
+		return err
+	}
+	if w.version >= VersionQt4_3 {
+		if err := w.WriteUint16(v.Stretch); err != nil {
+			return err
+		}
+	}
+	if w.version >= VersionQt4_4 {
+		var bits uint8
+		if v.IgnorePitch {
+			bits |= fontBitIgnorePitch
+		}
+		if v.LetterSpacingAbsolute {
+			bits |= fontBitLetterSpacingAbsolute
+		}
+		if err := w.WriteUint8(bits); err != nil {
+			return err
+		}
+	}
+	if w.version >= VersionQt4_5 {
+		if err := w.WriteInt32(v.LetterSpacing); err != nil {
+			return err
+		}
+		if err := w.WriteInt32(v.WordSpacing); err != nil {
+			return err
+		}
+	}
+	if w.version >= VersionQt5_4 {
+		if err := w.WriteUint8(v.HintingPreference); err != nil {
+			return err
+		}
+	}
+	if w.version >= VersionQt5_6 {
+		if err := w.WriteUint8(v.Capitalization); err != nil {
+			return err
+		}
+	}
+	if w.version >= VersionQt5_13 {
+		if err := w.WriteQStringQStringList(v.Families); err != nil {
+			return err
+		}
+	}
+	less := func(a, b uint32) bool { return a < b }
+	if w.version >= VersionQt6_6 {
+		if err := WriteMap(w, v.Features, less, (*Writer).WriteUint32, (*Writer).WriteUint32); err != nil {
+			return err
+		}
+	}
+	if w.version >= VersionQt6_7 {
+		if err := WriteMap(w, v.VariableAxes, less, (*Writer).WriteUint32, (*Writer).WriteFloat); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,97 @@
+package cutestream
+
+import (
+	"bytes"
+	"encoding/hex"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// QVariant(QFont("Arial", 10)) with Qt 5.15 and double precision
+const fontHex = "0000004000" +
+	"0000000a0041007200690061006c" + // family
+	"ffffffff" + // style name
+	"4024000000000000" + // point size
+	"ffffffff" + // pixel size
+	"05" + "0001" + // style hint, style strategy
+	"00" + "32" + // charset, weight
+	"10" + // kerning
+	"0000" + // stretch
+	"00" + // extended bits
+	"00000000" + "00000000" + // letter and word spacing
+	"00" + "00" + // hinting preference, capitalization
+	"00000000" // families
+
+func TestReadQFont(t *testing.T) {
+	data, _ := hex.DecodeString(fontHex)
+	reader, err := NewReaderWithVersion(bytes.NewReader(data), VersionQt5_13)
+	assert.Nil(t, err)
+	reader.DoublePrecision = true
+	readType, v, err := reader.ReadQVariant()
+	assert.Nil(t, err)
+	assert.Equal(t, QMetaTypeQFont, readType)
+	assert.Equal(t, QFont{
+		Family:        "Arial",
+		PointSize:     10,
+		PixelSize:     -1,
+		StyleHint:     5,
+		StyleStrategy: 1,
+		Weight:        50,
+		Kerning:       true,
+		Families:      []string{},
+	}, v)
+}
+
+func TestQFontRoundTrip(t *testing.T) {
+	qt4 := QFont{Family: "Arial", PointSize: 12, PixelSize: -1, Weight: 50, Style: FontStyleItalic, Stretch: 100}
+	qt5 := QFont{
+		Family:                "Noto Sans",
+		StyleName:             "Bold Italic",
+		PointSize:             -1,
+		PixelSize:             14,
+		StyleHint:             2,
+		StyleStrategy:         0x0100,
+		Weight:                75,
+		Style:                 FontStyleOblique,
+		Underline:             true,
+		Overline:              true,
+		StrikeOut:             true,
+		FixedPitch:            true,
+		Stretch:               100,
+		IgnorePitch:           true,
+		LetterSpacingAbsolute: true,
+		LetterSpacing:         128,
+		WordSpacing:           -64,
+		HintingPreference:     1,
+		Capitalization:        4,
+		Families:              []string{"Noto Sans", "DejaVu Sans"},
+	}
+	qt6 := qt5
+	qt6.Weight = 700
+	qt6.Features = map[uint32]uint32{0x6b65726e: 0}
+	qt6.VariableAxes = map[uint32]float32{0x77676874: 650}
+
+	fonts := []struct {
+		version int
+		font    QFont
+	}{
+		{VersionQt4_0, QFont{Family: "Arial", PointSize: 12, PixelSize: -1, Weight: 50, Underline: true}},
+		{VersionQt4_6, qt4},
+		{VersionQt5_13, qt5},
+		{VersionQt6_0, QFont{Family: "Arial", PointSize: 12, PixelSize: -1, Weight: 400, Families: []string{}}},
+		{VersionQt6_7, qt6},
+	}
+	for _, f := range fonts {
+		var buf bytes.Buffer
+		writer, err := NewWriterWithVersion(&buf, f.version)
+		assert.Nil(t, err)
+		assert.Nil(t, writer.WriteQVariant(QMetaTypeQFont, f.font))
+		reader, err := NewReaderWithVersion(&buf, f.version)
+		assert.Nil(t, err)
+		_, v, err := reader.ReadQVariant()
+		assert.Nil(t, err)
+		assert.Equal(t, f.font, v, "version %d", f.version)
+		assert.Equal(t, 0, buf.Len())
+	}
+}
@@ -0,0 +1,247 @@
+package cutestream
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"time"
+)
+
+// ErrFrameTooLarge is returned when a frame exceeds the configured maximum size
+var ErrFrameTooLarge = errors.New("frame too large")
+
+// DefaultMaxFrameSize is the maximum frame size used by NewFrameReader and NewFrameWriter
+const DefaultMaxFrameSize = 64 << 20
+
+type readDeadliner interface {
+	SetReadDeadline(t time.Time) error
+}
+
+type writeDeadliner interface {
+	SetWriteDeadline(t time.Time) error
+}
+
+// FrameReader reads length-prefixed messages, as commonly sent by Qt applications
+// over QTcpSocket or QLocalSocket: a block size followed by a QDataStream payload
+type FrameReader struct {
+	Reader             io.Reader
+	ByteOrder          binary.ByteOrder
+	version            int
+	DoublePrecision    bool          // Use Double precision for floats in the frames
+	SizeWidth          int           // Size of the block size field in bytes: 2, 4 or 8
+	SizeIncludesHeader bool          // Whether the block size counts the size field itself
+	MaxFrameSize       uint64        // Frames larger than this are rejected. 0 means no limit
+	Timeout            time.Duration // Time allowed to read a frame if the reader supports deadlines (e.g. net.Conn). 0 means no deadline
+}
+
+// NewFrameReader creates a new FrameReader with a 4-byte big endian block size,
+// version 19 and single precision payloads
+func NewFrameReader(reader io.Reader) *FrameReader {
+	return &FrameReader{
+		Reader:       reader,
+		ByteOrder:    binary.BigEndian,
+		version:      19,
+		SizeWidth:    4,
+		MaxFrameSize: DefaultMaxFrameSize,
+	}
+}
+
+func (f *FrameReader) SetVersion(version int) error {
+	if err := checkVersion(version); err != nil {
+		return err
+	}
+	f.version = version
+	return nil
+}
+
+// ReadFrame reads the next frame and returns its payload.
+// io.EOF is returned if the stream ends at a frame boundary
+func (f *FrameReader) ReadFrame() ([]byte, error) {
+	if err := checkSizeWidth(f.SizeWidth); err != nil {
+		return nil, err
+	}
+	if f.Timeout > 0 {
+		if d, ok := f.Reader.(readDeadliner); ok {
+			if err := d.SetReadDeadline(time.Now().Add(f.Timeout)); err != nil {
+				return nil, err
+			}
+		}
+	}
+	header := make([]byte, f.SizeWidth)
+	if _, err := io.ReadFull(f.Reader, header); err != nil {
+		return nil, err
+	}
+	var size uint64
+	switch f.SizeWidth {
+	case 2:
+		size = uint64(f.ByteOrder.Uint16(header))
+	case 4:
+		size = uint64(f.ByteOrder.Uint32(header))
+	default:
+		size = f.ByteOrder.Uint64(header)
+	}
+	if f.SizeIncludesHeader {
+		if size < uint64(f.SizeWidth) {
+			return nil, fmt.Errorf("invalid frame size %d", size)
+		}
+		size -= uint64(f.SizeWidth)
+	}
+	if f.MaxFrameSize > 0 && size > f.MaxFrameSize {
+		return nil, fmt.Errorf("%w: %d bytes", ErrFrameTooLarge, size)
+	}
+	if size > math.MaxInt64 {
+		return nil, fmt.Errorf("invalid frame size %d", size)
+	}
+	if size <= maxPrealloc {
+		buf := make([]byte, size)
+		if _, err := io.ReadFull(f.Reader, buf); err != nil {
+			if err == io.EOF {
+				err = io.ErrUnexpectedEOF
+			}
+			return nil, err
+		}
+		return buf, nil
+	}
+	// larger frames grow with the received data, so a forged size can't allocate it upfront
+	var buf bytes.Buffer
+	buf.Grow(maxPrealloc)
+	if _, err := io.CopyN(&buf, f.Reader, int64(size)); err != nil {
+		if err == io.EOF {
+			err = io.ErrUnexpectedEOF
+		}
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// Next reads the next frame and returns a Reader over its payload
+// using the byte order, version and precision of the FrameReader
+func (f *FrameReader) Next() (Reader, error) {
+	buf, err := f.ReadFrame()
+	if err != nil {
+		return Reader{}, err
+	}
+	r := NewReader(bytes.NewReader(buf))
+	r.ByteOrder = f.ByteOrder
+	r.version = f.version
+	r.DoublePrecision = f.DoublePrecision
+	return r, nil
+}
+
+// FrameWriter buffers a message and writes it prefixed with its size on Flush.
+// FrameWriter implements io.Writer, so it can be used as an underlying writer of Writer
+type FrameWriter struct {
+	Writer             io.Writer
+	ByteOrder          binary.ByteOrder
+	version            int
+	DoublePrecision    bool          // Use Double precision for floats in the frames
+	SizeWidth          int           // Size of the block size field in bytes: 2, 4 or 8
+	SizeIncludesHeader bool          // Whether the block size counts the size field itself
+	MaxFrameSize       uint64        // Frames larger than this are rejected. 0 means no limit
+	Timeout            time.Duration // Time allowed to write a frame if the writer supports deadlines (e.g. net.Conn). 0 means no deadline
+	buf                bytes.Buffer
+}
+
+// NewFrameWriter creates a new FrameWriter with a 4-byte big endian block size,
+// version 19 and single precision payloads
+func NewFrameWriter(writer io.Writer) *FrameWriter {
+	return &FrameWriter{
+		Writer:       writer,
+		ByteOrder:    binary.BigEndian,
+		version:      19,
+		SizeWidth:    4,
+		MaxFrameSize: DefaultMaxFrameSize,
+	}
+}
+
+func (f *FrameWriter) SetVersion(version int) error {
+	if err := checkVersion(version); err != nil {
+		return err
+	}
+	f.version = version
+	return nil
+}
+
+// Write appends data to the current frame
+func (f *FrameWriter) Write(p []byte) (int, error) {
+	return f.buf.Write(p)
+}
+
+// Buffered returns the size of the current frame
+func (f *FrameWriter) Buffered() int {
+	return f.buf.Len()
+}
+
+// Flush writes the current frame prefixed with its size and starts a new one
+func (f *FrameWriter) Flush() error {
+	defer f.buf.Reset()
+	if err := checkSizeWidth(f.SizeWidth); err != nil {
+		return err
+	}
+	size := uint64(f.buf.Len())
+	if f.MaxFrameSize > 0 && size > f.MaxFrameSize {
+		return fmt.Errorf("%w: %d bytes", ErrFrameTooLarge, size)
+	}
+	if f.SizeIncludesHeader {
+		size += uint64(f.SizeWidth)
+	}
+	header := make([]byte, f.SizeWidth)
+	switch f.SizeWidth {
+	case 2:
+		if size > 0xFFFF {
+			return fmt.Errorf("%w: %d bytes", ErrFrameTooLarge, size)
+		}
+		f.ByteOrder.PutUint16(header, uint16(size))
+	case 4:
+		if size > 0xFFFFFFFF {
+			return fmt.Errorf("%w: %d bytes", ErrFrameTooLarge, size)
+		}
+		f.ByteOrder.PutUint32(header, uint32(size))
+	default:
+		f.ByteOrder.PutUint64(header, size)
+	}
+	if f.Timeout > 0 {
+		if d, ok := f.Writer.(writeDeadliner); ok {
+			if err := d.SetWriteDeadline(time.Now().Add(f.Timeout)); err != nil {
+				return err
+			}
+		}
+	}
+	// a single write keeps the frame intact if the writer is shared
+	frame := append(header, f.buf.Bytes()...)
+	_, err := f.Writer.Write(frame)
+	return err
+}
+
+// checkSizeWidth checks the width of the block size field before it is allocated
+func checkSizeWidth(width int) error {
+	switch width {
+	case 2, 4, 8:
+		return nil
+	default:
+		return fmt.Errorf("unsupported frame size width %d", width)
+	}
+}
+
+// Stream returns a Writer appending to the current frame
+// using the byte order, version and precision of the FrameWriter
+func (f *FrameWriter) Stream() Writer {
+	w := NewWriter(f)
+	w.ByteOrder = f.ByteOrder
+	w.version = f.version
+	w.DoublePrecision = f.DoublePrecision
+	return w
+}
+
+// WriteFrame encodes a message with the specified function and sends it as a single frame
+func (f *FrameWriter) WriteFrame(encode func(w *Writer) error) error {
+	w := f.Stream()
+	if err := encode(&w); err != nil {
+		f.buf.Reset()
+		return err
+	}
+	return f.Flush()
+}
@@ -0,0 +1,124 @@
+package cutestream
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFrameRoundTrip(t *testing.T) {
+	for _, width := range []int{2, 4, 8} {
+		for _, includesHeader := range []bool{false, true} {
+			var buf bytes.Buffer
+			fw := NewFrameWriter(&buf)
+			fw.SizeWidth = width
+			fw.SizeIncludesHeader = includesHeader
+			for i := 0; i < 3; i++ {
+				err := fw.WriteFrame(func(w *Writer) error {
+					if err := w.WriteInt32(int32(i)); err != nil {
+						return err
+					}
+					return w.WriteQString("lap")
+				})
+				assert.Nil(t, err)
+			}
+			assert.Equal(t, 3*(width+4+4+6), buf.Len())
+
+			fr := NewFrameReader(&buf)
+			fr.SizeWidth = width
+			fr.SizeIncludesHeader = includesHeader
+			for i := 0; i < 3; i++ {
+				r, err := fr.Next()
+				assert.Nil(t, err)
+				n, err := r.ReadInt32()
+				assert.Nil(t, err)
+				assert.Equal(t, int32(i), n)
+				s, err := r.ReadQString()
+				assert.Nil(t, err)
+				assert.Equal(t, "lap", s)
+			}
+			_, err := fr.Next()
+			assert.Equal(t, io.EOF, err)
+		}
+	}
+}
+
+func TestFrameQtBlockSize(t *testing.T) {
+	// QDataStream out; out << quint32(0) << QString("ok"); out.device()->seek(0); out << quint32(block.size() - 4);
+	data := []byte{0, 0, 0, 8, 0, 0, 0, 4, 0, 'o', 0, 'k'}
+	r, err := NewFrameReader(bytes.NewReader(data)).Next()
+	assert.Nil(t, err)
+	s, err := r.ReadQString()
+	assert.Nil(t, err)
+	assert.Equal(t, "ok", s)
+}
+
+func TestFrameErrors(t *testing.T) {
+	fr := NewFrameReader(bytes.NewReader([]byte{0, 0, 1, 0, 1, 2}))
+	fr.MaxFrameSize = 16
+	_, err := fr.ReadFrame()
+	assert.True(t, errors.Is(err, ErrFrameTooLarge))
+
+	fr = NewFrameReader(bytes.NewReader([]byte{0, 0, 0, 4, 1, 2}))
+	_, err = fr.ReadFrame()
+	assert.Equal(t, io.ErrUnexpectedEOF, err)
+
+	// an unlimited frame size isn't allocated before the data is received
+	fr = NewFrameReader(bytes.NewReader([]byte{0x7f, 0xff, 0xff, 0xff, 1, 2}))
+	fr.MaxFrameSize = 0
+	_, err = fr.ReadFrame()
+	assert.Equal(t, io.ErrUnexpectedEOF, err)
+	fr = NewFrameReader(bytes.NewReader([]byte{0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff}))
+	fr.SizeWidth = 8
+	fr.MaxFrameSize = 0
+	_, err = fr.ReadFrame()
+	assert.NotNil(t, err)
+
+	for _, width := range []int{-1, 0, 3} {
+		fr = NewFrameReader(bytes.NewReader([]byte{0, 0, 0, 0}))
+		fr.SizeWidth = width
+		_, err = fr.ReadFrame()
+		assert.NotNil(t, err)
+		fw := NewFrameWriter(&bytes.Buffer{})
+		fw.SizeWidth = width
+		assert.NotNil(t, fw.Flush())
+	}
+
+	fw := NewFrameWriter(&bytes.Buffer{})
+	fw.SizeWidth = 2
+	fw.MaxFrameSize = 0
+	_, _ = fw.Write(make([]byte, 0x10000))
+	assert.True(t, errors.Is(fw.Flush(), ErrFrameTooLarge))
+	assert.Equal(t, 0, fw.Buffered())
+}
+
+func TestFrameDeadline(t *testing.T) {
+	client, server := net.Pipe()
+	defer client.Close()
+	defer server.Close()
+
+	fr := NewFrameReader(server)
+	fr.Timeout = 10 * time.Millisecond
+	_, err := fr.Next()
+	var netErr net.Error
+	assert.True(t, errors.As(err, &netErr) && netErr.Timeout())
+
+	go func() {
+		fw := NewFrameWriter(client)
+		fw.Timeout = time.Second
+		_ = fw.WriteFrame(func(w *Writer) error {
+			return w.WriteUint16(42)
+		})
+	}()
+	fr.Timeout = time.Second
+	r, err := fr.Next()
+	assert.Nil(t, err)
+	v, err := r.ReadUint16()
+	assert.Nil(t, err)
+	assert.Equal(t, uint16(42), v)
+}
@@ -0,0 +1,189 @@
+package cutestream
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// fuzzTypes are the metatypes supported by ReadValue
+var fuzzTypes = []QMetaType{
+	QMetaTypeUnknown, QMetaTypeBool, QMetaTypeInt, QMetaTypeUInt, QMetaTypeLongLong, QMetaTypeULongLong,
+	QMetaTypeDouble, QMetaTypeFloat, QMetaTypeChar, QMetaTypeUChar, QMetaTypeSChar, QMetaTypeShort,
+	QMetaTypeUShort, QMetaTypeQChar, QMetaTypeChar16, QMetaTypeChar32, QMetaTypeNullptr,
+	QMetaTypeQBitArray, QMetaTypeQByteArray, QMetaTypeQByteArrayList, QMetaTypeQString,
+	QMetaTypeQStringList, QMetaTypeQUuid, QMetaTypeQDate, QMetaTypeQTime, QMetaTypeQDateTime, QMetaTypeQUrl,
+	QMetaTypeQVariant, QMetaTypeQVariantList, QMetaTypeQVariantMap, QMetaTypeQVariantHash,
+	QMetaTypeQPoint, QMetaTypeQPointF, QMetaTypeQSize, QMetaTypeQSizeF, QMetaTypeQRect, QMetaTypeQRectF,
+	QMetaTypeQLine, QMetaTypeQLineF, QMetaTypeQPolygon, QMetaTypeQPolygonF, QMetaTypeQRegion,
+	QMetaTypeQColor, QMetaTypeQFont, QMetaTypeQBrush, QMetaTypeQPen, QMetaTypeQTransform, QMetaTypeQMatrix,
+	QMetaTypeQMatrix4x4, QMetaTypeQVector2D, QMetaTypeQVector3D, QMetaTypeQVector4D, QMetaTypeQQuaternion,
+	QMetaTypeQImage, QMetaTypeQPixmap, QMetaTypeQBitmap, QMetaTypeQLocale, QMetaTypeQRegExp,
+	QMetaTypeQRegularExpression, QMetaTypeQEasingCurve, QMetaTypeQKeySequence, QMetaTypeQCursor,
+	QMetaTypeQSizePolicy, QMetaTypeQTextLength, QMetaTypeQTextFormat, QMetaTypeQPalette, QMetaTypeQIcon,
+}
+
+// fuzzSeeds are QVariant streams written by Qt 5
+var fuzzSeeds = []string{
+	"0000000200" + "00000003",
+	"0000000a00" + "00000006005300700061",
+	"0000000a01" + "ffffffff",
+	"0000000600" + "3ff8000000000000",
+	"0000001000" + "00000000002582ae" + "02ebdd00" + "01",
+	"0000000900" + "00000002" + "0000000200" + "00000001" + "0000000a00" + "00000002" + "0061",
+	"0000000800" + "00000001" + "00000006006c00610070" + "0000000200" + "00000003",
+	"0000000d00" + "0000000a" + "0502",
+	"0000040000" + "0000000f" + hex.EncodeToString([]byte("QVersionNumber\x00")) + "00000002" + "00000005" + "0000000f",
+}
+
+func fuzzStreams(data []byte, version uint8, flags uint8) (Reader, func(*bytes.Buffer) Writer) {
+	v := VersionQt4_0 + int(version)%(VersionQt6_7-VersionQt4_0+1)
+	order := binary.ByteOrder(binary.BigEndian)
+	if flags&1 != 0 {
+		order = binary.LittleEndian
+	}
+	r, _ := NewReaderWithVersion(bytes.NewReader(data), v)
+	r.ByteOrder = order
+	r.DoublePrecision = flags&2 != 0
+	r.OrderedMaps = flags&4 != 0
+	r.NullStrings = flags&8 != 0
+	r.MaxAllocSize = 1 << 20
+	return r, func(buf *bytes.Buffer) Writer {
+		w, _ := NewWriterWithVersion(buf, v)
+		w.ByteOrder = order
+		w.DoublePrecision = r.DoublePrecision
+		return w
+	}
+}
+
+// checkRoundTrip writes a decoded value, reads it back and writes it again: both encodings must be equal
+func checkRoundTrip(t *testing.T, r Reader, newWriter func(*bytes.Buffer) Writer, write func(*Writer) error, read func(*Reader) (interface{}, error), writeAgain func(*Writer, interface{}) error) {
+	var first bytes.Buffer
+	w := newWriter(&first)
+	if err := write(&w); err != nil {
+		// values without a representation in the stream version, e.g. offset date times before Qt 5.2
+		return
+	}
+	reread := r
+	reread.Reader = bytes.NewReader(first.Bytes())
+	v, err := read(&reread)
+	if err != nil {
+		t.Fatalf("reading %x back: %v", first.Bytes(), err)
+	}
+	var second bytes.Buffer
+	w = newWriter(&second)
+	if err := writeAgain(&w, v); err != nil {
+		t.Fatalf("writing %#v again: %v", v, err)
+	}
+	if !bytes.Equal(first.Bytes(), second.Bytes()) {
+		t.Fatalf("unstable encoding of %#v: %x, then %x", v, first.Bytes(), second.Bytes())
+	}
+}
+
+func FuzzReadQVariant(f *testing.F) {
+	for _, seed := range fuzzSeeds {
+		data, _ := hex.DecodeString(seed)
+		f.Add(data, uint8(VersionQt5_13-VersionQt4_0), uint8(2))
+	}
+	f.Fuzz(func(t *testing.T, data []byte, version uint8, flags uint8) {
+		r, newWriter := fuzzStreams(data, version, flags)
+		for {
+			variant, err := r.ReadVariant()
+			if err != nil {
+				return
+			}
+			checkRoundTrip(t, r, newWriter,
+				func(w *Writer) error { return w.WriteQVariant(variant.Type, variant.Value) },
+				func(r *Reader) (interface{}, error) { return r.ReadVariant() },
+				func(w *Writer, v interface{}) error { return w.WriteQVariant(v.(Variant).Type, v.(Variant).Value) })
+		}
+	})
+}
+
+func FuzzReadValue(f *testing.F) {
+	for _, seed := range fuzzSeeds {
+		data, _ := hex.DecodeString(seed)
+		f.Add(data[5:], uint8(VersionQt5_13-VersionQt4_0), uint8(2), uint8(0))
+	}
+	f.Fuzz(func(t *testing.T, data []byte, version uint8, flags uint8, typeIndex uint8) {
+		r, newWriter := fuzzStreams(data, version, flags)
+		metaType := fuzzTypes[int(typeIndex)%len(fuzzTypes)]
+		v, err := r.ReadValue(metaType)
+		if err != nil {
+			return
+		}
+		checkRoundTrip(t, r, newWriter,
+			func(w *Writer) error { return w.WriteValue(metaType, v) },
+			func(r *Reader) (interface{}, error) { return r.ReadValue(metaType) },
+			func(w *Writer, v interface{}) error { return w.WriteValue(metaType, v) })
+	})
+}
+
+func FuzzReadQPainterPath(f *testing.F) {
+	f.Add([]byte{0, 0, 0, 1, 0, 0, 0, 0, 0x3f, 0xf0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0}, uint8(12), uint8(2))
+	f.Fuzz(func(t *testing.T, data []byte, version uint8, flags uint8) {
+		r, newWriter := fuzzStreams(data, version, flags)
+		path, err := r.ReadQPainterPath()
+		if err != nil {
+			return
+		}
+		checkRoundTrip(t, r, newWriter,
+			func(w *Writer) error { return w.WriteQPainterPath(path) },
+			func(r *Reader) (interface{}, error) { return r.ReadQPainterPath() },
+			func(w *Writer, v interface{}) error { return w.WriteQPainterPath(v.(QPainterPath)) })
+	})
+}
+
+func FuzzReadQCompressed(f *testing.F) {
+	data, _ := hex.DecodeString(qCompressedHex)
+	f.Add(data, uint8(VersionQt5_13-VersionQt4_0), uint8(0))
+	f.Fuzz(func(t *testing.T, data []byte, version uint8, flags uint8) {
+		r, _ := fuzzStreams(data, version, flags)
+		uncompressed, err := r.ReadQCompressed()
+		if err != nil {
+			return
+		}
+		for {
+			if _, err := uncompressed.ReadVariant(); err != nil {
+				return
+			}
+		}
+	})
+}
+
+func TestCorruptedInput(t *testing.T) {
+	read := func(data string, read func(r *Reader) error) error {
+		b, _ := hex.DecodeString(data)
+		r := NewReader(bytes.NewReader(b))
+		r.MaxAllocSize = 1 << 20
+		return read(&r)
+	}
+
+	// an empty bit array used to index past its buffer
+	err := read("00000000", func(r *Reader) error {
+		bits, err := r.ReadQBitArray()
+		assert.Empty(t, bits)
+		return err
+	})
+	assert.Nil(t, err)
+
+	// lengths larger than the data fail without allocating them
+	err = read("7fffffff"+"0061", func(r *Reader) error { _, err := r.ReadQByteArray(); return err })
+	assert.True(t, errors.Is(err, ErrAllocLimit))
+	err = read("000fffff"+"0061", func(r *Reader) error { _, err := r.ReadQString(); return err })
+	assert.NotNil(t, err)
+	err = read("ffffff00", func(r *Reader) error { _, err := r.ReadQBitArray(); return err })
+	assert.True(t, errors.Is(err, ErrAllocLimit))
+	err = read("ffffffff", func(r *Reader) error { _, err := r.ReadQStringQVariantList(); return err })
+	assert.NotNil(t, err)
+
+	// a QVariantList nested deeper than MaxNestingDepth
+	nested := strings.Repeat("0000000900"+"00000001", MaxNestingDepth+1) + "0000000200" + "00000001"
+	err = read(nested, func(r *Reader) error { _, err := r.ReadVariant(); return err })
+	assert.True(t, errors.Is(err, ErrTooDeep))
+}
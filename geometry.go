@@ -0,0 +1,180 @@
+package cutestream
+
+// QPoint is a point with integer coordinates
+type QPoint struct {
+	X, Y int32
+}
+
+// QPointF is a point with floating point coordinates
+type QPointF struct {
+	X, Y float64
+}
+
+// QSize is a size with integer dimensions
+type QSize struct {
+	Width, Height int32
+}
+
+// QSizeF is a size with floating point dimensions
+type QSizeF struct {
+	Width, Height float64
+}
+
+// QRect is a rectangle with integer coordinates
+type QRect struct {
+	X, Y, Width, Height int32
+}
+
+// QRectF is a rectangle with floating point coordinates
+type QRectF struct {
+	X, Y, Width, Height float64
+}
+
+// QLine is a line with integer coordinates
+type QLine struct {
+	P1, P2 QPoint
+}
+
+// QLineF is a line with floating point coordinates
+type QLineF struct {
+	P1, P2 QPointF
+}
+
+func (r *Reader) ReadQPoint() (QPoint, error) {
+	x, err := r.ReadInt32()
+	if err != nil {
+		return QPoint{}, err
+	}
+	y, err := r.ReadInt32()
+	if err != nil {
+		return QPoint{}, err
+	}
+	return QPoint{x, y}, nil
+}
+
+func (r *Reader) ReadQPointF() (QPointF, error) {
+	x, err := r.ReadDouble()
+	if err != nil {
+		return QPointF{}, err
+	}
+	y, err := r.ReadDouble()
+	if err != nil {
+		return QPointF{}, err
+	}
+	return QPointF{x, y}, nil
+}
+
+func (r *Reader) ReadQSize() (QSize, error) {
+	p, err := r.ReadQPoint()
+	return QSize{p.X, p.Y}, err
+}
+
+func (r *Reader) ReadQSizeF() (QSizeF, error) {
+	p, err := r.ReadQPointF()
+	return QSizeF{p.X, p.Y}, err
+}
+
+// ReadQRect reads a rectangle. QRect is serialized as left, top, right and bottom,
+// where right and bottom are inclusive
+func (r *Reader) ReadQRect() (QRect, error) {
+	topLeft, err := r.ReadQPoint()
+	if err != nil {
+		return QRect{}, err
+	}
+	bottomRight, err := r.ReadQPoint()
+	if err != nil {
+		return QRect{}, err
+	}
+	return QRect{
+		X:      topLeft.X,
+		Y:      topLeft.Y,
+		Width:  bottomRight.X - topLeft.X + 1,
+		Height: bottomRight.Y - topLeft.Y + 1,
+	}, nil
+}
+
+func (r *Reader) ReadQRectF() (QRectF, error) {
+	p, err := r.ReadQPointF()
+	if err != nil {
+		return QRectF{}, err
+	}
+	s, err := r.ReadQSizeF()
+	if err != nil {
+		return QRectF{}, err
+	}
+	return QRectF{p.X, p.Y, s.Width, s.Height}, nil
+}
+
+func (r *Reader) ReadQLine() (QLine, error) {
+	p1, err := r.ReadQPoint()
+	if err != nil {
+		return QLine{}, err
+	}
+	p2, err := r.ReadQPoint()
+	if err != nil {
+		return QLine{}, err
+	}
+	return QLine{p1, p2}, nil
+}
+
+func (r *Reader) ReadQLineF() (QLineF, error) {
+	p1, err := r.ReadQPointF()
+	if err != nil {
+		return QLineF{}, err
+	}
+	p2, err := r.ReadQPointF()
+	if err != nil {
+		return QLineF{}, err
+	}
+	return QLineF{p1, p2}, nil
+}
+
+func (w *Writer) WriteQPoint(v QPoint) error {
+	if err := w.WriteInt32(v.X); err != nil {
+		return err
+	}
+	return w.WriteInt32(v.Y)
+}
+
+func (w *Writer) WriteQPointF(v QPointF) error {
+	if err := w.WriteDouble(v.X); err != nil {
+		return err
+	}
+	return w.WriteDouble(v.Y)
+}
+
+func (w *Writer) WriteQSize(v QSize) error {
+	return w.WriteQPoint(QPoint{v.Width, v.Height})
+}
+
+func (w *Writer) WriteQSizeF(v QSizeF) error {
+	return w.WriteQPointF(QPointF{v.Width, v.Height})
+}
+
+func (w *Writer) WriteQRect(v QRect) error {
+	if err := w.WriteQPoint(QPoint{v.X, v.Y}); err != nil {
+		return err
+	}
+	return w.WriteQPoint(QPoint{v.X + v.Width - 1, v.Y + v.Height - 1})
+}
+
+func (w *Writer) WriteQRectF(v QRectF) error {
+	if err := w.WriteQPointF(QPointF{v.X, v.Y}); err != nil {
+		return err
+	}
+	return w.WriteQSizeF(QSizeF{v.Width, v.Height})
+}
+
+func (w *Writer) WriteQLine(v QLine) error {
+	if err := w.WriteQPoint(v.P1); err != nil {
+		return err
+	}
+	return w.WriteQPoint(v.P2)
+}
+
+func (w *Writer) WriteQLineF(v QLineF) error {
+	if err := w.WriteQPointF(v.P1); err != nil {
+		return err
+	}
+	return w.WriteQPointF(v.P2)
+}
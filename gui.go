@@ -0,0 +1,482 @@
+package cutestream
+
+import (
+	"fmt"
+	"image"
+	"math/bits"
+)
+
+// CursorShape is the shape of a mouse cursor (Qt::CursorShape)
+type CursorShape int16
+
+const (
+	ArrowCursor        CursorShape = 0
+	UpArrowCursor      CursorShape = 1
+	CrossCursor        CursorShape = 2
+	WaitCursor         CursorShape = 3
+	IBeamCursor        CursorShape = 4
+	SizeVerCursor      CursorShape = 5
+	SizeHorCursor      CursorShape = 6
+	SizeBDiagCursor    CursorShape = 7
+	SizeFDiagCursor    CursorShape = 8
+	SizeAllCursor      CursorShape = 9
+	BlankCursor        CursorShape = 10
+	SplitVCursor       CursorShape = 11
+	SplitHCursor       CursorShape = 12
+	PointingHandCursor CursorShape = 13
+	ForbiddenCursor    CursorShape = 14
+	WhatsThisCursor    CursorShape = 15
+	BusyCursor         CursorShape = 16
+	OpenHandCursor     CursorShape = 17
+	ClosedHandCursor   CursorShape = 18
+	DragCopyCursor     CursorShape = 19
+	DragMoveCursor     CursorShape = 20
+	DragLinkCursor     CursorShape = 21
+	BitmapCursor       CursorShape = 24
+)
+
+// QCursor is a mouse cursor. Bitmap cursors are made either of a pixmap
+// or of a bitmap and its mask
+type QCursor struct {
+	Shape   CursorShape
+	Pixmap  image.Image
+	Bitmap  image.Image
+	Mask    image.Image
+	HotSpot QPoint
+}
+
+// SizePolicy is a layout policy of a widget in one direction (QSizePolicy::Policy)
+type SizePolicy uint8
+
+const (
+	SizePolicyFixed            SizePolicy = 0
+	SizePolicyMinimum          SizePolicy = 1
+	SizePolicyMaximum          SizePolicy = 4
+	SizePolicyPreferred        SizePolicy = 5
+	SizePolicyMinimumExpanding SizePolicy = 3
+	SizePolicyExpanding        SizePolicy = 7
+	SizePolicyIgnored          SizePolicy = 13
+)
+
+// QSizePolicy describes how a widget is resized by layouts
+type QSizePolicy struct {
+	HorizontalPolicy     SizePolicy
+	VerticalPolicy       SizePolicy
+	HorizontalStretch    uint8
+	VerticalStretch      uint8
+	ControlType          uint32 // QSizePolicy::ControlType flag, e.g. 0x1 for DefaultType or 0x4 for CheckBox
+	HeightForWidth       bool
+	WidthForHeight       bool
+	RetainSizeWhenHidden bool
+}
+
+// TextLengthType is the unit of a QTextLength (QTextLength::Type)
+type TextLengthType int32
+
+const (
+	VariableLength   TextLengthType = 0
+	FixedLength      TextLengthType = 1
+	PercentageLength TextLengthType = 2
+)
+
+// QTextLength is a length of a text document element, e.g. of a table column
+type QTextLength struct {
+	Type  TextLengthType
+	Value float64 // Pixels for FixedLength, percents for PercentageLength
+}
+
+// Text format types (QTextFormat::FormatType)
+const (
+	InvalidTextFormat = -1
+	BlockTextFormat   = 1
+	CharTextFormat    = 2
+	ListTextFormat    = 3
+	FrameTextFormat   = 5
+	UserTextFormat    = 100
+)
+
+// QTextFormat is a text document format: a type and properties keyed by QTextFormat::Property ids.
+// Property ids are kept as found in the stream, Qt 6 renumbered some of them
+type QTextFormat struct {
+	Type       int32
+	Properties map[int32]Variant
+}
+
+// ColorGroup is a palette color group (QPalette::ColorGroup)
+type ColorGroup int
+
+const (
+	ColorGroupActive   ColorGroup = 0
+	ColorGroupDisabled ColorGroup = 1
+	ColorGroupInactive ColorGroup = 2
+)
+
+// ColorRole is a palette color role (QPalette::ColorRole)
+type ColorRole int
+
+const (
+	PaletteWindowText      ColorRole = 0
+	PaletteButton          ColorRole = 1
+	PaletteLight           ColorRole = 2
+	PaletteMidlight        ColorRole = 3
+	PaletteDark            ColorRole = 4
+	PaletteMid             ColorRole = 5
+	PaletteText            ColorRole = 6
+	PaletteBrightText      ColorRole = 7
+	PaletteButtonText      ColorRole = 8
+	PaletteBase            ColorRole = 9
+	PaletteWindow          ColorRole = 10
+	PaletteShadow          ColorRole = 11
+	PaletteHighlight       ColorRole = 12
+	PaletteHighlightedText ColorRole = 13
+	PaletteLink            ColorRole = 14
+	PaletteLinkVisited     ColorRole = 15
+	PaletteAlternateBase   ColorRole = 16
+	PaletteNoRole          ColorRole = 17
+	PaletteToolTipBase     ColorRole = 18
+	PaletteToolTipText     ColorRole = 19
+	PalettePlaceholderText ColorRole = 20 // Since Qt 5.12
+	PaletteAccent          ColorRole = 21 // Since Qt 6.6
+)
+
+// QPalette holds the brushes of the color roles for each color group, indexed by ColorGroup and ColorRole.
+// Streams of older Qt versions contain fewer roles
+type QPalette [3][]QBrush
+
+// Brush returns the brush of a color role in a color group
+func (p QPalette) Brush(group ColorGroup, role ColorRole) (QBrush, bool) {
+	if group < 0 || int(group) >= len(p) || role < 0 || int(role) >= len(p[group]) {
+		return QBrush{}, false
+	}
+	return p[group][role], true
+}
+
+// paletteRoleCount returns the number of color roles streamed per color group
+func paletteRoleCount(version int) int {
+	switch {
+	case version <= VersionQt4_3:
+		return int(PaletteAlternateBase) + 1
+	case version < VersionQt5_12:
+		return int(PaletteToolTipText) + 1
+	case version < VersionQt6_6:
+		return int(PalettePlaceholderText) + 1
+	default:
+		return int(PaletteAccent) + 1
+	}
+}
+
+// IconMode is the mode an icon pixmap is used in (QIcon::Mode)
+type IconMode uint32
+
+const (
+	IconNormal   IconMode = 0
+	IconDisabled IconMode = 1
+	IconActive   IconMode = 2
+	IconSelected IconMode = 3
+)
+
+// IconState is the state an icon pixmap is used in (QIcon::State)
+type IconState uint32
+
+const (
+	IconOn  IconState = 0
+	IconOff IconState = 1
+)
+
+// IconPixmap is a pixmap of a QIcon. Pixmap is nil for pixmaps added by file name
+// that could not be loaded
+type IconPixmap struct {
+	Pixmap   image.Image
+	FileName string
+	Size     QSize
+	Mode     IconMode
+	State    IconState
+}
+
+// Icon engines supported in QIcon streams
+const (
+	PixmapIconEngine = "QPixmapIconEngine"
+	ThemeIconEngine  = "QIconLoaderEngine"
+)
+
+// QIcon is an icon. Engine is empty for a null icon, icons made of pixmaps
+// use PixmapIconEngine and theme icons use ThemeIconEngine with ThemeName
+type QIcon struct {
+	Engine    string
+	Pixmaps   []IconPixmap
+	ThemeName string
+}
+
+func (r *Reader) ReadQCursor() (QCursor, error) {
+	shape, err := r.ReadInt16()
+	if err != nil {
+		return QCursor{}, err
+	}
+	c := QCursor{Shape: CursorShape(shape)}
+	if c.Shape != BitmapCursor {
+		return c, nil
+	}
+	isPixmap, err := r.ReadBool()
+	if err != nil {
+		return QCursor{}, err
+	}
+	if isPixmap {
+		if c.Pixmap, err = r.ReadQPixmap(); err != nil {
+			return QCursor{}, err
+		}
+	} else {
+		if c.Bitmap, err = r.ReadQPixmap(); err != nil {
+			return QCursor{}, err
+		}
+		if c.Mask, err = r.ReadQPixmap(); err != nil {
+			return QCursor{}, err
+		}
+	}
+	if c.HotSpot, err = r.ReadQPoint(); err != nil {
+		return QCursor{}, err
+	}
+	return c, nil
+}
+
+// ReadQSizePolicy reads a size policy, which is packed into 32 bits
+func (r *Reader) ReadQSizePolicy() (QSizePolicy, error) {
+	data, err := r.ReadUint32()
+	if err != nil {
+		return QSizePolicy{}, err
+	}
+	return QSizePolicy{
+		HorizontalPolicy:     SizePolicy(data & 0xf),
+		VerticalPolicy:       SizePolicy(data >> 4 & 0xf),
+		HeightForWidth:       data>>8&1 != 0,
+		ControlType:          1 << (data >> 9 & 0x1f),
+		WidthForHeight:       data>>14&1 != 0,
+		RetainSizeWhenHidden: data>>15&1 != 0,
+		VerticalStretch:      uint8(data >> 16),
+		HorizontalStretch:    uint8(data >> 24),
+	}, nil
+}
+
+func (r *Reader) ReadQTextLength() (QTextLength, error) {
+	t, err := r.ReadInt32()
+	if err != nil {
+		return QTextLength{}, err
+	}
+	v, err := r.ReadDouble()
+	if err != nil {
+		return QTextLength{}, err
+	}
+	return QTextLength{TextLengthType(t), v}, nil
+}
+
+func (r *Reader) ReadQTextFormat() (QTextFormat, error) {
+	t, err := r.ReadInt32()
+	if err != nil {
+		return QTextFormat{}, err
+	}
+	// properties are kept on errors for partial results of KeepUnknownType
+	properties, err := ReadMap(r, (*Reader).ReadInt32, (*Reader).ReadVariant)
+	return QTextFormat{t, properties}, err
+}
+
+// ReadQPalette reads a palette, which is streamed as brushes of all color roles group by group
+func (r *Reader) ReadQPalette() (QPalette, error) {
+	var p QPalette
+	roles := paletteRoleCount(r.version)
+	for group := range p {
+		p[group] = make([]QBrush, roles)
+		for role := range p[group] {
+			var err error
+			if p[group][role], err = r.ReadQBrush(); err != nil {
+				return QPalette{}, err
+			}
+		}
+	}
+	return p, nil
+}
+
+func (r *Reader) readIconPixmap() (IconPixmap, error) {
+	var p IconPixmap
+	var err error
+	if p.Pixmap, err = r.ReadQPixmap(); err != nil {
+		return IconPixmap{}, err
+	}
+	if p.FileName, err = r.ReadQString(); err != nil {
+		return IconPixmap{}, err
+	}
+	if p.Size, err = r.ReadQSize(); err != nil {
+		return IconPixmap{}, err
+	}
+	mode, err := r.ReadUint32()
+	if err != nil {
+		return IconPixmap{}, err
+	}
+	state, err := r.ReadUint32()
+	if err != nil {
+		return IconPixmap{}, err
+	}
+	p.Mode, p.State = IconMode(mode), IconState(state)
+	return p, nil
+}
+
+// ReadQIcon reads an icon. Since Qt 4.3 icons are streamed as the key of their engine
+// followed by engine specific data, only the pixmap and the theme engines are supported
+func (r *Reader) ReadQIcon() (QIcon, error) {
+	switch {
+	case r.version < VersionQt4_2:
+		pixmap, err := r.ReadQPixmap()
+		if err != nil || pixmap == nil {
+			return QIcon{}, err
+		}
+		return QIcon{Engine: PixmapIconEngine, Pixmaps: []IconPixmap{{Pixmap: pixmap}}}, nil
+	case r.version == VersionQt4_2:
+		pixmaps, err := ReadList(r, (*Reader).readIconPixmap)
+		if err != nil || len(pixmaps) == 0 {
+			return QIcon{}, err
+		}
+		return QIcon{Engine: PixmapIconEngine, Pixmaps: pixmaps}, nil
+	}
+	var icon QIcon
+	var err error
+	if icon.Engine, err = r.ReadQString(); err != nil {
+		return QIcon{}, err
+	}
+	switch icon.Engine {
+	case "":
+		// null icon
+	case PixmapIconEngine:
+		icon.Pixmaps, err = ReadList(r, (*Reader).readIconPixmap)
+	case ThemeIconEngine:
+		icon.ThemeName, err = r.ReadQString()
+	default:
+		return QIcon{}, fmt.Errorf("unsupported icon engine %q", icon.Engine)
+	}
+	if err != nil {
+		return QIcon{}, err
+	}
+	return icon, nil
+}
+
+func (w *Writer) WriteQCursor(v QCursor) error {
+	if err := w.WriteInt16(int16(v.Shape)); err != nil {
+		return err
+	}
+	if v.Shape != BitmapCursor {
+		return nil
+	}
+	if err := w.WriteBool(v.Pixmap != nil); err != nil {
+		return err
+	}
+	if v.Pixmap != nil {
+		if err := w.WriteQPixmap(v.Pixmap); err != nil {
+			return err
+		}
+	} else {
+		if err := w.WriteQPixmap(v.Bitmap); err != nil {
+			return err
+		}
+		if err := w.WriteQPixmap(v.Mask); err != nil {
+			return err
+		}
+	}
+	return w.WriteQPoint(v.HotSpot)
+}
+
+func boolBit(v bool, shift uint) uint32 {
+	if v {
+		return 1 << shift
+	}
+	return 0
+}
+
+func (w *Writer) WriteQSizePolicy(v QSizePolicy) error {
+	controlType := uint32(0)
+	if v.ControlType != 0 {
+		controlType = uint32(bits.TrailingZeros32(v.ControlType))
+	}
+	return w.WriteUint32(uint32(v.HorizontalPolicy&0xf) |
+		uint32(v.VerticalPolicy&0xf)<<4 |
+		boolBit(v.HeightForWidth, 8) |
+		(controlType&0x1f)<<9 |
+		boolBit(v.WidthForHeight, 14) |
+		boolBit(v.RetainSizeWhenHidden, 15) |
+		uint32(v.VerticalStretch)<<16 |
+		uint32(v.HorizontalStretch)<<24)
+}
+
+func (w *Writer) WriteQTextLength(v QTextLength) error {
+	if err := w.WriteInt32(int32(v.Type)); err != nil {
+		return err
+	}
+	return w.WriteDouble(v.Value)
+}
+
+func (w *Writer) WriteQTextFormat(v QTextFormat) error {
+	if err := w.WriteInt32(v.Type); err != nil {
+		return err
+	}
+	return WriteMap(w, v.Properties, func(a, b int32) bool { return a < b }, (*Writer).WriteInt32, (*Writer).WriteVariant)
+}
+
+// WriteQPalette writes a palette with the color roles of the stream version.
+// Missing roles are written as empty brushes, roles unknown to the stream version are dropped
+func (w *Writer) WriteQPalette(v QPalette) error {
+	roles := paletteRoleCount(w.version)
+	for group := range v {
+		for role := 0; role < roles; role++ {
+			brush, ok := v.Brush(ColorGroup(group), ColorRole(role))
+			if !ok {
+				brush = QBrush{Transform: IdentityTransform()}
+			}
+			if err := w.WriteQBrush(brush); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func (w *Writer) writeIconPixmap(v IconPixmap) error {
+	if err := w.WriteQPixmap(v.Pixmap); err != nil {
+		return err
+	}
+	if err := w.WriteQString(v.FileName); err != nil {
+		return err
+	}
+	if err := w.WriteQSize(v.Size); err != nil {
+		return err
+	}
+	if err := w.WriteUint32(uint32(v.Mode)); err != nil {
+		return err
+	}
+	return w.WriteUint32(uint32(v.State))
+}
+
+func (w *Writer) WriteQIcon(v QIcon) error {
+	switch {
+	case w.version < VersionQt4_2:
+		var pixmap image.Image
+		if len(v.Pixmaps) > 0 {
+			pixmap = v.Pixmaps[0].Pixmap
+		}
+		return w.WriteQPixmap(pixmap)
+	case w.version == VersionQt4_2:
+		if v.Engine != "" && v.Engine != PixmapIconEngine {
+			return fmt.Errorf("icon engine %q can't be written to Qt 4.2 streams", v.Engine)
+		}
+		return WriteList(w, v.Pixmaps, (*Writer).writeIconPixmap)
+	}
+	// a null icon has a null engine key
+	if err := w.WriteNullQString(NullQString{String: v.Engine, Valid: v.Engine != ""}); err != nil {
+		return err
+	}
+	switch v.Engine {
+	case "":
+		return nil
+	case PixmapIconEngine:
+		return WriteList(w, v.Pixmaps, (*Writer).writeIconPixmap)
+	case ThemeIconEngine:
+		return w.WriteQString(v.ThemeName)
+	default:
+		return fmt.Errorf("unsupported icon engine %q", v.Engine)
+	}
+}
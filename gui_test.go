@@ -0,0 +1,197 @@
+package cutestream
+
+import (
+	"bytes"
+	"encoding/hex"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestReadQSizePolicy(t *testing.T) {
+	// QVariant(QSizePolicy(Preferred, Expanding, PushButton)) with horizontal stretch 1, Qt 5.15
+	data, _ := hex.DecodeString("0000007900" + "01000875")
+	reader := NewReader(bytes.NewReader(data))
+	readType, v, err := reader.ReadQVariant()
+	assert.Nil(t, err)
+	assert.Equal(t, QMetaTypeQSizePolicy, readType)
+	policy := QSizePolicy{
+		HorizontalPolicy:  SizePolicyPreferred,
+		VerticalPolicy:    SizePolicyExpanding,
+		HorizontalStretch: 1,
+		ControlType:       0x10,
+	}
+	assert.Equal(t, policy, v)
+
+	var buf bytes.Buffer
+	writer := NewWriter(&buf)
+	assert.Nil(t, writer.WriteQVariant(QMetaTypeQSizePolicy, policy))
+	assert.Equal(t, data, buf.Bytes())
+}
+
+func TestReadQTextLength(t *testing.T) {
+	// QVariant(QTextLength(QTextLength::PercentageLength, 50)) with Qt 5.15 and double precision
+	data, _ := hex.DecodeString("0000004d00" + "00000002" + "4049000000000000")
+	reader := NewReader(bytes.NewReader(data))
+	reader.DoublePrecision = true
+	readType, v, err := reader.ReadQVariant()
+	assert.Nil(t, err)
+	assert.Equal(t, QMetaTypeQTextLength, readType)
+	assert.Equal(t, QTextLength{PercentageLength, 50}, v)
+}
+
+func TestGuiRoundTrip(t *testing.T) {
+	img := testImage()
+	values := []Variant{
+		{QMetaTypeQCursor, QCursor{Shape: PointingHandCursor}},
+		{QMetaTypeQCursor, QCursor{Shape: BitmapCursor, Pixmap: img, HotSpot: QPoint{1, 2}}},
+		{QMetaTypeQCursor, QCursor{Shape: BitmapCursor, Bitmap: img, Mask: img}},
+		{QMetaTypeQSizePolicy, QSizePolicy{
+			HorizontalPolicy: SizePolicyIgnored, VerticalPolicy: SizePolicyMinimumExpanding,
+			VerticalStretch: 255, ControlType: 0x4000, HeightForWidth: true, RetainSizeWhenHidden: true,
+		}},
+		{QMetaTypeQTextLength, QTextLength{FixedLength, 120}},
+		{QMetaTypeQTextFormat, QTextFormat{Type: CharTextFormat, Properties: map[int32]Variant{
+			0x2001: {QMetaTypeDouble, 12.0},
+			0x2003: {QMetaTypeInt, int32(75)},
+			0x820:  {QMetaTypeQBrush, QBrush{Style: SolidPattern, Color: NewRgbColor(0, 0, 255, 255), Transform: IdentityTransform()}},
+		}}},
+		{QMetaTypeQIcon, QIcon{}},
+		{QMetaTypeQIcon, QIcon{Engine: ThemeIconEngine, ThemeName: "document-open"}},
+		{QMetaTypeQIcon, QIcon{Engine: PixmapIconEngine, Pixmaps: []IconPixmap{
+			{Pixmap: img, Size: QSize{3, 2}, Mode: IconNormal, State: IconOff},
+			{FileName: ":/icons/missing.png", Size: QSize{16, 16}, Mode: IconDisabled, State: IconOn},
+		}}},
+	}
+	var buf bytes.Buffer
+	writer := NewWriter(&buf)
+	writer.DoublePrecision = true
+	for _, v := range values {
+		assert.Nil(t, writer.WriteVariant(v))
+	}
+	reader := NewReader(&buf)
+	reader.DoublePrecision = true
+	for _, v := range values {
+		read, err := reader.ReadVariant()
+		assert.Nil(t, err)
+		assert.Equal(t, v, read)
+	}
+	assert.Equal(t, 0, buf.Len())
+}
+
+func TestQPaletteRoles(t *testing.T) {
+	for _, test := range []struct {
+		version int
+		roles   int
+	}{
+		{VersionQt4_3, 17},
+		{VersionQt5_6, 20},
+		{VersionQt5_12, 21},
+		{VersionQt6_6, 22},
+	} {
+		var palette QPalette
+		for group := range palette {
+			for role := 0; role <= int(PaletteAccent); role++ {
+				brush := QBrush{Style: SolidPattern, Color: NewRgbColor(uint8(group), uint8(role), 0, 255), Transform: IdentityTransform()}
+				palette[group] = append(palette[group], brush)
+			}
+		}
+		var buf bytes.Buffer
+		writer, err := NewWriterWithVersion(&buf, test.version)
+		assert.Nil(t, err)
+		assert.Nil(t, writer.WriteQVariant(QMetaTypeQPalette, palette))
+		reader, err := NewReaderWithVersion(&buf, test.version)
+		assert.Nil(t, err)
+		_, v, err := reader.ReadQVariant()
+		assert.Nil(t, err)
+		assert.Equal(t, 0, buf.Len())
+		read := v.(QPalette)
+		for group := range read {
+			assert.Equal(t, palette[group][:test.roles], read[group])
+		}
+		brush, ok := read.Brush(ColorGroupInactive, PaletteHighlight)
+		assert.True(t, ok)
+		assert.Equal(t, NewRgbColor(2, 12, 0, 255), brush.Color)
+		_, ok = read.Brush(ColorGroupActive, ColorRole(test.roles))
+		assert.False(t, ok)
+	}
+}
+
+func TestQIconQt4(t *testing.T) {
+	img := testImage()
+	icon := QIcon{Engine: PixmapIconEngine, Pixmaps: []IconPixmap{{Pixmap: img, Size: QSize{3, 2}}}}
+	for _, version := range []int{VersionQt4_0, VersionQt4_2} {
+		var buf bytes.Buffer
+		writer, err := NewWriterWithVersion(&buf, version)
+		assert.Nil(t, err)
+		assert.Nil(t, writer.WriteQIcon(icon))
+		assert.Nil(t, writer.WriteQIcon(QIcon{}))
+		reader, err := NewReaderWithVersion(&buf, version)
+		assert.Nil(t, err)
+		v, err := reader.ReadQIcon()
+		assert.Nil(t, err)
+		if version == VersionQt4_0 {
+			// only a pixmap is streamed
+			assert.Equal(t, QIcon{Engine: PixmapIconEngine, Pixmaps: []IconPixmap{{Pixmap: img}}}, v)
+		} else {
+			assert.Equal(t, icon, v)
+		}
+		v, err = reader.ReadQIcon()
+		assert.Nil(t, err)
+		assert.Equal(t, QIcon{}, v)
+		assert.Equal(t, 0, buf.Len())
+	}
+}
+
+func TestNullQIcon(t *testing.T) {
+	// QVariant(QIcon()) with Qt 5.15
+	data, _ := hex.DecodeString("0000004500" + "ffffffff")
+	reader := NewReader(bytes.NewReader(data))
+	v, err := reader.ReadVariant()
+	assert.Nil(t, err)
+	assert.Equal(t, Variant{Type: QMetaTypeQIcon, Value: QIcon{}}, v)
+
+	var buf bytes.Buffer
+	writer := NewWriter(&buf)
+	assert.Nil(t, writer.WriteVariant(v))
+	assert.Equal(t, data, buf.Bytes())
+}
+
+func TestReadQIconUnsupportedEngine(t *testing.T) {
+	var buf bytes.Buffer
+	writer := NewWriter(&buf)
+	assert.Nil(t, writer.WriteQString("QSvgIconEngine"))
+	reader := NewReader(&buf)
+	_, err := reader.ReadQIcon()
+	assert.NotNil(t, err)
+}
+
+func TestQt6GuiMetaTypes(t *testing.T) {
+	// GUI metatypes were renumbered in Qt 6: QVariant(QColor(Qt::red)), QVariant(QTextLength(FixedLength, 120)),
+	// QVariant(QPolygonF({{1, 2}})) and QVariant(QSizePolicy(Preferred, Expanding, PushButton)) with Qt 6.0
+	for _, test := range []struct {
+		data     string
+		metaType QMetaType
+		value    interface{}
+	}{
+		{"0000100300" + "01" + "ffff" + "ffff" + "0000" + "0000" + "0000", QMetaTypeQColor, NewRgbColor(255, 0, 0, 255)},
+		{"0000100d00" + "00000001" + "405e000000000000", QMetaTypeQTextLength, QTextLength{FixedLength, 120}},
+		{"0000101600" + "00000001" + "3ff0000000000000" + "4000000000000000", QMetaTypeQPolygonF, QPolygonF{{1, 2}}},
+		{"0000200000" + "01000875", QMetaTypeQSizePolicy, QSizePolicy{
+			HorizontalPolicy: SizePolicyPreferred, VerticalPolicy: SizePolicyExpanding, HorizontalStretch: 1, ControlType: 0x10,
+		}},
+	} {
+		data, _ := hex.DecodeString(test.data)
+		reader, _ := NewReaderWithVersion(bytes.NewReader(data), VersionQt6_0)
+		reader.DoublePrecision = true
+		v, err := reader.ReadVariant()
+		assert.Nil(t, err)
+		assert.Equal(t, Variant{Type: test.metaType, Value: test.value}, v)
+
+		var buf bytes.Buffer
+		writer, _ := NewWriterWithVersion(&buf, VersionQt6_0)
+		writer.DoublePrecision = true
+		assert.Nil(t, writer.WriteVariant(v))
+		assert.Equal(t, test.data, hex.EncodeToString(buf.Bytes()))
+	}
+}
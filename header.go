@@ -0,0 +1,81 @@
+package cutestream
+
+import (
+	"errors"
+	"fmt"
+)
+
+var (
+	// ErrInvalidMagic is returned by ReadHeader when the stream doesn't start with the expected magic number
+	ErrInvalidMagic = errors.New("invalid magic number")
+	// ErrUnsupportedFormatVersion is returned when a format version is missing from StreamVersions
+	ErrUnsupportedFormatVersion = errors.New("unsupported format version")
+)
+
+// StreamVersions maps versions of an application file format to the QDataStream versions
+// they are written with. A format version between two entries uses the stream version
+// of the lower one, versions below the first entry or above the last one are unsupported.
+// A nil table keeps the stream version of the reader or writer for any format version
+type StreamVersions map[int32]int
+
+// streamVersion returns the stream version for a format version
+func (s StreamVersions) streamVersion(version int32) (int, error) {
+	found, first := false, true
+	var best, last int32
+	for v := range s {
+		if v <= version && (!found || v > best) {
+			best, found = v, true
+		}
+		if first || v > last {
+			last, first = v, false
+		}
+	}
+	if !found || version > last {
+		return 0, fmt.Errorf("%w %d", ErrUnsupportedFormatVersion, version)
+	}
+	return s[best], nil
+}
+
+// ReadHeader reads the header of the common Qt file layout: a quint32 magic number followed
+// by a qint32 format version. The magic number is validated and the stream version
+// of the reader is set according to the format version, which is returned.
+// The byte order has to be set before, e.g. with DetectByteOrder(MagicByteOrder(magic))
+func (r *Reader) ReadHeader(magic uint32, versions StreamVersions) (int32, error) {
+	m, err := r.ReadUint32()
+	if err != nil {
+		return 0, err
+	}
+	if m != magic {
+		return 0, fmt.Errorf("%w %08x, expected %08x", ErrInvalidMagic, m, magic)
+	}
+	version, err := r.ReadInt32()
+	if err != nil {
+		return 0, err
+	}
+	if versions == nil {
+		return version, nil
+	}
+	streamVersion, err := versions.streamVersion(version)
+	if err != nil {
+		return version, err
+	}
+	return version, r.SetVersion(streamVersion)
+}
+
+// WriteHeader writes the magic number and the format version and sets the stream version
+// of the writer according to the format version, mirroring ReadHeader
+func (w *Writer) WriteHeader(magic uint32, version int32, versions StreamVersions) error {
+	if versions != nil {
+		streamVersion, err := versions.streamVersion(version)
+		if err != nil {
+			return err
+		}
+		if err := w.SetVersion(streamVersion); err != nil {
+			return err
+		}
+	}
+	if err := w.WriteUint32(magic); err != nil {
+		return err
+	}
+	return w.WriteInt32(version)
+}
@@ -0,0 +1,75 @@
+package cutestream
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const testMagic = 0xa0b0c0d0
+
+var testVersions = StreamVersions{
+	100: VersionQt4_6,
+	110: VersionQt5_6,
+	120: VersionQt6_0,
+}
+
+func TestReadHeader(t *testing.T) {
+	for _, test := range []struct {
+		version       string
+		formatVersion int32
+		streamVersion int
+	}{
+		{"00000064", 100, VersionQt4_6},
+		{"00000065", 101, VersionQt4_6},
+		{"0000006e", 110, VersionQt5_6},
+		{"00000078", 120, VersionQt6_0},
+	} {
+		data, _ := hex.DecodeString("a0b0c0d0" + test.version)
+		reader := NewReader(bytes.NewReader(data))
+		version, err := reader.ReadHeader(testMagic, testVersions)
+		assert.Nil(t, err)
+		assert.Equal(t, test.formatVersion, version)
+		assert.Equal(t, test.streamVersion, reader.Version())
+
+		var buf bytes.Buffer
+		writer := NewWriter(&buf)
+		assert.Nil(t, writer.WriteHeader(testMagic, version, testVersions))
+		assert.Equal(t, data, buf.Bytes())
+		assert.Equal(t, test.streamVersion, writer.Version())
+	}
+}
+
+func TestReadHeaderErrors(t *testing.T) {
+	for _, test := range []struct {
+		data string
+		err  error
+	}{
+		{"a0b0c0d1" + "00000064", ErrInvalidMagic},
+		{"a0b0c0d0" + "00000063", ErrUnsupportedFormatVersion},
+		{"a0b0c0d0" + "00000079", ErrUnsupportedFormatVersion},
+	} {
+		data, _ := hex.DecodeString(test.data)
+		reader := NewReader(bytes.NewReader(data))
+		_, err := reader.ReadHeader(testMagic, testVersions)
+		assert.True(t, errors.Is(err, test.err), test.data)
+	}
+
+	writer := NewWriter(&bytes.Buffer{})
+	assert.True(t, errors.Is(writer.WriteHeader(testMagic, 99, testVersions), ErrUnsupportedFormatVersion))
+}
+
+func TestReadHeaderLittleEndian(t *testing.T) {
+	data, _ := hex.DecodeString("d0c0b0a0" + "07000000")
+	reader := NewReader(bytes.NewReader(data))
+	assert.Nil(t, reader.DetectByteOrder(MagicByteOrder(testMagic)))
+	assert.Equal(t, binary.LittleEndian, reader.ByteOrder)
+	version, err := reader.ReadHeader(testMagic, nil)
+	assert.Nil(t, err)
+	assert.Equal(t, int32(7), version)
+	assert.Equal(t, VersionQt5_13, reader.Version())
+}
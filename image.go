@@ -0,0 +1,95 @@
+package cutestream
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"image"
+	"image/png"
+	"io"
+)
+
+var pngSignature = []byte{0x89, 'P', 'N', 'G', '\r', '\n', 0x1a, '\n'}
+
+// maxBytesPerPixel is the pixel size of the largest image type the PNG decoder creates, 16-bit RGBA
+const maxBytesPerPixel = 8
+
+// readPNG reads a PNG file chunk by chunk up to the IEND chunk,
+// so that no data following the image is consumed
+func (r *Reader) readPNG() ([]byte, error) {
+	var buf bytes.Buffer
+	if _, err := io.CopyN(&buf, r.Reader, int64(len(pngSignature))); err != nil {
+		return nil, err
+	}
+	if !bytes.Equal(buf.Bytes(), pngSignature) {
+		return nil, fmt.Errorf("invalid PNG signature")
+	}
+	for {
+		header := make([]byte, 8)
+		if _, err := io.ReadFull(r.Reader, header); err != nil {
+			return nil, err
+		}
+		buf.Write(header)
+		// PNG is always big endian regardless of the stream byte order
+		length := int64(binary.BigEndian.Uint32(header)) + 4 // data and CRC
+		n, err := io.CopyN(&buf, r.Reader, length)
+		if err != nil {
+			if err == io.EOF && n < length {
+				err = io.ErrUnexpectedEOF
+			}
+			return nil, err
+		}
+		if string(header[4:]) == "IEND" {
+			return buf.Bytes(), nil
+		}
+	}
+}
+
+// ReadQImage reads an image. Qt streams images as PNG files prefixed with a null image marker,
+// a null QImage is returned as nil
+func (r *Reader) ReadQImage() (image.Image, error) {
+	marker, err := r.ReadInt32()
+	if err != nil {
+		return nil, err
+	}
+	if marker == 0 {
+		return nil, nil
+	}
+	data, err := r.readPNG()
+	if err != nil {
+		return nil, err
+	}
+	// the decoder allocates the image from the dimensions in the header
+	config, err := png.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	if err := r.checkAlloc(uint64(config.Width) * uint64(config.Height) * maxBytesPerPixel); err != nil {
+		return nil, err
+	}
+	return png.Decode(bytes.NewReader(data))
+}
+
+// ReadQPixmap reads a pixmap or a bitmap, which are streamed as images
+func (r *Reader) ReadQPixmap() (image.Image, error) {
+	return r.ReadQImage()
+}
+
+// WriteQImage writes an image as PNG. A nil image is written as a null QImage
+func (w *Writer) WriteQImage(v image.Image) error {
+	if v == nil {
+		return w.WriteInt32(0)
+	}
+	if err := w.WriteInt32(1); err != nil {
+		return err
+	}
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, v); err != nil {
+		return err
+	}
+	return w.writeRaw(buf.Bytes())
+}
+
+func (w *Writer) WriteQPixmap(v image.Image) error {
+	return w.WriteQImage(v)
+}
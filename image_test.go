@@ -0,0 +1,68 @@
+package cutestream
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"io"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func testImage() *image.NRGBA {
+	img := image.NewNRGBA(image.Rect(0, 0, 3, 2))
+	for x := 0; x < 3; x++ {
+		for y := 0; y < 2; y++ {
+			img.Set(x, y, color.NRGBA{uint8(x * 100), uint8(y * 200), 50, uint8(128 + x)})
+		}
+	}
+	return img
+}
+
+func TestQImageRoundTrip(t *testing.T) {
+	img := testImage()
+	var buf bytes.Buffer
+	writer := NewWriter(&buf)
+	assert.Nil(t, writer.WriteQVariant(QMetaTypeQImage, img))
+	assert.Nil(t, writer.WriteQVariant(QMetaTypeQPixmap, nil))
+	assert.Nil(t, writer.WriteQVariant(QMetaTypeQPixmap, img))
+	assert.Nil(t, writer.WriteQBrush(QBrush{Style: TexturePattern, Texture: img, Transform: IdentityTransform()}))
+	assert.Nil(t, writer.WriteInt32(42))
+
+	reader := NewReader(&buf)
+	readType, v, err := reader.ReadQVariant()
+	assert.Nil(t, err)
+	assert.Equal(t, QMetaTypeQImage, readType)
+	assert.Equal(t, img, v)
+	readType, v, err = reader.ReadQVariant()
+	assert.Nil(t, err)
+	assert.Equal(t, QMetaTypeQPixmap, readType)
+	assert.Nil(t, v)
+	_, v, err = reader.ReadQVariant()
+	assert.Nil(t, err)
+	assert.Equal(t, img, v)
+	brush, err := reader.ReadQBrush()
+	assert.Nil(t, err)
+	assert.Equal(t, img, brush.Texture)
+	// the PNG data is consumed exactly
+	n, err := reader.ReadInt32()
+	assert.Nil(t, err)
+	assert.Equal(t, int32(42), n)
+}
+
+func TestQImageErrors(t *testing.T) {
+	var buf bytes.Buffer
+	writer := NewWriter(&buf)
+	assert.Nil(t, writer.WriteQImage(testImage()))
+	data := buf.Bytes()
+
+	reader := NewReader(bytes.NewReader(data[:len(data)-6]))
+	_, err := reader.ReadQImage()
+	assert.Equal(t, io.ErrUnexpectedEOF, err)
+
+	data[5] = 'J'
+	reader = NewReader(bytes.NewReader(data))
+	_, err = reader.ReadQImage()
+	assert.NotNil(t, err)
+}
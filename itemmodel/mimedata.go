@@ -0,0 +1,115 @@
+// Package itemmodel decodes and encodes data dragged or copied from Qt item views
+package itemmodel
+
+import (
+	"bytes"
+
+	"github.com/race-engineering-center/cutestream"
+)
+
+// MimeType is the MIME type of the data produced by QAbstractItemModel::mimeData()
+const MimeType = "application/x-qabstractitemmodeldatalist"
+
+// Role is an item data role (Qt::ItemDataRole)
+type Role int32
+
+// Item data roles, from qnamespace.h
+const (
+	DisplayRole               Role = 0
+	DecorationRole            Role = 1
+	EditRole                  Role = 2
+	ToolTipRole               Role = 3
+	StatusTipRole             Role = 4
+	WhatsThisRole             Role = 5
+	FontRole                  Role = 6
+	TextAlignmentRole         Role = 7
+	BackgroundRole            Role = 8
+	ForegroundRole            Role = 9
+	CheckStateRole            Role = 10
+	AccessibleTextRole        Role = 11
+	AccessibleDescriptionRole Role = 12
+	SizeHintRole              Role = 13
+	InitialSortOrderRole      Role = 14
+	UserRole                  Role = 0x0100
+)
+
+// Item is a single model index with its data, as returned by QAbstractItemModel::itemData()
+type Item struct {
+	Row    int32
+	Column int32
+	Data   map[Role]cutestream.Variant
+}
+
+// ReadItem reads a single item. It can be used as a cutestream.RecordDecoder
+func ReadItem(r *cutestream.Reader) (interface{}, error) {
+	var item Item
+	var err error
+	if item.Row, err = r.ReadInt32(); err != nil {
+		return nil, err
+	}
+	if item.Column, err = r.ReadInt32(); err != nil {
+		return nil, err
+	}
+	item.Data, err = cutestream.ReadMap(r, readRole, (*cutestream.Reader).ReadVariant)
+	if err != nil {
+		return nil, err
+	}
+	return item, nil
+}
+
+// WriteItem writes a single item
+func WriteItem(w *cutestream.Writer, item Item) error {
+	if err := w.WriteInt32(item.Row); err != nil {
+		return err
+	}
+	if err := w.WriteInt32(item.Column); err != nil {
+		return err
+	}
+	return cutestream.WriteMap(w, item.Data, lessRole, writeRole, (*cutestream.Writer).WriteVariant)
+}
+
+func readRole(r *cutestream.Reader) (Role, error) {
+	v, err := r.ReadInt32()
+	return Role(v), err
+}
+
+func writeRole(w *cutestream.Writer, v Role) error {
+	return w.WriteInt32(int32(v))
+}
+
+func lessRole(a, b Role) bool {
+	return a < b
+}
+
+// Decode decodes MimeType data serialized with the specified QDataStream version.
+// Qt uses the default version of the library, e.g. cutestream.VersionQt5_13 for Qt 5.13 - 5.15,
+// and the default double precision of QDataStream
+func Decode(data []byte, version int) ([]Item, error) {
+	r, err := cutestream.NewReaderWithVersion(bytes.NewReader(data), version)
+	if err != nil {
+		return nil, err
+	}
+	r.DoublePrecision = true
+	var items []Item
+	s := cutestream.NewRecordScanner(&r, ReadItem)
+	for s.Next() {
+		items = append(items, s.Record().(Item))
+	}
+	return items, s.Err()
+}
+
+// Encode encodes the items as MimeType data with the specified QDataStream version
+func Encode(items []Item, version int) ([]byte, error) {
+	var buf bytes.Buffer
+	w, err := cutestream.NewWriterWithVersion(&buf, version)
+	if err != nil {
+		return nil, err
+	}
+	w.DoublePrecision = true
+	for _, item := range items {
+		if err := WriteItem(&w, item); err != nil {
+			return nil, err
+		}
+	}
+	return buf.Bytes(), nil
+}
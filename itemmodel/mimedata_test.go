@@ -0,0 +1,77 @@
+package itemmodel
+
+import (
+	"encoding/hex"
+	"errors"
+	"testing"
+
+	"github.com/race-engineering-center/cutestream"
+	"github.com/stretchr/testify/assert"
+)
+
+// Two rows dragged from a QStringListModel, Qt 5.15
+const mimeDataHex = "00000000" + "00000000" + // row, column
+	"00000002" + // roles
+	"00000002" + "0000000a00" + "00000006005300700061" + // EditRole: "Spa"
+	"00000000" + "0000000a00" + "00000006005300700061" + // DisplayRole: "Spa"
+	"00000001" + "00000000" +
+	"00000001" +
+	"00000000" + "0000000a00" + "0000000a004d006f006e007a0061" // DisplayRole: "Monza"
+
+func TestDecode(t *testing.T) {
+	data, _ := hex.DecodeString(mimeDataHex)
+	items, err := Decode(data, cutestream.VersionQt5_13)
+	assert.Nil(t, err)
+	spa := cutestream.Variant{Type: cutestream.QMetaTypeQString, Value: "Spa"}
+	assert.Equal(t, []Item{
+		{Row: 0, Column: 0, Data: map[Role]cutestream.Variant{DisplayRole: spa, EditRole: spa}},
+		{Row: 1, Column: 0, Data: map[Role]cutestream.Variant{
+			DisplayRole: {Type: cutestream.QMetaTypeQString, Value: "Monza"},
+		}},
+	}, items)
+
+	encoded, err := Encode(items, cutestream.VersionQt5_13)
+	assert.Nil(t, err)
+	assert.Equal(t, data, encoded)
+
+	_, err = Decode(data[:len(data)-1], cutestream.VersionQt5_13)
+	assert.True(t, errors.Is(err, cutestream.ErrTruncatedRecord))
+}
+
+func TestDecodeDouble(t *testing.T) {
+	// an item with setData(1.5, Qt::UserRole) and setText("Spa") dragged from a QStandardItemModel, Qt 5.15
+	data, _ := hex.DecodeString("00000000" + "00000000" + "00000002" +
+		"00000100" + "0000000600" + "3ff8000000000000" + // UserRole: 1.5
+		"00000000" + "0000000a00" + "00000006005300700061") // DisplayRole: "Spa"
+	items, err := Decode(data, cutestream.VersionQt5_13)
+	assert.Nil(t, err)
+	assert.Equal(t, []Item{{Row: 0, Column: 0, Data: map[Role]cutestream.Variant{
+		UserRole:    {Type: cutestream.QMetaTypeDouble, Value: 1.5},
+		DisplayRole: {Type: cutestream.QMetaTypeQString, Value: "Spa"},
+	}}}, items)
+
+	encoded, err := Encode(items, cutestream.VersionQt5_13)
+	assert.Nil(t, err)
+	assert.Equal(t, data, encoded)
+}
+
+func TestEncodeQt6(t *testing.T) {
+	items := []Item{{Row: 3, Column: 1, Data: map[Role]cutestream.Variant{
+		DisplayRole:       {Type: cutestream.QMetaTypeQString, Value: "Lap 3"},
+		UserRole:          {Type: cutestream.QMetaTypeInt, Value: int32(3)},
+		CheckStateRole:    {Type: cutestream.QMetaTypeInt, Value: int32(2)},
+		TextAlignmentRole: {Type: cutestream.QMetaTypeInt, Value: nil},
+	}}}
+	encoded, err := Encode(items, cutestream.VersionQt6_0)
+	assert.Nil(t, err)
+	// roles are written in ascending order since Qt 6
+	assert.Equal(t, []byte{0, 0, 0, 0}, encoded[12:16])
+
+	decoded, err := Decode(encoded, cutestream.VersionQt6_0)
+	assert.Nil(t, err)
+	assert.Equal(t, items, decoded)
+
+	empty, err := Decode(nil, cutestream.VersionQt6_0)
+	assert.Nil(t, err)
+	assert.Empty(t, empty)
+}
@@ -0,0 +1,127 @@
+package cutestream
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+)
+
+// Keyboard modifiers combined with key codes (Qt::KeyboardModifier)
+const (
+	ShiftModifier   = 0x02000000
+	ControlModifier = 0x04000000
+	AltModifier     = 0x08000000
+	MetaModifier    = 0x10000000
+	KeypadModifier  = 0x20000000
+	modifierMask    = 0xfe000000
+)
+
+// QKeySequence is a keyboard shortcut of up to four key combinations,
+// each being a Qt::Key code combined with modifiers. Unused combinations are 0
+type QKeySequence [4]uint32
+
+var keyNames = map[uint32]string{
+	0x20:       "Space",
+	0x01000000: "Esc",
+	0x01000001: "Tab",
+	0x01000002: "Backtab",
+	0x01000003: "Backspace",
+	0x01000004: "Return",
+	0x01000005: "Enter",
+	0x01000006: "Ins",
+	0x01000007: "Del",
+	0x01000008: "Pause",
+	0x01000009: "Print",
+	0x0100000a: "SysReq",
+	0x0100000b: "Clear",
+	0x01000010: "Home",
+	0x01000011: "End",
+	0x01000012: "Left",
+	0x01000013: "Up",
+	0x01000014: "Right",
+	0x01000015: "Down",
+	0x01000016: "PgUp",
+	0x01000017: "PgDown",
+	0x01000024: "CapsLock",
+	0x01000025: "NumLock",
+	0x01000026: "ScrollLock",
+	0x01000055: "Menu",
+	0x01000058: "Help",
+}
+
+const (
+	keyF1  = 0x01000030
+	keyF35 = 0x01000052
+)
+
+// Count returns the number of key combinations in the sequence
+func (k QKeySequence) Count() int {
+	n := 0
+	for n < len(k) && k[n] != 0 {
+		n++
+	}
+	return n
+}
+
+// String returns the sequence in the portable text format of QKeySequence::toString(),
+// e.g. "Ctrl+Shift+S, Ctrl+Q"
+func (k QKeySequence) String() string {
+	parts := make([]string, 0, len(k))
+	for _, key := range k[:k.Count()] {
+		var b strings.Builder
+		for _, m := range []struct {
+			modifier uint32
+			name     string
+		}{
+			{MetaModifier, "Meta"},
+			{ControlModifier, "Ctrl"},
+			{AltModifier, "Alt"},
+			{ShiftModifier, "Shift"},
+			{KeypadModifier, "Num"},
+		} {
+			if key&m.modifier != 0 {
+				b.WriteString(m.name + "+")
+			}
+		}
+		code := key &^ modifierMask
+		switch name, ok := keyNames[code]; {
+		case ok:
+			b.WriteString(name)
+		case code >= keyF1 && code <= keyF35:
+			fmt.Fprintf(&b, "F%d", code-keyF1+1)
+		case code < 0x01000000 && unicode.IsPrint(rune(code)):
+			b.WriteRune(unicode.ToUpper(rune(code)))
+		default:
+			fmt.Fprintf(&b, "0x%x", code)
+		}
+		parts = append(parts, b.String())
+	}
+	return strings.Join(parts, ", ")
+}
+
+// ReadQKeySequence reads a key sequence. Qt writes a single key combination
+// unless the sequence has more than one
+func (r *Reader) ReadQKeySequence() (QKeySequence, error) {
+	n, err := r.ReadUint32()
+	if err != nil {
+		return QKeySequence{}, err
+	}
+	var k QKeySequence
+	if n > uint32(len(k)) {
+		return QKeySequence{}, fmt.Errorf("invalid key sequence length %d", n)
+	}
+	for i := uint32(0); i < n; i++ {
+		if k[i], err = r.ReadUint32(); err != nil {
+			return QKeySequence{}, err
+		}
+	}
+	return k, nil
+}
+
+func (w *Writer) WriteQKeySequence(v QKeySequence) error {
+	keys := v[:1]
+	if v.Count() > 1 {
+		keys = v[:]
+	}
+	return WriteList(w, keys, (*Writer).WriteUint32)
+}
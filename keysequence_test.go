@@ -0,0 +1,55 @@
+package cutestream
+
+import (
+	"bytes"
+	"encoding/hex"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestReadQKeySequence(t *testing.T) {
+	// QVariant(QKeySequence("Ctrl+Shift+S")) with Qt 5.15
+	data, _ := hex.DecodeString("0000004b00" + "00000001" + "06000053")
+	reader := NewReader(bytes.NewReader(data))
+	readType, v, err := reader.ReadQVariant()
+	assert.Nil(t, err)
+	assert.Equal(t, QMetaTypeQKeySequence, readType)
+	assert.Equal(t, QKeySequence{ControlModifier | ShiftModifier | 'S'}, v)
+	assert.Equal(t, "Ctrl+Shift+S", v.(QKeySequence).String())
+}
+
+func TestQKeySequenceString(t *testing.T) {
+	assert.Equal(t, "", QKeySequence{}.String())
+	assert.Equal(t, "Ctrl+K, Ctrl+C", QKeySequence{ControlModifier | 'K', ControlModifier | 'C'}.String())
+	assert.Equal(t, "Meta+Alt+F4", QKeySequence{AltModifier | MetaModifier | 0x01000033}.String())
+	assert.Equal(t, "Del", QKeySequence{0x01000007}.String())
+	assert.Equal(t, "Shift+Space", QKeySequence{ShiftModifier | ' '}.String())
+	assert.Equal(t, "Num+5", QKeySequence{KeypadModifier | '5'}.String())
+	assert.Equal(t, "Ctrl+Num+Enter", QKeySequence{KeypadModifier | ControlModifier | 0x01000005}.String())
+}
+
+func TestQKeySequenceRoundTrip(t *testing.T) {
+	var buf bytes.Buffer
+	writer := NewWriter(&buf)
+	assert.Nil(t, writer.WriteQKeySequence(QKeySequence{ControlModifier | 'Q'}))
+	assert.Equal(t, 8, buf.Len())
+	sequence := QKeySequence{ControlModifier | 'K', ControlModifier | 'C'}
+	assert.Nil(t, writer.WriteQKeySequence(sequence))
+	// multi-key sequences are always written with four keys
+	assert.Equal(t, 8+20, buf.Len())
+	reader := NewReader(&buf)
+	v, err := reader.ReadQKeySequence()
+	assert.Nil(t, err)
+	assert.Equal(t, QKeySequence{ControlModifier | 'Q'}, v)
+	v, err = reader.ReadQKeySequence()
+	assert.Nil(t, err)
+	assert.Equal(t, sequence, v)
+}
+
+func TestReadQKeySequenceInvalidLength(t *testing.T) {
+	data, _ := hex.DecodeString("00000005")
+	reader := NewReader(bytes.NewReader(data))
+	_, err := reader.ReadQKeySequence()
+	assert.NotNil(t, err)
+}
@@ -0,0 +1,312 @@
+package cutestream
+
+import "math"
+
+// QTransform is a 3x3 transformation matrix. M31 and M32 are the horizontal and vertical translation
+type QTransform struct {
+	M11, M12, M13 float64
+	M21, M22, M23 float64
+	M31, M32, M33 float64
+}
+
+// IdentityTransform returns the identity transformation, the default value of QTransform
+func IdentityTransform() QTransform {
+	return QTransform{M11: 1, M22: 1, M33: 1}
+}
+
+// Multiply returns the transformation applying t first and o second, like t * o in Qt
+func (t QTransform) Multiply(o QTransform) QTransform {
+	return QTransform{
+		M11: t.M11*o.M11 + t.M12*o.M21 + t.M13*o.M31,
+		M12: t.M11*o.M12 + t.M12*o.M22 + t.M13*o.M32,
+		M13: t.M11*o.M13 + t.M12*o.M23 + t.M13*o.M33,
+		M21: t.M21*o.M11 + t.M22*o.M21 + t.M23*o.M31,
+		M22: t.M21*o.M12 + t.M22*o.M22 + t.M23*o.M32,
+		M23: t.M21*o.M13 + t.M22*o.M23 + t.M23*o.M33,
+		M31: t.M31*o.M11 + t.M32*o.M21 + t.M33*o.M31,
+		M32: t.M31*o.M12 + t.M32*o.M22 + t.M33*o.M32,
+		M33: t.M31*o.M13 + t.M32*o.M23 + t.M33*o.M33,
+	}
+}
+
+// Map applies the transformation to a point
+func (t QTransform) Map(p QPointF) QPointF {
+	x := t.M11*p.X + t.M21*p.Y + t.M31
+	y := t.M12*p.X + t.M22*p.Y + t.M32
+	if w := t.M13*p.X + t.M23*p.Y + t.M33; w != 1 && w != 0 {
+		x, y = x/w, y/w
+	}
+	return QPointF{x, y}
+}
+
+// QMatrix is a 2D affine transformation, the Qt 5 predecessor of QTransform
+type QMatrix struct {
+	M11, M12, M21, M22, Dx, Dy float64
+}
+
+// IdentityMatrix returns the identity matrix, the default value of QMatrix
+func IdentityMatrix() QMatrix {
+	return QMatrix{M11: 1, M22: 1}
+}
+
+// Transform returns the QTransform equivalent to the matrix
+func (m QMatrix) Transform() QTransform {
+	return QTransform{M11: m.M11, M12: m.M12, M21: m.M21, M22: m.M22, M31: m.Dx, M32: m.Dy, M33: 1}
+}
+
+// QMatrix4x4 is a 4x4 transformation matrix indexed as [row][column]
+type QMatrix4x4 [4][4]float32
+
+// IdentityMatrix4x4 returns the identity matrix, the default value of QMatrix4x4
+func IdentityMatrix4x4() QMatrix4x4 {
+	return QMatrix4x4{{1, 0, 0, 0}, {0, 1, 0, 0}, {0, 0, 1, 0}, {0, 0, 0, 1}}
+}
+
+// Multiply returns the matrix product m * o, i.e. the transformation applying o first and m second
+func (m QMatrix4x4) Multiply(o QMatrix4x4) QMatrix4x4 {
+	var res QMatrix4x4
+	for row := 0; row < 4; row++ {
+		for col := 0; col < 4; col++ {
+			for i := 0; i < 4; i++ {
+				res[row][col] += m[row][i] * o[i][col]
+			}
+		}
+	}
+	return res
+}
+
+// Map applies the transformation to a point, like QMatrix4x4::map()
+func (m QMatrix4x4) Map(p QVector3D) QVector3D {
+	var res [4]float32
+	for row := 0; row < 4; row++ {
+		res[row] = m[row][0]*p[0] + m[row][1]*p[1] + m[row][2]*p[2] + m[row][3]
+	}
+	if w := res[3]; w != 1 && w != 0 {
+		return QVector3D{res[0] / w, res[1] / w, res[2] / w}
+	}
+	return QVector3D{res[0], res[1], res[2]}
+}
+
+// QVector2D is a vector or a vertex in 2D space
+type QVector2D [2]float32
+
+// QVector3D is a vector or a vertex in 3D space
+type QVector3D [3]float32
+
+// QVector4D is a vector or a vertex in 4D space
+type QVector4D [4]float32
+
+// QQuaternion is a rotation quaternion
+type QQuaternion struct {
+	Scalar, X, Y, Z float32
+}
+
+// IdentityQuaternion returns the quaternion of no rotation, the default value of QQuaternion
+func IdentityQuaternion() QQuaternion {
+	return QQuaternion{Scalar: 1}
+}
+
+// QuaternionFromEulerAngles creates a quaternion rotating by roll degrees around the z axis,
+// then by pitch degrees around the x axis and then by yaw degrees around the y axis,
+// like QQuaternion::fromEulerAngles()
+func QuaternionFromEulerAngles(pitch, yaw, roll float64) QQuaternion {
+	toHalfRadians := math.Pi / 360
+	c1, s1 := math.Cos(yaw*toHalfRadians), math.Sin(yaw*toHalfRadians)
+	c2, s2 := math.Cos(roll*toHalfRadians), math.Sin(roll*toHalfRadians)
+	c3, s3 := math.Cos(pitch*toHalfRadians), math.Sin(pitch*toHalfRadians)
+	return QQuaternion{
+		Scalar: float32(c1*c2*c3 + s1*s2*s3),
+		X:      float32(c1*c2*s3 + s1*s2*c3),
+		Y:      float32(s1*c2*c3 - c1*s2*s3),
+		Z:      float32(c1*s2*c3 - s1*c2*s3),
+	}
+}
+
+// EulerAngles returns the rotation of the quaternion as pitch, yaw and roll in degrees,
+// like QQuaternion::getEulerAngles()
+func (q QQuaternion) EulerAngles() (pitch, yaw, roll float64) {
+	w, x, y, z := float64(q.Scalar), float64(q.X), float64(q.Y), float64(q.Z)
+	xx, xy, xz, xw := x*x, x*y, x*z, x*w
+	yy, yz, yw := y*y, y*z, y*w
+	zz, zw := z*z, z*w
+	if lengthSquared := xx + yy + zz + w*w; lengthSquared != 0 && lengthSquared != 1 {
+		xx, xy, xz, xw = xx/lengthSquared, xy/lengthSquared, xz/lengthSquared, xw/lengthSquared
+		yy, yz, yw = yy/lengthSquared, yz/lengthSquared, yw/lengthSquared
+		zz, zw = zz/lengthSquared, zw/lengthSquared
+	}
+	sinPitch := math.Max(-1, math.Min(1, -2*(yz-xw)))
+	pitch = math.Asin(sinPitch)
+	switch {
+	case sinPitch >= 1:
+		// gimbal lock, there is no unique solution
+		yaw = math.Atan2(-2*(xy-zw), 1-2*(yy+zz))
+	case sinPitch <= -1:
+		yaw = -math.Atan2(-2*(xy-zw), 1-2*(yy+zz))
+	default:
+		yaw = math.Atan2(2*(xz+yw), 1-2*(xx+yy))
+		roll = math.Atan2(2*(xy+zw), 1-2*(xx+zz))
+	}
+	toDegrees := 180 / math.Pi
+	return pitch * toDegrees, yaw * toDegrees, roll * toDegrees
+}
+
+// Multiply returns the Hamilton product q * o, i.e. the rotation applying o first and q second
+func (q QQuaternion) Multiply(o QQuaternion) QQuaternion {
+	return QQuaternion{
+		Scalar: q.Scalar*o.Scalar - q.X*o.X - q.Y*o.Y - q.Z*o.Z,
+		X:      q.Scalar*o.X + q.X*o.Scalar + q.Y*o.Z - q.Z*o.Y,
+		Y:      q.Scalar*o.Y - q.X*o.Z + q.Y*o.Scalar + q.Z*o.X,
+		Z:      q.Scalar*o.Z + q.X*o.Y - q.Y*o.X + q.Z*o.Scalar,
+	}
+}
+
+// Conjugated returns the conjugate of the quaternion,
+// which is the inverse rotation for normalized quaternions
+func (q QQuaternion) Conjugated() QQuaternion {
+	return QQuaternion{q.Scalar, -q.X, -q.Y, -q.Z}
+}
+
+// RotatedVector rotates a vector by the quaternion, which is expected to be normalized
+func (q QQuaternion) RotatedVector(v QVector3D) QVector3D {
+	r := q.Multiply(QQuaternion{0, v[0], v[1], v[2]}).Multiply(q.Conjugated())
+	return QVector3D{r.X, r.Y, r.Z}
+}
+
+func (r *Reader) ReadQTransform() (QTransform, error) {
+	var t QTransform
+	var err error
+	for _, v := range []*float64{&t.M11, &t.M12, &t.M13, &t.M21, &t.M22, &t.M23, &t.M31, &t.M32, &t.M33} {
+		if *v, err = r.ReadDouble(); err != nil {
+			return QTransform{}, err
+		}
+	}
+	return t, nil
+}
+
+func (r *Reader) ReadQMatrix() (QMatrix, error) {
+	var m QMatrix
+	var err error
+	for _, v := range []*float64{&m.M11, &m.M12, &m.M21, &m.M22, &m.Dx, &m.Dy} {
+		if *v, err = r.ReadDouble(); err != nil {
+			return QMatrix{}, err
+		}
+	}
+	return m, nil
+}
+
+// readVectorComponent reads a component of a 3D type. Qt 4 used qreal (double) for them,
+// Qt 5 and later use float
+func (r *Reader) readVectorComponent() (float32, error) {
+	if r.version < VersionQt5_0 {
+		v, err := r.ReadDouble()
+		return float32(v), err
+	}
+	return r.ReadFloat()
+}
+
+func (r *Reader) readVectorComponents(dst []float32) error {
+	for i := range dst {
+		v, err := r.readVectorComponent()
+		if err != nil {
+			return err
+		}
+		dst[i] = v
+	}
+	return nil
+}
+
+// ReadQMatrix4x4 reads a 4x4 matrix, which is serialized row by row
+func (r *Reader) ReadQMatrix4x4() (QMatrix4x4, error) {
+	var m QMatrix4x4
+	for row := range m {
+		if err := r.readVectorComponents(m[row][:]); err != nil {
+			return QMatrix4x4{}, err
+		}
+	}
+	return m, nil
+}
+
+func (r *Reader) ReadQVector2D() (QVector2D, error) {
+	var v QVector2D
+	err := r.readVectorComponents(v[:])
+	return v, err
+}
+
+func (r *Reader) ReadQVector3D() (QVector3D, error) {
+	var v QVector3D
+	err := r.readVectorComponents(v[:])
+	return v, err
+}
+
+func (r *Reader) ReadQVector4D() (QVector4D, error) {
+	var v QVector4D
+	err := r.readVectorComponents(v[:])
+	return v, err
+}
+
+// ReadQQuaternion reads a quaternion, which is serialized as scalar, x, y and z
+func (r *Reader) ReadQQuaternion() (QQuaternion, error) {
+	var v [4]float32
+	if err := r.readVectorComponents(v[:]); err != nil {
+		return QQuaternion{}, err
+	}
+	return QQuaternion{v[0], v[1], v[2], v[3]}, nil
+}
+
+func (w *Writer) WriteQTransform(v QTransform) error {
+	for _, m := range []float64{v.M11, v.M12, v.M13, v.M21, v.M22, v.M23, v.M31, v.M32, v.M33} {
+		if err := w.WriteDouble(m); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (w *Writer) WriteQMatrix(v QMatrix) error {
+	for _, m := range []float64{v.M11, v.M12, v.M21, v.M22, v.Dx, v.Dy} {
+		if err := w.WriteDouble(m); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (w *Writer) writeVectorComponents(v []float32) error {
+	for _, c := range v {
+		var err error
+		if w.version < VersionQt5_0 {
+			err = w.WriteDouble(float64(c))
+		} else {
+			err = w.WriteFloat(c)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (w *Writer) WriteQMatrix4x4(v QMatrix4x4) error {
+	for row := range v {
+		if err := w.writeVectorComponents(v[row][:]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (w *Writer) WriteQVector2D(v QVector2D) error {
+	return w.writeVectorComponents(v[:])
+}
+
+func (w *Writer) WriteQVector3D(v QVector3D) error {
+	return w.writeVectorComponents(v[:])
+}
+
+func (w *Writer) WriteQVector4D(v QVector4D) error {
+	return w.writeVectorComponents(v[:])
+}
+
+func (w *Writer) WriteQQuaternion(v QQuaternion) error {
+	return w.writeVectorComponents([]float32{v.Scalar, v.X, v.Y, v.Z})
+}
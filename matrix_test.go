@@ -0,0 +1,126 @@
+package cutestream
+
+import (
+	"bytes"
+	"math"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestReadQQuaternion(t *testing.T) {
+	// QVariant(QQuaternion(1, 0.5, -2, 0)) with Qt 5.15
+	data := []byte{0, 0, 0, 85, 0, 0x3f, 0x80, 0, 0, 0x3f, 0, 0, 0, 0xc0, 0, 0, 0, 0, 0, 0, 0}
+	reader := NewReader(bytes.NewReader(data))
+	readType, v, err := reader.ReadQVariant()
+	assert.Nil(t, err)
+	assert.Equal(t, QMetaTypeQQuaternion, readType)
+	assert.Equal(t, QQuaternion{1, 0.5, -2, 0}, v)
+
+	// Qt 4 used doubles and a different metatype id
+	var buf bytes.Buffer
+	writer, err := NewWriterWithVersion(&buf, VersionQt4_6)
+	assert.Nil(t, err)
+	writer.DoublePrecision = true
+	assert.Nil(t, writer.WriteQVariant(QMetaTypeQVector2D, QVector2D{1, 2}))
+	assert.Equal(t, []byte{0, 0, 0, 83, 0, 0x3f, 0xf0, 0, 0, 0, 0, 0, 0, 0x40, 0, 0, 0, 0, 0, 0, 0}, buf.Bytes())
+}
+
+func TestMatrixRoundTrip(t *testing.T) {
+	m4 := IdentityMatrix4x4()
+	m4[0][3] = 5
+	m4[2][1] = -0.25
+	values := []struct {
+		t QMetaType
+		v interface{}
+	}{
+		{QMetaTypeQTransform, QTransform{1, 2, 3, 4, 5, 6, 7, 8, 9}},
+		{QMetaTypeQMatrix, QMatrix{1, 0.5, -0.5, 1, 10, 20}},
+		{QMetaTypeQMatrix4x4, m4},
+		{QMetaTypeQVector2D, QVector2D{1, -1}},
+		{QMetaTypeQVector3D, QVector3D{1, 2, 3}},
+		{QMetaTypeQVector4D, QVector4D{1, 2, 3, 4}},
+		{QMetaTypeQQuaternion, QQuaternion{0.5, 0.5, 0.5, 0.5}},
+	}
+	for _, version := range []int{VersionQt4_0, VersionQt4_6, VersionQt5_13} {
+		for _, precision := range []bool{false, true} {
+			var buf bytes.Buffer
+			writer, err := NewWriterWithVersion(&buf, version)
+			assert.Nil(t, err)
+			writer.DoublePrecision = precision
+			for _, v := range values {
+				assert.Nil(t, writer.WriteQVariant(v.t, v.v))
+			}
+			assert.Nil(t, writer.WriteValue(QMetaTypeQMatrix4x4, nil))
+			reader, err := NewReaderWithVersion(&buf, version)
+			assert.Nil(t, err)
+			reader.DoublePrecision = precision
+			for _, v := range values {
+				readType, readValue, err := reader.ReadQVariant()
+				assert.Nil(t, err)
+				assert.Equal(t, v.t, readType)
+				assert.Equal(t, v.v, readValue, "version %d", version)
+			}
+			// nil is written as the default value
+			m, err := reader.ReadValue(QMetaTypeQMatrix4x4)
+			assert.Nil(t, err)
+			assert.Equal(t, IdentityMatrix4x4(), m)
+			assert.Equal(t, 0, buf.Len())
+		}
+	}
+}
+
+func assertVector(t *testing.T, expected, actual QVector3D) {
+	for i := range expected {
+		assert.InDelta(t, expected[i], actual[i], 1e-5, "%v != %v", expected, actual)
+	}
+}
+
+func TestTransformComposition(t *testing.T) {
+	scale := QTransform{M11: 2, M22: 3, M33: 1}
+	translate := QMatrix{M11: 1, M22: 1, Dx: 10, Dy: -10}.Transform()
+	// scale first, translate second
+	assert.Equal(t, QPointF{12, -7}, scale.Multiply(translate).Map(QPointF{1, 1}))
+	assert.Equal(t, QPointF{22, -27}, translate.Multiply(scale).Map(QPointF{1, 1}))
+	assert.Equal(t, scale, scale.Multiply(IdentityTransform()))
+
+	m := IdentityMatrix4x4()
+	m[0][3], m[1][3], m[2][3] = 1, 2, 3
+	assertVector(t, QVector3D{2, 3, 4}, m.Map(QVector3D{1, 1, 1}))
+	assertVector(t, QVector3D{3, 5, 7}, m.Multiply(m).Map(QVector3D{1, 1, 1}))
+	perspective := IdentityMatrix4x4()
+	perspective[3] = [4]float32{0, 0, 1, 0}
+	assertVector(t, QVector3D{0.5, 1, 1}, perspective.Map(QVector3D{1, 2, 2}))
+}
+
+func TestQuaternion(t *testing.T) {
+	yaw := QuaternionFromEulerAngles(0, 90, 0)
+	assertVector(t, QVector3D{0, 0, -1}, yaw.RotatedVector(QVector3D{1, 0, 0}))
+	pitch := QuaternionFromEulerAngles(90, 0, 0)
+	assertVector(t, QVector3D{0, 0, 1}, pitch.RotatedVector(QVector3D{0, 1, 0}))
+	// roll is applied first, then pitch, then yaw
+	q := QuaternionFromEulerAngles(30, -45, 10)
+	rollPitchYaw := QuaternionFromEulerAngles(0, -45, 0).Multiply(QuaternionFromEulerAngles(30, 0, 0)).
+		Multiply(QuaternionFromEulerAngles(0, 0, 10))
+	assert.InDelta(t, q.Scalar, rollPitchYaw.Scalar, 1e-6)
+	assert.InDelta(t, q.X, rollPitchYaw.X, 1e-6)
+	assert.InDelta(t, q.Y, rollPitchYaw.Y, 1e-6)
+	assert.InDelta(t, q.Z, rollPitchYaw.Z, 1e-6)
+
+	for _, angles := range [][3]float64{{30, -45, 10}, {-80, 170, -120}, {0, 0, 0}, {5, 0, 0}} {
+		pitch, yaw, roll := QuaternionFromEulerAngles(angles[0], angles[1], angles[2]).EulerAngles()
+		assert.InDelta(t, angles[0], pitch, 1e-3)
+		assert.InDelta(t, angles[1], yaw, 1e-3)
+		assert.InDelta(t, angles[2], roll, 1e-3)
+	}
+
+	// gimbal lock: yaw and roll rotate around the same axis
+	pitch90, yaw90, roll90 := QuaternionFromEulerAngles(90, 20, 30).EulerAngles()
+	assert.InDelta(t, 90, pitch90, 1e-2)
+	assert.InDelta(t, 0, roll90, 1e-6)
+	assert.False(t, math.IsNaN(yaw90))
+
+	// unnormalized quaternions are handled
+	p, y, r := QQuaternion{2, 0, 0, 0}.EulerAngles()
+	assert.Equal(t, [3]float64{0, 0, 0}, [3]float64{p, y, r})
+}
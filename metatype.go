@@ -0,0 +1,119 @@
+package cutestream
+
+import "strconv"
+
+// metaTypeNames are the names of the built-in metatypes as returned by QMetaType::typeName()
+var metaTypeNames = map[QMetaType]string{
+	QMetaTypeBool:               "bool",
+	QMetaTypeInt:                "int",
+	QMetaTypeUInt:               "uint",
+	QMetaTypeLongLong:           "qlonglong",
+	QMetaTypeULongLong:          "qulonglong",
+	QMetaTypeDouble:             "double",
+	QMetaTypeQChar:              "QChar",
+	QMetaTypeQVariantMap:        "QVariantMap",
+	QMetaTypeQVariantList:       "QVariantList",
+	QMetaTypeQString:            "QString",
+	QMetaTypeQStringList:        "QStringList",
+	QMetaTypeQByteArray:         "QByteArray",
+	QMetaTypeQBitArray:          "QBitArray",
+	QMetaTypeQDate:              "QDate",
+	QMetaTypeQTime:              "QTime",
+	QMetaTypeQDateTime:          "QDateTime",
+	QMetaTypeQUrl:               "QUrl",
+	QMetaTypeQLocale:            "QLocale",
+	QMetaTypeQRect:              "QRect",
+	QMetaTypeQRectF:             "QRectF",
+	QMetaTypeQSize:              "QSize",
+	QMetaTypeQSizeF:             "QSizeF",
+	QMetaTypeQLine:              "QLine",
+	QMetaTypeQLineF:             "QLineF",
+	QMetaTypeQPoint:             "QPoint",
+	QMetaTypeQPointF:            "QPointF",
+	QMetaTypeQRegExp:            "QRegExp",
+	QMetaTypeQVariantHash:       "QVariantHash",
+	QMetaTypeQEasingCurve:       "QEasingCurve",
+	QMetaTypeQUuid:              "QUuid",
+	QMetaTypeVoidStar:           "void*",
+	QMetaTypeLong:               "long",
+	QMetaTypeShort:              "short",
+	QMetaTypeChar:               "char",
+	QMetaTypeULong:              "ulong",
+	QMetaTypeUShort:             "ushort",
+	QMetaTypeUChar:              "uchar",
+	QMetaTypeFloat:              "float",
+	QMetaTypeQObjectStar:        "QObject*",
+	QMetaTypeSChar:              "signed char",
+	QMetaTypeQVariant:           "QVariant",
+	QMetaTypeQModelIndex:        "QModelIndex",
+	QMetaTypeVoid:               "void",
+	QMetaTypeQRegularExpression: "QRegularExpression",
+	QMetaTypeQJsonValue:         "QJsonValue",
+	QMetaTypeQJsonObject:        "QJsonObject",
+	QMetaTypeQJsonArray:         "QJsonArray",
+	QMetaTypeQJsonDocument:      "QJsonDocument",
+	QMetaTypeQByteArrayList:     "QByteArrayList",
+	50:                          "QPersistentModelIndex",
+	QMetaTypeNullptr:            "std::nullptr_t",
+	QMetaTypeQCborSimpleType:    "QCborSimpleType",
+	QMetaTypeQCborValue:         "QCborValue",
+	QMetaTypeQCborArray:         "QCborArray",
+	QMetaTypeQCborMap:           "QCborMap",
+	QMetaTypeChar16:             "char16_t",
+	QMetaTypeChar32:             "char32_t",
+	QMetaTypeQFont:              "QFont",
+	QMetaTypeQPixmap:            "QPixmap",
+	QMetaTypeQBrush:             "QBrush",
+	QMetaTypeQColor:             "QColor",
+	QMetaTypeQPalette:           "QPalette",
+	QMetaTypeQIcon:              "QIcon",
+	QMetaTypeQImage:             "QImage",
+	QMetaTypeQPolygon:           "QPolygon",
+	QMetaTypeQRegion:            "QRegion",
+	QMetaTypeQBitmap:            "QBitmap",
+	QMetaTypeQCursor:            "QCursor",
+	QMetaTypeQKeySequence:       "QKeySequence",
+	QMetaTypeQPen:               "QPen",
+	QMetaTypeQTextLength:        "QTextLength",
+	QMetaTypeQTextFormat:        "QTextFormat",
+	QMetaTypeQMatrix:            "QMatrix",
+	QMetaTypeQTransform:         "QTransform",
+	QMetaTypeQMatrix4x4:         "QMatrix4x4",
+	QMetaTypeQVector2D:          "QVector2D",
+	QMetaTypeQVector3D:          "QVector3D",
+	QMetaTypeQVector4D:          "QVector4D",
+	QMetaTypeQQuaternion:        "QQuaternion",
+	QMetaTypeQPolygonF:          "QPolygonF",
+	QMetaTypeQSizePolicy:        "QSizePolicy",
+}
+
+// String returns the Qt name of the metatype, e.g. "QVariantMap".
+// QMetaTypeUnknown is "Invalid" and QMetaTypeUser is "User"
+func (t QMetaType) String() string {
+	switch t {
+	case QMetaTypeUnknown:
+		return "Invalid"
+	case QMetaTypeUser:
+		return "User"
+	}
+	if name, ok := metaTypeNames[t]; ok {
+		return name
+	}
+	return strconv.Itoa(int(t))
+}
+
+// MetaTypeByName returns the built-in metatype with the specified Qt name
+func MetaTypeByName(name string) (QMetaType, bool) {
+	switch name {
+	case "Invalid":
+		return QMetaTypeUnknown, true
+	case "User":
+		return QMetaTypeUser, true
+	}
+	for t, n := range metaTypeNames {
+		if n == name {
+			return t, true
+		}
+	}
+	return 0, false
+}
@@ -0,0 +1,124 @@
+package cutestream
+
+// KeyValue is an entry of an OrderedMap
+type KeyValue struct {
+	Key   string
+	Value interface{}
+}
+
+// OrderedMap is a QVariantMap or QVariantHash with the entries in the order they are streamed.
+// Unlike map[string]interface{} it keeps duplicate keys inserted with QMultiMap::insert()
+// or QMap::insertMulti(), and writing it reproduces the bytes it was read from
+type OrderedMap []KeyValue
+
+// Value returns the value of the key. For duplicate keys the last streamed value is returned,
+// which is the one QMap::value() returns after reading the map
+func (m OrderedMap) Value(key string) (interface{}, bool) {
+	for i := len(m) - 1; i >= 0; i-- {
+		if m[i].Key == key {
+			return m[i].Value, true
+		}
+	}
+	return nil, false
+}
+
+// Values returns all values of the key in the stream order
+func (m OrderedMap) Values(key string) []interface{} {
+	var values []interface{}
+	for _, e := range m {
+		if e.Key == key {
+			values = append(values, e.Value)
+		}
+	}
+	return values
+}
+
+// Keys returns the keys in the stream order, duplicate keys are returned once
+func (m OrderedMap) Keys() []string {
+	seen := make(map[string]bool, len(m))
+	keys := make([]string, 0, len(m))
+	for _, e := range m {
+		if !seen[e.Key] {
+			seen[e.Key] = true
+			keys = append(keys, e.Key)
+		}
+	}
+	return keys
+}
+
+// Map returns the entries as a map like ReadQStringQVariantAssociative does,
+// keeping the last value of duplicate keys. Nested values are not converted
+func (m OrderedMap) Map() map[string]interface{} {
+	result := make(map[string]interface{}, len(m))
+	for _, e := range m {
+		result[e.Key] = e.Value
+	}
+	return result
+}
+
+// OrderedHash is a QVariantHash with the entries in the order they are streamed, like OrderedMap.
+// The separate type keeps the metatype of hashes contained in other values, so they are written back as QVariantHash
+type OrderedHash OrderedMap
+
+// Value returns the last streamed value of the key
+func (h OrderedHash) Value(key string) (interface{}, bool) {
+	return OrderedMap(h).Value(key)
+}
+
+// Values returns all values of the key in the stream order
+func (h OrderedHash) Values(key string) []interface{} {
+	return OrderedMap(h).Values(key)
+}
+
+// Keys returns the keys in the stream order, duplicate keys are returned once
+func (h OrderedHash) Keys() []string {
+	return OrderedMap(h).Keys()
+}
+
+// Map returns the entries as a map keeping the last value of duplicate keys
+func (h OrderedHash) Map() map[string]interface{} {
+	return OrderedMap(h).Map()
+}
+
+// ReadQStringQVariantOrderedMap reads a QVariantMap or QVariantHash keeping the order of the entries
+// and duplicate keys. Nested maps and hashes are read as OrderedMap and OrderedHash
+// only if OrderedMaps of the reader is set
+func (r *Reader) ReadQStringQVariantOrderedMap() (OrderedMap, error) {
+	n, err := r.ReadUint32()
+	if err != nil {
+		return nil, err
+	}
+	m := make(OrderedMap, 0, preallocCount(n))
+	for i := uint32(0); i < n; i++ {
+		k, err := r.ReadQString()
+		if err != nil {
+			return m, err
+		}
+		_, v, err := r.ReadQVariant()
+		if err != nil {
+			if r.keepPartial(err) {
+				m = append(m, KeyValue{k, v})
+			}
+			return m, err
+		}
+		m = append(m, KeyValue{k, v})
+	}
+	return m, nil
+}
+
+// WriteQStringQVariantOrderedMap writes a QVariantMap or QVariantHash with the entries
+// in the order of the slice
+func (w *Writer) WriteQStringQVariantOrderedMap(v OrderedMap) error {
+	if err := w.WriteUint32(uint32(len(v))); err != nil {
+		return err
+	}
+	for _, e := range v {
+		if err := w.WriteQString(e.Key); err != nil {
+			return err
+		}
+		if err := w.writeContainedQVariant(e.Value); err != nil {
+			return err
+		}
+	}
+	return nil
+}
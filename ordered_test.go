@@ -0,0 +1,88 @@
+package cutestream
+
+import (
+	"bytes"
+	"encoding/hex"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestOrderedMapDuplicates(t *testing.T) {
+	// QMultiMap<QString, QVariant> with insert("a", 1), insert("a", 2), insert("b", 3), Qt 5
+	data, _ := hex.DecodeString("0000000800" + "00000003" +
+		"000000020062" + "0000000200" + "00000003" +
+		"000000020061" + "0000000200" + "00000001" +
+		"000000020061" + "0000000200" + "00000002")
+	reader := NewReader(bytes.NewReader(data))
+	reader.OrderedMaps = true
+	v, err := reader.ReadVariant()
+	assert.Nil(t, err)
+	m := v.Value.(OrderedMap)
+	assert.Equal(t, OrderedMap{{"b", int32(3)}, {"a", int32(1)}, {"a", int32(2)}}, m)
+
+	value, ok := m.Value("a")
+	assert.True(t, ok)
+	assert.Equal(t, int32(2), value)
+	_, ok = m.Value("c")
+	assert.False(t, ok)
+	assert.Equal(t, []interface{}{int32(1), int32(2)}, m.Values("a"))
+	assert.Equal(t, []string{"b", "a"}, m.Keys())
+	assert.Equal(t, map[string]interface{}{"a": int32(2), "b": int32(3)}, m.Map())
+
+	var buf bytes.Buffer
+	w := NewWriter(&buf)
+	assert.Nil(t, w.WriteVariant(v))
+	assert.Equal(t, data, buf.Bytes())
+}
+
+func TestOrderedMapNested(t *testing.T) {
+	// QVariantHash in QHash iteration order holding a QVariantMap, Qt 6
+	data, _ := hex.DecodeString("0000001c00" + "00000002" +
+		"00000002007a" + "0000000a00" + "00000002" + "007a" +
+		"000000020061" + "0000000800" + "00000002" +
+		"000000020062" + "0000000100" + "01" +
+		"000000020063" + "0000000100" + "00")
+	reader, _ := NewReaderWithVersion(bytes.NewReader(data), VersionQt6_0)
+	reader.OrderedMaps = true
+	v, err := reader.ReadVariant()
+	assert.Nil(t, err)
+	assert.Equal(t, Variant{Type: QMetaTypeQVariantHash, Value: OrderedHash{
+		{"z", "z"},
+		{"a", OrderedMap{{"b", true}, {"c", false}}},
+	}}, v)
+
+	var buf bytes.Buffer
+	w, _ := NewWriterWithVersion(&buf, VersionQt6_0)
+	assert.Nil(t, w.WriteVariant(v))
+	assert.Equal(t, data, buf.Bytes())
+
+	// maps and hashes nested into a list deduce their metatypes
+	buf.Reset()
+	assert.Nil(t, w.WriteQStringQVariantList([]interface{}{OrderedMap{{"b", true}}, OrderedHash{}}))
+	assert.Equal(t, "00000002"+"0000000800"+"00000001"+"000000020062"+"0000000100"+"01"+"0000001c00"+"00000000",
+		hex.EncodeToString(buf.Bytes()))
+}
+
+func TestOrderedMapNestedHash(t *testing.T) {
+	// QVariantMap{{"h", QVariantHash{{"x", 1}}}, {"m", QVariantMap{}}}, Qt 5
+	data, _ := hex.DecodeString("0000000800" + "00000002" +
+		"00000002006d" + "0000000800" + "00000000" +
+		"000000020068" + "0000001c00" + "00000001" +
+		"000000020078" + "0000000200" + "00000001")
+	reader := NewReader(bytes.NewReader(data))
+	reader.OrderedMaps = true
+	v, err := reader.ReadVariant()
+	assert.Nil(t, err)
+	assert.Equal(t, Variant{Type: QMetaTypeQVariantMap, Value: OrderedMap{
+		{"m", OrderedMap{}},
+		{"h", OrderedHash{{"x", int32(1)}}},
+	}}, v)
+	h, _ := v.Value.(OrderedMap).Value("h")
+	assert.Equal(t, map[string]interface{}{"x": int32(1)}, h.(OrderedHash).Map())
+
+	var buf bytes.Buffer
+	w := NewWriter(&buf)
+	assert.Nil(t, w.WriteVariant(v))
+	assert.Equal(t, data, buf.Bytes())
+}
@@ -0,0 +1,451 @@
+package cutestream
+
+import (
+	"fmt"
+	"image"
+)
+
+// ColorSpec is the color model of a QColor (QColor::Spec)
+type ColorSpec int8
+
+const (
+	ColorSpecInvalid     ColorSpec = 0
+	ColorSpecRgb         ColorSpec = 1
+	ColorSpecHsv         ColorSpec = 2
+	ColorSpecCmyk        ColorSpec = 3
+	ColorSpecHsl         ColorSpec = 4
+	ColorSpecExtendedRgb ColorSpec = 5 // Since Qt 5.14, components are float16 values
+)
+
+// QColor is a color in one of the Qt color models.
+// Components are 16-bit values in the order of the model:
+// red, green, blue and padding for ColorSpecRgb, hue, saturation, value and padding
+// for ColorSpecHsv, cyan, magenta, yellow and black for ColorSpecCmyk etc.
+type QColor struct {
+	Spec       ColorSpec
+	Alpha      uint16
+	Components [4]uint16
+}
+
+// NewRgbColor creates an RGB color from 8-bit components the way QColor::fromRgb() does
+func NewRgbColor(r, g, b, a uint8) QColor {
+	return QColor{
+		Spec:       ColorSpecRgb,
+		Alpha:      uint16(a) * 0x101,
+		Components: [4]uint16{uint16(r) * 0x101, uint16(g) * 0x101, uint16(b) * 0x101, 0},
+	}
+}
+
+// BrushStyle is the fill pattern of a brush (Qt::BrushStyle)
+type BrushStyle uint8
+
+const (
+	NoBrush                BrushStyle = 0
+	SolidPattern           BrushStyle = 1
+	Dense1Pattern          BrushStyle = 2
+	Dense2Pattern          BrushStyle = 3
+	Dense3Pattern          BrushStyle = 4
+	Dense4Pattern          BrushStyle = 5
+	Dense5Pattern          BrushStyle = 6
+	Dense6Pattern          BrushStyle = 7
+	Dense7Pattern          BrushStyle = 8
+	HorPattern             BrushStyle = 9
+	VerPattern             BrushStyle = 10
+	CrossPattern           BrushStyle = 11
+	BDiagPattern           BrushStyle = 12
+	FDiagPattern           BrushStyle = 13
+	DiagCrossPattern       BrushStyle = 14
+	LinearGradientPattern  BrushStyle = 15
+	RadialGradientPattern  BrushStyle = 16
+	ConicalGradientPattern BrushStyle = 17
+	TexturePattern         BrushStyle = 24
+)
+
+// GradientType is the type of a gradient (QGradient::Type)
+type GradientType int32
+
+const (
+	LinearGradient  GradientType = 0
+	RadialGradient  GradientType = 1
+	ConicalGradient GradientType = 2
+	NoGradient      GradientType = 3
+)
+
+// GradientStop is a color at a position between 0 and 1 of a gradient
+type GradientStop struct {
+	Position float64
+	Color    QColor
+}
+
+// QGradient is a gradient of a QBrush. The fields used depend on the type:
+// Start and FinalStop for linear gradients, Center, FocalPoint, Radius and FocalRadius
+// for radial gradients, Center and Angle for conical gradients
+type QGradient struct {
+	Type              GradientType
+	Spread            int32 // QGradient::Spread, since Qt 4.3
+	CoordinateMode    int32 // QGradient::CoordinateMode, since Qt 4.3
+	InterpolationMode int32 // QGradient::InterpolationMode, since Qt 4.5
+	Stops             []GradientStop
+	Start             QPointF
+	FinalStop         QPointF
+	Center            QPointF
+	FocalPoint        QPointF
+	Radius            float64
+	FocalRadius       float64 // Since Qt 6.0
+	Angle             float64
+}
+
+// QBrush is a fill pattern. Gradient is set for gradient styles
+// and Texture for TexturePattern only
+type QBrush struct {
+	Style     BrushStyle
+	Color     QColor
+	Gradient  *QGradient
+	Texture   image.Image
+	Transform QTransform // Since Qt 4.3
+}
+
+// PenStyle is the line style of a pen (Qt::PenStyle)
+type PenStyle uint16
+
+const (
+	NoPen          PenStyle = 0
+	SolidLine      PenStyle = 1
+	DashLine       PenStyle = 2
+	DotLine        PenStyle = 3
+	DashDotLine    PenStyle = 4
+	DashDotDotLine PenStyle = 5
+	CustomDashLine PenStyle = 6
+)
+
+// PenCapStyle is the line end style of a pen (Qt::PenCapStyle)
+type PenCapStyle uint16
+
+const (
+	FlatCap   PenCapStyle = 0x00
+	SquareCap PenCapStyle = 0x10
+	RoundCap  PenCapStyle = 0x20
+)
+
+// PenJoinStyle is the line join style of a pen (Qt::PenJoinStyle)
+type PenJoinStyle uint16
+
+const (
+	MiterJoin    PenJoinStyle = 0x00
+	BevelJoin    PenJoinStyle = 0x40
+	RoundJoin    PenJoinStyle = 0x80
+	SvgMiterJoin PenJoinStyle = 0x100
+)
+
+const (
+	penStyleMask = 0x0f
+	penCapMask   = 0x30
+	penJoinMask  = 0x1c0
+)
+
+// QPen describes how lines and outlines are drawn
+type QPen struct {
+	Style        PenStyle
+	CapStyle     PenCapStyle
+	JoinStyle    PenJoinStyle
+	Cosmetic     bool // Since Qt 4.3
+	Width        float64
+	Brush        QBrush
+	MiterLimit   float64
+	DashPattern  []float64
+	DashOffset   float64 // Since Qt 4.3
+	DefaultWidth bool    // Whether the width is zero, since Qt 5.0
+}
+
+func (r *Reader) ReadQColor() (QColor, error) {
+	spec, err := r.ReadInt8()
+	if err != nil {
+		return QColor{}, err
+	}
+	c := QColor{Spec: ColorSpec(spec)}
+	if c.Alpha, err = r.ReadUint16(); err != nil {
+		return QColor{}, err
+	}
+	for i := range c.Components {
+		if c.Components[i], err = r.ReadUint16(); err != nil {
+			return QColor{}, err
+		}
+	}
+	return c, nil
+}
+
+func (r *Reader) readGradientStop() (GradientStop, error) {
+	position, err := r.ReadDouble()
+	if err != nil {
+		return GradientStop{}, err
+	}
+	color, err := r.ReadQColor()
+	return GradientStop{position, color}, err
+}
+
+func (r *Reader) readGradient() (*QGradient, error) {
+	t, err := r.ReadInt32()
+	if err != nil {
+		return nil, err
+	}
+	g := &QGradient{Type: GradientType(t)}
+	if r.version >= VersionQt4_3 {
+		if g.Spread, err = r.ReadInt32(); err != nil {
+			return nil, err
+		}
+		if g.CoordinateMode, err = r.ReadInt32(); err != nil {
+			return nil, err
+		}
+	}
+	if r.version >= VersionQt4_5 {
+		if g.InterpolationMode, err = r.ReadInt32(); err != nil {
+			return nil, err
+		}
+	}
+	if g.Stops, err = ReadList(r, (*Reader).readGradientStop); err != nil {
+		return nil, err
+	}
+	switch g.Type {
+	case LinearGradient:
+		if g.Start, err = r.ReadQPointF(); err != nil {
+			return nil, err
+		}
+		if g.FinalStop, err = r.ReadQPointF(); err != nil {
+			return nil, err
+		}
+	case RadialGradient:
+		if g.Center, err = r.ReadQPointF(); err != nil {
+			return nil, err
+		}
+		if g.FocalPoint, err = r.ReadQPointF(); err != nil {
+			return nil, err
+		}
+		if g.Radius, err = r.ReadDouble(); err != nil {
+			return nil, err
+		}
+		if r.version >= VersionQt6_0 {
+			if g.FocalRadius, err = r.ReadDouble(); err != nil {
+				return nil, err
+			}
+		}
+	default:
+		if g.Center, err = r.ReadQPointF(); err != nil {
+			return nil, err
+		}
+		if g.Angle, err = r.ReadDouble(); err != nil {
+			return nil, err
+		}
+	}
+	return g, nil
+}
+
+func isGradientStyle(style BrushStyle) bool {
+	return style == LinearGradientPattern || style == RadialGradientPattern || style == ConicalGradientPattern
+}
+
+func (r *Reader) ReadQBrush() (QBrush, error) {
+	style, err := r.ReadUint8()
+	if err != nil {
+		return QBrush{}, err
+	}
+	b := QBrush{Style: BrushStyle(style), Transform: IdentityTransform()}
+	if b.Color, err = r.ReadQColor(); err != nil {
+		return QBrush{}, err
+	}
+	if b.Style == TexturePattern {
+		// QImage since Qt 5.5 and QPixmap before, both are streamed the same way
+		if b.Texture, err = r.ReadQImage(); err != nil {
+			return QBrush{}, err
+		}
+	} else if isGradientStyle(b.Style) {
+		if b.Gradient, err = r.readGradient(); err != nil {
+			return QBrush{}, err
+		}
+	}
+	if r.version >= VersionQt4_3 {
+		if b.Transform, err = r.ReadQTransform(); err != nil {
+			return QBrush{}, err
+		}
+	}
+	return b, nil
+}
+
+func (r *Reader) ReadQPen() (QPen, error) {
+	var p QPen
+	var flags uint16
+	if r.version >= VersionQt4_3 {
+		v, err := r.ReadUint16()
+		if err != nil {
+			return QPen{}, err
+		}
+		flags = v
+		if p.Cosmetic, err = r.ReadBool(); err != nil {
+			return QPen{}, err
+		}
+	} else {
+		v, err := r.ReadUint8()
+		if err != nil {
+			return QPen{}, err
+		}
+		flags = uint16(v)
+	}
+	p.Style = PenStyle(flags & penStyleMask)
+	p.CapStyle = PenCapStyle(flags & penCapMask)
+	p.JoinStyle = PenJoinStyle(flags & penJoinMask)
+	var err error
+	if p.Width, err = r.ReadDouble(); err != nil {
+		return QPen{}, err
+	}
+	if p.Brush, err = r.ReadQBrush(); err != nil {
+		return QPen{}, err
+	}
+	if p.MiterLimit, err = r.ReadDouble(); err != nil {
+		return QPen{}, err
+	}
+	if p.DashPattern, err = ReadList(r, (*Reader).ReadDouble); err != nil {
+		return QPen{}, err
+	}
+	if r.version >= VersionQt4_3 {
+		if p.DashOffset, err = r.ReadDouble(); err != nil {
+			return QPen{}, err
+		}
+	}
+	if r.version >= VersionQt5_0 {
+		if p.DefaultWidth, err = r.ReadBool(); err != nil {
+			return QPen{}, err
+		}
+	}
+	return p, nil
+}
+
+func (w *Writer) WriteQColor(v QColor) error {
+	if err := w.WriteInt8(int8(v.Spec)); err != nil {
+		return err
+	}
+	if err := w.WriteUint16(v.Alpha); err != nil {
+		return err
+	}
+	for _, c := range v.Components {
+		if err := w.WriteUint16(c); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (w *Writer) writeGradientStop(v GradientStop) error {
+	if err := w.WriteDouble(v.Position); err != nil {
+		return err
+	}
+	return w.WriteQColor(v.Color)
+}
+
+func (w *Writer) writeGradient(g *QGradient) error {
+	if err := w.WriteInt32(int32(g.Type)); err != nil {
+		return err
+	}
+	if w.version >= VersionQt4_3 {
+		if err := w.WriteInt32(g.Spread); err != nil {
+			return err
+		}
+		if err := w.WriteInt32(g.CoordinateMode); err != nil {
+			return err
+		}
+	}
+	if w.version >= VersionQt4_5 {
+		if err := w.WriteInt32(g.InterpolationMode); err != nil {
+			return err
+		}
+	}
+	if err := WriteList(w, g.Stops, (*Writer).writeGradientStop); err != nil {
+		return err
+	}
+	switch g.Type {
+	case LinearGradient:
+		if err := w.WriteQPointF(g.Start); err != nil {
+			return err
+		}
+		return w.WriteQPointF(g.FinalStop)
+	case RadialGradient:
+		if err := w.WriteQPointF(g.Center); err != nil {
+			return err
+		}
+		if err := w.WriteQPointF(g.FocalPoint); err != nil {
+			return err
+		}
+		if err := w.WriteDouble(g.Radius); err != nil {
+			return err
+		}
+		if w.version >= VersionQt6_0 {
+			return w.WriteDouble(g.FocalRadius)
+		}
+		return nil
+	default:
+		if err := w.WriteQPointF(g.Center); err != nil {
+			return err
+		}
+		return w.WriteDouble(g.Angle)
+	}
+}
+
+func (w *Writer) WriteQBrush(v QBrush) error {
+	if isGradientStyle(v.Style) && v.Gradient == nil {
+		return fmt.Errorf("brush style %d requires a gradient", v.Style)
+	}
+	if err := w.WriteUint8(uint8(v.Style)); err != nil {
+		return err
+	}
+	if err := w.WriteQColor(v.Color); err != nil {
+		return err
+	}
+	if v.Style == TexturePattern {
+		if err := w.WriteQImage(v.Texture); err != nil {
+			return err
+		}
+	} else if isGradientStyle(v.Style) {
+		if err := w.writeGradient(v.Gradient); err != nil {
+			return err
+		}
+	}
+	if w.version >= VersionQt4_3 {
+		return w.WriteQTransform(v.Transform)
+	}
+	return nil
+}
+
+func (w *Writer) WriteQPen(v QPen) error {
+	flags := uint16(v.Style)&penStyleMask | uint16(v.CapStyle)&penCapMask | uint16(v.JoinStyle)&penJoinMask
+	if w.version >= VersionQt4_3 {
+		if err := w.WriteUint16(flags); err != nil {
+			return err
+		}
+		if err := w.WriteBool(v.Cosmetic); err != nil {
+			return err
+		}
+	} else {
+		if err := w.WriteUint8(uint8(flags)); err != nil {
+			return err
+		}
+	}
+	if err := w.WriteDouble(v.Width); err != nil {
+		return err
+	}
+	if err := w.WriteQBrush(v.Brush); err != nil {
+		return err
+	}
+	if err := w.WriteDouble(v.MiterLimit); err != nil {
+		return err
+	}
+	if err := WriteList(w, v.DashPattern, (*Writer).WriteDouble); err != nil {
+		return err
+	}
+	if w.version >= VersionQt4_3 {
+		if err := w.WriteDouble(v.DashOffset); err != nil {
+			return err
+		}
+	}
+	if w.version >= VersionQt5_0 {
+		return w.WriteBool(v.DefaultWidth)
+	}
+	return nil
+}
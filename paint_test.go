@@ -0,0 +1,131 @@
+package cutestream
+
+import (
+	"bytes"
+	"encoding/hex"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const identityHex = "3ff0000000000000" + "0000000000000000" + "0000000000000000" +
+	"0000000000000000" + "3ff0000000000000" + "0000000000000000" +
+	"0000000000000000" + "0000000000000000" + "3ff0000000000000"
+
+// QVariant(QPen(Qt::red, 2)) with Qt 5.15 and double precision
+const penHex = "0000004c00" +
+	"0051" + "00" + // solid line, square cap, bevel join, not cosmetic
+	"4000000000000000" + // width
+	"01" + "01ffffffff000000000000" + identityHex + // brush
+	"4000000000000000" + // miter limit
+	"00000000" + // dash pattern
+	"0000000000000000" + // dash offset
+	"00" // default width
+
+func TestReadQPen(t *testing.T) {
+	data, _ := hex.DecodeString(penHex)
+	reader, err := NewReaderWithVersion(bytes.NewReader(data), VersionQt5_13)
+	assert.Nil(t, err)
+	reader.DoublePrecision = true
+	readType, v, err := reader.ReadQVariant()
+	assert.Nil(t, err)
+	assert.Equal(t, QMetaTypeQPen, readType)
+	assert.Equal(t, QPen{
+		Style:      SolidLine,
+		CapStyle:   SquareCap,
+		JoinStyle:  BevelJoin,
+		Width:      2,
+		Brush:      QBrush{Style: SolidPattern, Color: NewRgbColor(255, 0, 0, 255), Transform: IdentityTransform()},
+		MiterLimit: 2,
+	}, v)
+
+	var buf bytes.Buffer
+	writer, err := NewWriterWithVersion(&buf, VersionQt5_13)
+	assert.Nil(t, err)
+	writer.DoublePrecision = true
+	assert.Nil(t, writer.WriteQVariant(QMetaTypeQPen, v))
+	assert.Equal(t, data, buf.Bytes())
+}
+
+func TestPaintRoundTrip(t *testing.T) {
+	stops := []GradientStop{
+		{0, NewRgbColor(0, 0, 0, 255)},
+		{0.5, QColor{Spec: ColorSpecHsv, Alpha: 0xffff, Components: [4]uint16{18000, 0xffff, 0x8000, 0}}},
+		{1, QColor{Spec: ColorSpecCmyk, Alpha: 0x8080, Components: [4]uint16{1, 2, 3, 4}}},
+	}
+	linear := QBrush{
+		Style: LinearGradientPattern,
+		Gradient: &QGradient{
+			Type: LinearGradient, Spread: 1, CoordinateMode: 2, InterpolationMode: 1,
+			Stops: stops, Start: QPointF{0, 0}, FinalStop: QPointF{100, 0},
+		},
+		Transform: QTransform{M11: 2, M22: 0.5, M31: 10, M32: -10, M33: 1},
+	}
+	radial := QBrush{
+		Style: RadialGradientPattern,
+		Gradient: &QGradient{
+			Type: RadialGradient, Stops: stops,
+			Center: QPointF{50, 50}, FocalPoint: QPointF{40, 40}, Radius: 50, FocalRadius: 5,
+		},
+		Transform: IdentityTransform(),
+	}
+	conical := QBrush{
+		Style:     ConicalGradientPattern,
+		Gradient:  &QGradient{Type: ConicalGradient, Stops: stops[:1], Center: QPointF{1, 2}, Angle: 90},
+		Transform: IdentityTransform(),
+	}
+	pen := QPen{
+		Style:        CustomDashLine,
+		CapStyle:     RoundCap,
+		JoinStyle:    SvgMiterJoin,
+		Cosmetic:     true,
+		Width:        1.5,
+		Brush:        QBrush{Style: DiagCrossPattern, Color: NewRgbColor(1, 2, 3, 4), Transform: IdentityTransform()},
+		MiterLimit:   4,
+		DashPattern:  []float64{4, 2, 1, 2},
+		DashOffset:   0.5,
+		DefaultWidth: false,
+	}
+	values := []struct {
+		t QMetaType
+		v interface{}
+	}{
+		{QMetaTypeQColor, NewRgbColor(10, 20, 30, 40)},
+		{QMetaTypeQColor, QColor{Spec: ColorSpecInvalid, Alpha: 0xffff}},
+		{QMetaTypeQTransform, linear.Transform},
+		{QMetaTypeQBrush, linear},
+		{QMetaTypeQBrush, radial},
+		{QMetaTypeQBrush, conical},
+		{QMetaTypeQPen, pen},
+	}
+	for _, version := range []int{VersionQt5_13, VersionQt6_0} {
+		radial.Gradient.FocalRadius = 0
+		if version >= VersionQt6_0 {
+			radial.Gradient.FocalRadius = 5
+		}
+		var buf bytes.Buffer
+		writer, err := NewWriterWithVersion(&buf, version)
+		assert.Nil(t, err)
+		for _, v := range values {
+			assert.Nil(t, writer.WriteQVariant(v.t, v.v))
+		}
+		reader, err := NewReaderWithVersion(&buf, version)
+		assert.Nil(t, err)
+		for _, v := range values {
+			readType, readValue, err := reader.ReadQVariant()
+			assert.Nil(t, err)
+			assert.Equal(t, v.t, readType)
+			assert.Equal(t, v.v, readValue, "version %d", version)
+		}
+		assert.Equal(t, 0, buf.Len())
+	}
+}
+
+func TestQBrushErrors(t *testing.T) {
+	writer := NewWriter(&bytes.Buffer{})
+	assert.NotNil(t, writer.WriteQBrush(QBrush{Style: LinearGradientPattern}))
+
+	reader := NewReader(bytes.NewReader([]byte{24, 1, 0xff, 0xff, 0, 0, 0, 0, 0, 0, 0, 0}))
+	_, err := reader.ReadQBrush()
+	assert.NotNil(t, err)
+}
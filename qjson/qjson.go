@@ -0,0 +1,343 @@
+// Package qjson converts QVariant streams to JSON and back.
+//
+// The typed representation is lossless: every QVariant is an object with its metatype,
+// its null flag and its value, and the document keeps the stream version and the
+// floating point precision needed to write the stream back:
+//
+//	{
+//	  "version": 19,
+//	  "doublePrecision": true,
+//	  "values": [
+//	    {"type": "QVariantMap", "value": [{"key": "lap", "type": "int", "value": 3}]}
+//	  ]
+//	}
+//
+// QVariantMap and QVariantHash values are arrays of entries in the stream order,
+// so duplicate keys of QMultiMap are kept. A null QString inside a non-null QVariant,
+// which Qt 6 streams for QVariant(QString()), has a null value.
+// Metatypes are named like QMetaType::typeName() does, user types by their type name.
+// Structured values are objects with the fields of the corresponding cutestream type,
+// byte arrays and images (as PNG) are base64 encoded, dates and times are strings
+// keeping the time zone of a QDateTime, e.g. "2024-03-01T12:00:00.000+01:00[Europe/Berlin]",
+// and floating point values which are not numbers are "NaN", "Infinity" and "-Infinity".
+//
+// The plain representation is natural JSON without metatypes. Converting it back
+// deduces the metatypes from the JSON types: objects become QVariantMap, arrays QVariantList,
+// integers int or qlonglong, other numbers double and strings QString.
+package qjson
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+
+	"github.com/race-engineering-center/cutestream"
+)
+
+// Document is a stream of QVariant values in the typed representation
+type Document struct {
+	Version         int     `json:"version"`
+	DoublePrecision bool    `json:"doublePrecision"`
+	Values          []Value `json:"values"`
+}
+
+// Value is a QVariant in the typed representation. Value is omitted for null variants
+type Value struct {
+	Type  string          `json:"type"`
+	Null  bool            `json:"null,omitempty"`
+	Value json.RawMessage `json:"value,omitempty"`
+}
+
+// Entry is an entry of a QVariantMap or QVariantHash in the typed representation
+type Entry struct {
+	Key string `json:"key"`
+	Value
+}
+
+// ReadDocument reads QVariant values until the end of the stream.
+// A stream ending inside a value fails with cutestream.ErrTruncatedRecord
+func ReadDocument(r *cutestream.Reader) (*Document, error) {
+	d := &Document{Version: r.Version(), DoublePrecision: r.DoublePrecision, Values: []Value{}}
+	s := cutestream.NewRecordScanner(r, func(r *cutestream.Reader) (interface{}, error) {
+		return ReadValue(r)
+	})
+	for s.Next() {
+		d.Values = append(d.Values, s.Record().(Value))
+	}
+	if err := s.Err(); err != nil {
+		return nil, err
+	}
+	return d, nil
+}
+
+// WriteDocument writes the values of the document with its version and precision
+func WriteDocument(w io.Writer, d *Document) error {
+	writer, err := cutestream.NewWriterWithVersion(w, d.Version)
+	if err != nil {
+		return err
+	}
+	writer.DoublePrecision = d.DoublePrecision
+	for i, v := range d.Values {
+		if err := WriteValue(&writer, v); err != nil {
+			return fmt.Errorf("value %d: %w", i, err)
+		}
+	}
+	return nil
+}
+
+// ReadValue reads a QVariant. Container elements keep their metatypes
+func ReadValue(r *cutestream.Reader) (Value, error) {
+	return readValue(r, 0)
+}
+
+func readValue(r *cutestream.Reader, depth int) (Value, error) {
+	if depth >= cutestream.MaxNestingDepth {
+		return Value{}, cutestream.ErrTooDeep
+	}
+	readElement := func(r *cutestream.Reader) (Value, error) {
+		return readValue(r, depth+1)
+	}
+	h, err := r.ReadQVariantHeader()
+	if err != nil {
+		return Value{}, err
+	}
+	var data interface{}
+	switch h.Type {
+	case cutestream.QMetaTypeQVariantList:
+		list, err := cutestream.ReadList(r, readElement)
+		if err != nil {
+			return Value{}, err
+		}
+		if list == nil {
+			list = []Value{}
+		}
+		data = list
+	case cutestream.QMetaTypeQVariantMap, cutestream.QMetaTypeQVariantHash:
+		entries, err := cutestream.ReadList(r, func(r *cutestream.Reader) (Entry, error) {
+			key, err := r.ReadQString()
+			if err != nil {
+				return Entry{}, err
+			}
+			v, err := readElement(r)
+			return Entry{key, v}, err
+		})
+		if err != nil {
+			return Value{}, err
+		}
+		if entries == nil {
+			entries = []Entry{}
+		}
+		data = entries
+	case cutestream.QMetaTypeQString:
+		s, err := r.ReadNullQString()
+		if err != nil {
+			return Value{}, err
+		}
+		// a null string is kept as a null value
+		if s.Valid {
+			data = s.String
+		}
+	default:
+		v, err := r.ReadVariantValue(h)
+		if err != nil {
+			return Value{}, err
+		}
+		if data, err = encodeValue(h.Type, v, true); err != nil {
+			return Value{}, err
+		}
+	}
+	return newValue(h, data)
+}
+
+func newValue(h cutestream.VariantHeader, data interface{}) (Value, error) {
+	v := Value{Type: typeName(h), Null: h.Null}
+	if h.Null || h.Type == cutestream.QMetaTypeUnknown || h.Type == cutestream.QMetaTypeNullptr {
+		return v, nil
+	}
+	var err error
+	v.Value, err = json.Marshal(data)
+	return v, err
+}
+
+// WriteValue writes a QVariant
+func WriteValue(w *cutestream.Writer, v Value) error {
+	h, err := parseTypeName(v.Type)
+	if err != nil {
+		return err
+	}
+	h.Null = v.Null
+	if err := w.WriteQVariantHeader(h); err != nil {
+		return err
+	}
+	hasValue := !v.Null && len(v.Value) > 0
+	switch h.Type {
+	case cutestream.QMetaTypeQVariantList:
+		var list []Value
+		if hasValue {
+			if err := json.Unmarshal(v.Value, &list); err != nil {
+				return err
+			}
+		}
+		return cutestream.WriteList(w, list, WriteValue)
+	case cutestream.QMetaTypeQVariantMap, cutestream.QMetaTypeQVariantHash:
+		var entries []Entry
+		if hasValue {
+			if err := json.Unmarshal(v.Value, &entries); err != nil {
+				return err
+			}
+		}
+		return cutestream.WriteList(w, entries, func(w *cutestream.Writer, e Entry) error {
+			if err := w.WriteQString(e.Key); err != nil {
+				return err
+			}
+			return WriteValue(w, e.Value)
+		})
+	}
+	value, err := decodeValue(h, v.Value, hasValue)
+	if err != nil {
+		return err
+	}
+	return w.WriteValue(h.Type, value)
+}
+
+// typeName returns the name of the metatype of a QVariant
+func typeName(h cutestream.VariantHeader) string {
+	if h.Type == cutestream.QMetaTypeUser {
+		return h.Name
+	}
+	return h.Type.String()
+}
+
+// parseTypeName returns the QVariant header for a metatype name.
+// Names of unknown built-in types are their ids, other names are user types
+func parseTypeName(name string) (cutestream.VariantHeader, error) {
+	if name == "" {
+		return cutestream.VariantHeader{}, errors.New("missing type")
+	}
+	if t, ok := cutestream.MetaTypeByName(name); ok && t != cutestream.QMetaTypeUser {
+		return cutestream.VariantHeader{Type: t}, nil
+	}
+	if id, err := strconv.Atoi(name); err == nil {
+		return cutestream.VariantHeader{Type: cutestream.QMetaType(id)}, nil
+	}
+	return cutestream.VariantHeader{Type: cutestream.QMetaTypeUser, Name: name}, nil
+}
+
+// Plain converts a typed value to natural JSON data
+func Plain(v Value) (interface{}, error) {
+	if v.Null || len(v.Value) == 0 {
+		return nil, nil
+	}
+	h, err := parseTypeName(v.Type)
+	if err != nil {
+		return nil, err
+	}
+	switch h.Type {
+	case cutestream.QMetaTypeQVariantList:
+		var list []Value
+		if err := json.Unmarshal(v.Value, &list); err != nil {
+			return nil, err
+		}
+		res := make([]interface{}, len(list))
+		for i, item := range list {
+			if res[i], err = Plain(item); err != nil {
+				return nil, err
+			}
+		}
+		return res, nil
+	case cutestream.QMetaTypeQVariantMap, cutestream.QMetaTypeQVariantHash:
+		var entries []Entry
+		if err := json.Unmarshal(v.Value, &entries); err != nil {
+			return nil, err
+		}
+		// plain JSON objects keep the last value of duplicate keys
+		res := make(map[string]interface{}, len(entries))
+		for _, e := range entries {
+			if res[e.Key], err = Plain(e.Value); err != nil {
+				return nil, err
+			}
+		}
+		return res, nil
+	case cutestream.QMetaTypeQChar, cutestream.QMetaTypeChar16, cutestream.QMetaTypeChar32:
+		var c int32
+		if err := json.Unmarshal(v.Value, &c); err != nil {
+			return nil, err
+		}
+		if h.Type == cutestream.QMetaTypeChar32 {
+			return string(rune(c)), nil
+		}
+		return string(cutestream.QChar(c).Rune()), nil
+	}
+	// structured values keep the typed representation of the variants they contain
+	var res interface{}
+	err = json.Unmarshal(v.Value, &res)
+	return res, err
+}
+
+// ReadPlain reads a QVariant as natural JSON data
+func ReadPlain(r *cutestream.Reader) (interface{}, error) {
+	v, err := ReadValue(r)
+	if err != nil {
+		return nil, err
+	}
+	return Plain(v)
+}
+
+// WritePlain writes natural JSON data as a QVariant. Numbers are expected
+// to be decoded as json.Number to tell integers from doubles
+func WritePlain(w *cutestream.Writer, data interface{}) error {
+	v, err := fromPlain(data)
+	if err != nil {
+		return err
+	}
+	if v == nil {
+		return w.WriteQVariant(cutestream.QMetaTypeUnknown, nil)
+	}
+	t, err := cutestream.MetaTypeOf(v)
+	if err != nil {
+		return err
+	}
+	return w.WriteQVariant(t, v)
+}
+
+// fromPlain converts JSON data to values with the deduced metatypes
+func fromPlain(data interface{}) (interface{}, error) {
+	switch data := data.(type) {
+	case nil, bool, string:
+		return data, nil
+	case float64:
+		return data, nil
+	case json.Number:
+		if i, err := data.Int64(); err == nil {
+			if int64(int32(i)) == i {
+				return int32(i), nil
+			}
+			return i, nil
+		}
+		return data.Float64()
+	case []interface{}:
+		list := make([]interface{}, len(data))
+		for i, item := range data {
+			v, err := fromPlain(item)
+			if err != nil {
+				return nil, err
+			}
+			list[i] = v
+		}
+		return list, nil
+	case map[string]interface{}:
+		m := make(map[string]interface{}, len(data))
+		for k, item := range data {
+			v, err := fromPlain(item)
+			if err != nil {
+				return nil, err
+			}
+			m[k] = v
+		}
+		return m, nil
+	default:
+		return nil, fmt.Errorf("unsupported JSON value %T", data)
+	}
+}
@@ -0,0 +1,208 @@
+package qjson
+
+import (
+	"bytes"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"math"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/race-engineering-center/cutestream"
+	"github.com/stretchr/testify/assert"
+)
+
+func variants() []cutestream.Variant {
+	u, _ := url.Parse("https://example.com/laps?session=1")
+	return []cutestream.Variant{
+		{Type: cutestream.QMetaTypeUnknown},
+		{Type: cutestream.QMetaTypeBool, Value: true},
+		{Type: cutestream.QMetaTypeInt, Value: int32(-3)},
+		{Type: cutestream.QMetaTypeULongLong, Value: uint64(math.MaxUint64)},
+		{Type: cutestream.QMetaTypeDouble, Value: 0.1},
+		{Type: cutestream.QMetaTypeDouble, Value: math.Inf(-1)},
+		{Type: cutestream.QMetaTypeFloat, Value: float32(0.1)},
+		{Type: cutestream.QMetaTypeQString},
+		{Type: cutestream.QMetaTypeQString, Value: "Spa"},
+		{Type: cutestream.QMetaTypeQString, Value: cutestream.NullQString{}},
+		{Type: cutestream.QMetaTypeQString, Value: ""},
+		{Type: cutestream.QMetaTypeQChar, Value: cutestream.QChar('é')},
+		{Type: cutestream.QMetaTypeQByteArray, Value: []byte{0, 1, 0xff}},
+		{Type: cutestream.QMetaTypeQStringList, Value: []string{"a", "b"}},
+		{Type: cutestream.QMetaTypeQDate, Value: time.Date(2023, 7, 30, 0, 0, 0, 0, time.UTC)},
+		{Type: cutestream.QMetaTypeQTime, Value: 13*time.Hour + 5*time.Minute + 1234*time.Millisecond},
+		{Type: cutestream.QMetaTypeQDateTime, Value: time.Date(2023, 7, 30, 15, 4, 5, 6e6, time.UTC)},
+		{Type: cutestream.QMetaTypeQDateTime, Value: time.Date(2023, 7, 30, 15, 4, 5, 0, time.FixedZone("", -(5*3600+30*60)))},
+		{Type: cutestream.QMetaTypeQUrl, Value: u},
+		{Type: cutestream.QMetaTypeQUuid, Value: cutestream.QUuid{0x67, 0xc8, 0x77, 0x0b, 0x44, 0xf1, 0x41, 0x0a, 0xab, 0x9a, 0xf9, 0xb5, 0x44, 0x6f, 0x13, 0xee}},
+		{Type: cutestream.QMetaTypeQRectF, Value: cutestream.QRectF{X: 1, Y: 2, Width: 3.5, Height: 4}},
+		{Type: cutestream.QMetaTypeQColor, Value: cutestream.NewRgbColor(1, 2, 3, 255)},
+		{Type: cutestream.QMetaTypeQTextFormat, Value: cutestream.QTextFormat{Type: 1, Properties: map[int32]cutestream.Variant{
+			0x2000: {Type: cutestream.QMetaTypeInt, Value: int32(12)},
+		}}},
+		{Type: cutestream.QMetaTypeQVariantList, Value: []interface{}{int32(1), "two", nil, []interface{}{3.0}}},
+		{Type: cutestream.QMetaTypeQVariantMap, Value: map[string]interface{}{
+			"lap":  int32(3),
+			"time": cutestream.Variant{Type: cutestream.QMetaTypeFloat, Value: float32(81.5)},
+		}},
+		{Type: cutestream.QMetaTypeQVariantMap, Value: cutestream.OrderedMap{
+			{Key: "lap", Value: int32(1)},
+			{Key: "lap", Value: int32(2)},
+		}},
+		{Type: cutestream.QMetaTypeQVariantHash, Value: cutestream.OrderedMap{
+			{Key: "b", Value: true},
+			{Key: "a", Value: false},
+		}},
+		{Type: cutestream.QMetaTypeUser, Value: cutestream.QVersionNumber{6, 5, 2}},
+	}
+}
+
+func TestTypedRoundTrip(t *testing.T) {
+	for _, version := range []int{cutestream.VersionQt5_13, cutestream.VersionQt6_0} {
+		var stream bytes.Buffer
+		writer, err := cutestream.NewWriterWithVersion(&stream, version)
+		assert.Nil(t, err)
+		writer.DoublePrecision = true
+		for _, v := range variants() {
+			assert.Nil(t, writer.WriteQVariant(v.Type, v.Value))
+		}
+
+		reader, err := cutestream.NewReaderWithVersion(bytes.NewReader(stream.Bytes()), version)
+		assert.Nil(t, err)
+		reader.DoublePrecision = true
+		d, err := ReadDocument(&reader)
+		assert.Nil(t, err)
+		data, err := json.Marshal(d)
+		assert.Nil(t, err)
+
+		var decoded Document
+		assert.Nil(t, json.Unmarshal(data, &decoded))
+		var result bytes.Buffer
+		assert.Nil(t, WriteDocument(&result, &decoded))
+		assert.Equal(t, stream.Bytes(), result.Bytes(), "version %d", version)
+	}
+}
+
+func TestNullString(t *testing.T) {
+	// QVariant(QString()) and QVariant(QString("")) with Qt 6, null strings are not null variants
+	data, _ := hex.DecodeString("0000000a00" + "ffffffff" + "0000000a00" + "00000000")
+	reader, _ := cutestream.NewReaderWithVersion(bytes.NewReader(data), cutestream.VersionQt6_0)
+	d, err := ReadDocument(&reader)
+	assert.Nil(t, err)
+	encoded, err := json.Marshal(d.Values)
+	assert.Nil(t, err)
+	assert.JSONEq(t, `[{"type": "QString", "value": null}, {"type": "QString", "value": ""}]`, string(encoded))
+
+	var result bytes.Buffer
+	assert.Nil(t, WriteDocument(&result, d))
+	assert.Equal(t, data, result.Bytes())
+}
+
+func TestTruncatedDocument(t *testing.T) {
+	// a QString header without the string
+	data, _ := hex.DecodeString("0000000200" + "00000001" + "0000000a00")
+	reader := cutestream.NewReader(bytes.NewReader(data))
+	_, err := ReadDocument(&reader)
+	assert.True(t, errors.Is(err, cutestream.ErrTruncatedRecord), "%v", err)
+
+	reader = cutestream.NewReader(bytes.NewReader(data[:9]))
+	d, err := ReadDocument(&reader)
+	assert.Nil(t, err)
+	assert.Len(t, d.Values, 1)
+}
+
+func TestTypedRepresentation(t *testing.T) {
+	var stream bytes.Buffer
+	writer := cutestream.NewWriter(&stream)
+	assert.Nil(t, writer.WriteQVariant(cutestream.QMetaTypeQVariantMap, map[string]interface{}{
+		"lap":   int32(3),
+		"date":  cutestream.Variant{Type: cutestream.QMetaTypeQDate, Value: time.Date(2023, 7, 30, 0, 0, 0, 0, time.UTC)},
+		"speed": cutestream.Variant{Type: cutestream.QMetaTypeFloat, Value: float32(math.NaN())},
+		"name":  cutestream.Variant{Type: cutestream.QMetaTypeQString},
+	}))
+
+	reader := cutestream.NewReader(&stream)
+	v, err := ReadValue(&reader)
+	assert.Nil(t, err)
+	data, err := json.Marshal(v)
+	assert.Nil(t, err)
+	assert.JSONEq(t, `{"type": "QVariantMap", "value": [
+		{"key": "speed", "type": "float", "value": "NaN"},
+		{"key": "name", "type": "QString", "null": true},
+		{"key": "lap", "type": "int", "value": 3},
+		{"key": "date", "type": "QDate", "value": "2023-07-30"}
+	]}`, string(data))
+}
+
+func TestPlain(t *testing.T) {
+	var stream bytes.Buffer
+	writer := cutestream.NewWriter(&stream)
+	writer.DoublePrecision = true
+	assert.Nil(t, writer.WriteQVariant(cutestream.QMetaTypeQVariantMap, map[string]interface{}{
+		"lap":   int32(3),
+		"laps":  []interface{}{1.5, int64(1) << 40},
+		"name":  "Spa",
+		"valid": true,
+		"none":  nil,
+	}))
+	expected := stream.Bytes()
+
+	reader := cutestream.NewReader(bytes.NewReader(expected))
+	reader.DoublePrecision = true
+	v, err := ReadPlain(&reader)
+	assert.Nil(t, err)
+	data, err := json.Marshal(v)
+	assert.Nil(t, err)
+	assert.JSONEq(t, `{"lap": 3, "laps": [1.5, 1099511627776], "name": "Spa", "valid": true, "none": null}`, string(data))
+
+	var plain interface{}
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	assert.Nil(t, decoder.Decode(&plain))
+	var result bytes.Buffer
+	writer = cutestream.NewWriter(&result)
+	writer.DoublePrecision = true
+	assert.Nil(t, WritePlain(&writer, plain))
+	assert.Equal(t, expected, result.Bytes())
+}
+
+func TestDateTime(t *testing.T) {
+	berlin, err := time.LoadLocation("Europe/Berlin")
+	assert.Nil(t, err)
+	for _, tm := range []time.Time{
+		time.Date(2023, 7, 30, 15, 4, 5, 0, time.Local),
+		time.Date(2023, 7, 30, 15, 4, 5, 0, time.UTC),
+		time.Date(2023, 7, 30, 15, 4, 5, 0, time.FixedZone("", 3600+15)),
+		time.Date(2023, 7, 30, 15, 4, 5, 0, time.FixedZone("", -1800)),
+		time.Date(2023, 10, 29, 2, 30, 0, 0, time.FixedZone("", 3600)).In(berlin),
+	} {
+		parsed, err := parseDateTime(formatDateTime(tm))
+		assert.Nil(t, err)
+		assert.True(t, tm.Equal(parsed))
+		_, offset := tm.Zone()
+		_, parsedOffset := parsed.Zone()
+		assert.Equal(t, offset, parsedOffset)
+		assert.Equal(t, tm.Location() == time.Local, parsed.Location() == time.Local)
+		assert.Equal(t, tm.Location().String(), parsed.Location().String())
+	}
+}
+
+func TestDateTimeZone(t *testing.T) {
+	// QVariant(QDateTime(QDate(2024, 3, 1), QTime(12, 0), QTimeZone("Europe/Berlin"))) with Qt 5.15
+	data, _ := hex.DecodeString("0000001000" + "0000000000258ad3" + "02932e00" + "03" +
+		"0000001a" + "004500750072006f00700065002f004200650072006c0069006e")
+	reader := cutestream.NewReader(bytes.NewReader(data))
+	d, err := ReadDocument(&reader)
+	assert.Nil(t, err)
+	j, err := json.Marshal(d)
+	assert.Nil(t, err)
+	assert.Contains(t, string(j), `"value":"2024-03-01T12:00:00.000+01:00[Europe/Berlin]"`)
+
+	var decoded Document
+	assert.Nil(t, json.Unmarshal(j, &decoded))
+	var buf bytes.Buffer
+	assert.Nil(t, WriteDocument(&buf, &decoded))
+	assert.Equal(t, data, buf.Bytes())
+}
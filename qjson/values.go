@@ -0,0 +1,575 @@
+package qjson
+
+import (
+	"bytes"
+	"encoding"
+	"encoding/json"
+	"fmt"
+	"image"
+	"image/png"
+	"math"
+	"net/url"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/race-engineering-center/cutestream"
+)
+
+var (
+	variantType   = reflect.TypeOf(cutestream.Variant{})
+	timeType      = reflect.TypeOf(time.Time{})
+	durationType  = reflect.TypeOf(time.Duration(0))
+	urlType       = reflect.TypeOf(&url.URL{})
+	imageType     = reflect.TypeOf((*image.Image)(nil)).Elem()
+	bytesType     = reflect.TypeOf([]byte{})
+	unmarshalerTy = reflect.TypeOf((*encoding.TextUnmarshaler)(nil)).Elem()
+)
+
+// valueTypes are the Go types of the values of the metatypes, as returned by cutestream.Reader
+var valueTypes = map[cutestream.QMetaType]reflect.Type{
+	cutestream.QMetaTypeBool:               reflect.TypeOf(false),
+	cutestream.QMetaTypeInt:                reflect.TypeOf(int32(0)),
+	cutestream.QMetaTypeUInt:               reflect.TypeOf(uint32(0)),
+	cutestream.QMetaTypeLongLong:           reflect.TypeOf(int64(0)),
+	cutestream.QMetaTypeULongLong:          reflect.TypeOf(uint64(0)),
+	cutestream.QMetaTypeDouble:             reflect.TypeOf(float64(0)),
+	cutestream.QMetaTypeFloat:              reflect.TypeOf(float32(0)),
+	cutestream.QMetaTypeQChar:              reflect.TypeOf(cutestream.QChar(0)),
+	cutestream.QMetaTypeChar16:             reflect.TypeOf(cutestream.QChar(0)),
+	cutestream.QMetaTypeChar32:             reflect.TypeOf(rune(0)),
+	cutestream.QMetaTypeChar:               reflect.TypeOf(uint8(0)),
+	cutestream.QMetaTypeUChar:              reflect.TypeOf(uint8(0)),
+	cutestream.QMetaTypeSChar:              reflect.TypeOf(int8(0)),
+	cutestream.QMetaTypeShort:              reflect.TypeOf(int16(0)),
+	cutestream.QMetaTypeUShort:             reflect.TypeOf(uint16(0)),
+	cutestream.QMetaTypeQBitArray:          reflect.TypeOf([]bool{}),
+	cutestream.QMetaTypeQUuid:              reflect.TypeOf(cutestream.QUuid{}),
+	cutestream.QMetaTypeQByteArray:         bytesType,
+	cutestream.QMetaTypeQByteArrayList:     reflect.TypeOf([][]byte{}),
+	cutestream.QMetaTypeQString:            reflect.TypeOf(""),
+	cutestream.QMetaTypeQStringList:        reflect.TypeOf([]string{}),
+	cutestream.QMetaTypeQDate:              timeType,
+	cutestream.QMetaTypeQTime:              durationType,
+	cutestream.QMetaTypeQDateTime:          timeType,
+	cutestream.QMetaTypeQUrl:               urlType,
+	cutestream.QMetaTypeQPoint:             reflect.TypeOf(cutestream.QPoint{}),
+	cutestream.QMetaTypeQPointF:            reflect.TypeOf(cutestream.QPointF{}),
+	cutestream.QMetaTypeQSize:              reflect.TypeOf(cutestream.QSize{}),
+	cutestream.QMetaTypeQSizeF:             reflect.TypeOf(cutestream.QSizeF{}),
+	cutestream.QMetaTypeQRect:              reflect.TypeOf(cutestream.QRect{}),
+	cutestream.QMetaTypeQRectF:             reflect.TypeOf(cutestream.QRectF{}),
+	cutestream.QMetaTypeQLine:              reflect.TypeOf(cutestream.QLine{}),
+	cutestream.QMetaTypeQLineF:             reflect.TypeOf(cutestream.QLineF{}),
+	cutestream.QMetaTypeQFont:              reflect.TypeOf(cutestream.QFont{}),
+	cutestream.QMetaTypeQBrush:             reflect.TypeOf(cutestream.QBrush{}),
+	cutestream.QMetaTypeQColor:             reflect.TypeOf(cutestream.QColor{}),
+	cutestream.QMetaTypeQPen:               reflect.TypeOf(cutestream.QPen{}),
+	cutestream.QMetaTypeQTransform:         reflect.TypeOf(cutestream.QTransform{}),
+	cutestream.QMetaTypeQMatrix:            reflect.TypeOf(cutestream.QMatrix{}),
+	cutestream.QMetaTypeQMatrix4x4:         reflect.TypeOf(cutestream.QMatrix4x4{}),
+	cutestream.QMetaTypeQVector2D:          reflect.TypeOf(cutestream.QVector2D{}),
+	cutestream.QMetaTypeQVector3D:          reflect.TypeOf(cutestream.QVector3D{}),
+	cutestream.QMetaTypeQVector4D:          reflect.TypeOf(cutestream.QVector4D{}),
+	cutestream.QMetaTypeQQuaternion:        reflect.TypeOf(cutestream.QQuaternion{}),
+	cutestream.QMetaTypeQPolygon:           reflect.TypeOf(cutestream.QPolygon{}),
+	cutestream.QMetaTypeQPolygonF:          reflect.TypeOf(cutestream.QPolygonF{}),
+	cutestream.QMetaTypeQRegion:            reflect.TypeOf(cutestream.QRegion{}),
+	cutestream.QMetaTypeQLocale:            reflect.TypeOf(cutestream.QLocale("")),
+	cutestream.QMetaTypeQRegExp:            reflect.TypeOf(cutestream.QRegExp{}),
+	cutestream.QMetaTypeQRegularExpression: reflect.TypeOf(cutestream.QRegularExpression{}),
+	cutestream.QMetaTypeQEasingCurve:       reflect.TypeOf(cutestream.QEasingCurve{}),
+	cutestream.QMetaTypeQImage:             imageType,
+	cutestream.QMetaTypeQPixmap:            imageType,
+	cutestream.QMetaTypeQBitmap:            imageType,
+	cutestream.QMetaTypeQKeySequence:       reflect.TypeOf(cutestream.QKeySequence{}),
+	cutestream.QMetaTypeQCursor:            reflect.TypeOf(cutestream.QCursor{}),
+	cutestream.QMetaTypeQSizePolicy:        reflect.TypeOf(cutestream.QSizePolicy{}),
+	cutestream.QMetaTypeQTextLength:        reflect.TypeOf(cutestream.QTextLength{}),
+	cutestream.QMetaTypeQTextFormat:        reflect.TypeOf(cutestream.QTextFormat{}),
+	cutestream.QMetaTypeQPalette:           reflect.TypeOf(cutestream.QPalette{}),
+	cutestream.QMetaTypeQIcon:              reflect.TypeOf(cutestream.QIcon{}),
+}
+
+// userValueTypes are the Go types of the supported user types
+var userValueTypes = map[string]reflect.Type{
+	"QVersionNumber": reflect.TypeOf(cutestream.QVersionNumber{}),
+	"QTimeZone":      reflect.TypeOf(cutestream.QTimeZone{}),
+}
+
+const (
+	dateLayout     = "2006-01-02"
+	timeLayout     = "15:04:05.000"
+	dateTimeLayout = "2006-01-02T15:04:05.000"
+)
+
+// encodeValue converts a value of the specified metatype to JSON data
+func encodeValue(t cutestream.QMetaType, v interface{}, typed bool) (interface{}, error) {
+	switch t {
+	case cutestream.QMetaTypeQDate:
+		return v.(time.Time).Format(dateLayout), nil
+	case cutestream.QMetaTypeQTime:
+		return formatTime(v.(time.Duration)), nil
+	case cutestream.QMetaTypeQDateTime:
+		return formatDateTime(v.(time.Time)), nil
+	}
+	return encode(reflect.ValueOf(v), typed)
+}
+
+// encode converts a value to JSON data. Variants are typed values in the typed representation
+func encode(v reflect.Value, typed bool) (interface{}, error) {
+	if !v.IsValid() {
+		return nil, nil
+	}
+	if v.Kind() == reflect.Interface && v.Type().NumMethod() == 0 {
+		// elements of QVariantList, QVariantMap and QTextFormat values
+		if v.IsNil() {
+			return encodeVariant(cutestream.Variant{}, typed)
+		}
+		if x, ok := v.Elem().Interface().(cutestream.Variant); ok {
+			return encodeVariant(x, typed)
+		}
+		t, err := cutestream.MetaTypeOf(v.Elem().Interface())
+		if err != nil {
+			return nil, err
+		}
+		return encodeVariant(cutestream.Variant{Type: t, Value: v.Elem().Interface()}, typed)
+	}
+	switch x := v.Interface().(type) {
+	case cutestream.Variant:
+		return encodeVariant(x, typed)
+	case image.Image:
+		if v.Kind() == reflect.Ptr && v.IsNil() {
+			return nil, nil
+		}
+		var buf bytes.Buffer
+		if err := png.Encode(&buf, x); err != nil {
+			return nil, err
+		}
+		return buf.Bytes(), nil
+	case []byte, encoding.TextMarshaler:
+		return x, nil
+	case *url.URL:
+		if x == nil {
+			return nil, nil
+		}
+		return x.String(), nil
+	}
+	switch v.Kind() {
+	case reflect.Float32:
+		return encodeFloat(v.Float(), 32), nil
+	case reflect.Float64:
+		return encodeFloat(v.Float(), 64), nil
+	case reflect.Bool, reflect.String,
+		reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return v.Interface(), nil
+	case reflect.Slice, reflect.Array:
+		if v.Kind() == reflect.Slice && v.IsNil() {
+			return nil, nil
+		}
+		list := make([]interface{}, v.Len())
+		for i := range list {
+			var err error
+			if list[i], err = encode(v.Index(i), typed); err != nil {
+				return nil, err
+			}
+		}
+		return list, nil
+	case reflect.Map:
+		if v.IsNil() {
+			return nil, nil
+		}
+		m := make(map[string]interface{}, v.Len())
+		iter := v.MapRange()
+		for iter.Next() {
+			item, err := encode(iter.Value(), typed)
+			if err != nil {
+				return nil, err
+			}
+			m[fmt.Sprint(iter.Key().Interface())] = item
+		}
+		return m, nil
+	case reflect.Struct:
+		m := make(map[string]interface{}, v.NumField())
+		for i := 0; i < v.NumField(); i++ {
+			if !v.Type().Field(i).IsExported() {
+				continue
+			}
+			item, err := encode(v.Field(i), typed)
+			if err != nil {
+				return nil, err
+			}
+			m[v.Type().Field(i).Name] = item
+		}
+		return m, nil
+	case reflect.Ptr, reflect.Interface:
+		if v.IsNil() {
+			return nil, nil
+		}
+		return encode(v.Elem(), typed)
+	default:
+		return nil, fmt.Errorf("can't encode %s", v.Type())
+	}
+}
+
+func encodeVariant(v cutestream.Variant, typed bool) (interface{}, error) {
+	if v.Type == cutestream.QMetaTypeUser && v.Value == nil {
+		return nil, fmt.Errorf("null user type values can't be encoded")
+	}
+	var data interface{}
+	if v.Value != nil {
+		var err error
+		if data, err = encodeValue(v.Type, v.Value, typed); err != nil {
+			return nil, err
+		}
+	}
+	if !typed {
+		return data, nil
+	}
+	h := cutestream.VariantHeader{Type: v.Type, Null: v.Value == nil}
+	if v.Type == cutestream.QMetaTypeUser {
+		h.Name = reflect.TypeOf(v.Value).Name()
+	}
+	value, err := newValue(h, data)
+	if err != nil {
+		return nil, err
+	}
+	return value, nil
+}
+
+func encodeFloat(f float64, bitSize int) interface{} {
+	switch {
+	case math.IsNaN(f):
+		return "NaN"
+	case math.IsInf(f, 1):
+		return "Infinity"
+	case math.IsInf(f, -1):
+		return "-Infinity"
+	}
+	return json.Number(strconv.FormatFloat(f, 'g', -1, bitSize))
+}
+
+// formatTime formats a time of the day, invalid times are kept as milliseconds
+func formatTime(d time.Duration) interface{} {
+	if d < 0 || d >= 24*time.Hour || d%time.Millisecond != 0 {
+		return int64(d / time.Millisecond)
+	}
+	return time.Time{}.Add(d).Format(timeLayout)
+}
+
+// formatDateTime formats a date and time with the offset from UTC.
+// Local times have no offset, UTC times end with Z and times in a time zone
+// of the time zone database end with its id, e.g. 2024-03-01T12:00:00.000+01:00[Europe/Berlin]
+func formatDateTime(t time.Time) string {
+	s := t.Format(dateTimeLayout)
+	switch t.Location() {
+	case time.Local:
+		return s
+	case time.UTC:
+		return s + "Z"
+	}
+	_, offset := t.Zone()
+	sign := "+"
+	if offset < 0 {
+		sign, offset = "-", -offset
+	}
+	s += fmt.Sprintf("%s%02d:%02d", sign, offset/3600, offset/60%60)
+	if offset%60 != 0 {
+		s += fmt.Sprintf(":%02d", offset%60)
+	}
+	// like cutestream.Writer, which writes such times with their QTimeZone
+	if name := t.Location().String(); name != "" {
+		if _, err := time.LoadLocation(name); err == nil {
+			s += "[" + name + "]"
+		}
+	}
+	return s
+}
+
+// decodeValue converts JSON data to a value of the metatype of the header.
+// nil is returned for missing values, which are written as the default value of the metatype
+func decodeValue(h cutestream.VariantHeader, data json.RawMessage, hasValue bool) (interface{}, error) {
+	switch h.Type {
+	case cutestream.QMetaTypeUnknown, cutestream.QMetaTypeNullptr:
+		return nil, nil
+	}
+	t, ok := valueTypes[h.Type]
+	if h.Type == cutestream.QMetaTypeUser {
+		t, ok = userValueTypes[h.Name]
+	}
+	if !ok {
+		return nil, fmt.Errorf("unsupported type %s", typeName(h))
+	}
+	if !hasValue {
+		if h.Type == cutestream.QMetaTypeUser {
+			return reflect.Zero(t).Interface(), nil
+		}
+		return nil, nil
+	}
+	switch h.Type {
+	case cutestream.QMetaTypeQString:
+		if string(data) == "null" {
+			return cutestream.NullQString{}, nil
+		}
+	case cutestream.QMetaTypeQDate:
+		return time.Parse(dateLayout, unquote(data))
+	case cutestream.QMetaTypeQTime:
+		return parseTime(data)
+	}
+	v, err := decode(data, t)
+	if err != nil {
+		return nil, err
+	}
+	return v.Interface(), nil
+}
+
+// decode converts JSON data to a value of the specified type
+func decode(data json.RawMessage, t reflect.Type) (reflect.Value, error) {
+	if string(data) == "null" {
+		return reflect.Zero(t), nil
+	}
+	if t.Kind() == reflect.Interface && t.NumMethod() == 0 {
+		t = variantType
+	}
+	switch t {
+	case variantType:
+		var v Value
+		if err := json.Unmarshal(data, &v); err != nil {
+			return reflect.Value{}, err
+		}
+		variant, err := toVariant(v)
+		if err != nil {
+			return reflect.Value{}, err
+		}
+		return reflect.ValueOf(variant), nil
+	case timeType:
+		tm, err := parseDateTime(unquote(data))
+		return reflect.ValueOf(tm), err
+	case urlType:
+		u, err := url.Parse(unquote(data))
+		return reflect.ValueOf(u), err
+	case imageType:
+		var buf []byte
+		if err := json.Unmarshal(data, &buf); err != nil {
+			return reflect.Value{}, err
+		}
+		img, err := png.Decode(bytes.NewReader(buf))
+		if err != nil {
+			return reflect.Value{}, err
+		}
+		v := reflect.New(imageType).Elem()
+		v.Set(reflect.ValueOf(img))
+		return v, nil
+	case bytesType:
+		return decodeJSON(data, t)
+	}
+	if reflect.PtrTo(t).Implements(unmarshalerTy) {
+		return decodeJSON(data, t)
+	}
+	switch t.Kind() {
+	case reflect.Float32, reflect.Float64:
+		return decodeFloat(data, t)
+	case reflect.Bool, reflect.String,
+		reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return decodeJSON(data, t)
+	case reflect.Slice, reflect.Array:
+		var items []json.RawMessage
+		if err := json.Unmarshal(data, &items); err != nil {
+			return reflect.Value{}, err
+		}
+		var v reflect.Value
+		if t.Kind() == reflect.Slice {
+			v = reflect.MakeSlice(t, len(items), len(items))
+		} else if len(items) != t.Len() {
+			return reflect.Value{}, fmt.Errorf("expected %d elements for %s, got %d", t.Len(), t, len(items))
+		} else {
+			v = reflect.New(t).Elem()
+		}
+		for i, item := range items {
+			e, err := decode(item, t.Elem())
+			if err != nil {
+				return reflect.Value{}, err
+			}
+			v.Index(i).Set(e)
+		}
+		return v, nil
+	case reflect.Map:
+		var items map[string]json.RawMessage
+		if err := json.Unmarshal(data, &items); err != nil {
+			return reflect.Value{}, err
+		}
+		v := reflect.MakeMapWithSize(t, len(items))
+		for k, item := range items {
+			key, err := decodeJSON(json.RawMessage(strconv.Quote(k)), t.Key())
+			if err != nil {
+				// integer keys
+				key, err = decodeJSON(json.RawMessage(k), t.Key())
+			}
+			if err != nil {
+				return reflect.Value{}, err
+			}
+			e, err := decode(item, t.Elem())
+			if err != nil {
+				return reflect.Value{}, err
+			}
+			v.SetMapIndex(key, e)
+		}
+		return v, nil
+	case reflect.Struct:
+		var fields map[string]json.RawMessage
+		if err := json.Unmarshal(data, &fields); err != nil {
+			return reflect.Value{}, err
+		}
+		v := reflect.New(t).Elem()
+		for i := 0; i < t.NumField(); i++ {
+			field, ok := fields[t.Field(i).Name]
+			if !ok || !t.Field(i).IsExported() {
+				continue
+			}
+			e, err := decode(field, t.Field(i).Type)
+			if err != nil {
+				return reflect.Value{}, fmt.Errorf("%s.%s: %w", t.Name(), t.Field(i).Name, err)
+			}
+			v.Field(i).Set(e)
+		}
+		return v, nil
+	case reflect.Ptr:
+		e, err := decode(data, t.Elem())
+		if err != nil {
+			return reflect.Value{}, err
+		}
+		v := reflect.New(t.Elem())
+		v.Elem().Set(e)
+		return v, nil
+	default:
+		return reflect.Value{}, fmt.Errorf("can't decode %s", t)
+	}
+}
+
+func decodeJSON(data json.RawMessage, t reflect.Type) (reflect.Value, error) {
+	v := reflect.New(t)
+	if err := json.Unmarshal(data, v.Interface()); err != nil {
+		return reflect.Value{}, err
+	}
+	return v.Elem(), nil
+}
+
+func decodeFloat(data json.RawMessage, t reflect.Type) (reflect.Value, error) {
+	var f float64
+	switch unquote(data) {
+	case "NaN":
+		f = math.NaN()
+	case "Infinity":
+		f = math.Inf(1)
+	case "-Infinity":
+		f = math.Inf(-1)
+	default:
+		return decodeJSON(data, t)
+	}
+	return reflect.ValueOf(f).Convert(t), nil
+}
+
+// toVariant converts a typed value to a Variant. Container elements are Variants as well
+func toVariant(v Value) (cutestream.Variant, error) {
+	h, err := parseTypeName(v.Type)
+	if err != nil {
+		return cutestream.Variant{}, err
+	}
+	variant := cutestream.Variant{Type: h.Type}
+	if v.Null || len(v.Value) == 0 {
+		return variant, nil
+	}
+	switch h.Type {
+	case cutestream.QMetaTypeQVariantList:
+		var items []Value
+		if err := json.Unmarshal(v.Value, &items); err != nil {
+			return cutestream.Variant{}, err
+		}
+		list := make([]interface{}, len(items))
+		for i, item := range items {
+			if list[i], err = toVariant(item); err != nil {
+				return cutestream.Variant{}, err
+			}
+		}
+		variant.Value = list
+	case cutestream.QMetaTypeQVariantMap, cutestream.QMetaTypeQVariantHash:
+		var items map[string]Value
+		if err := json.Unmarshal(v.Value, &items); err != nil {
+			return cutestream.Variant{}, err
+		}
+		m := make(map[string]interface{}, len(items))
+		for k, item := range items {
+			if m[k], err = toVariant(item); err != nil {
+				return cutestream.Variant{}, err
+			}
+		}
+		variant.Value = m
+	default:
+		variant.Value, err = decodeValue(h, v.Value, true)
+	}
+	return variant, err
+}
+
+func unquote(data json.RawMessage) string {
+	var s string
+	if err := json.Unmarshal(data, &s); err != nil {
+		return string(data)
+	}
+	return s
+}
+
+func parseTime(data json.RawMessage) (time.Duration, error) {
+	var ms int64
+	if err := json.Unmarshal(data, &ms); err == nil {
+		return time.Duration(ms) * time.Millisecond, nil
+	}
+	t, err := time.Parse(timeLayout, unquote(data))
+	if err != nil {
+		return 0, err
+	}
+	return t.Sub(time.Date(0, 1, 1, 0, 0, 0, 0, time.UTC)), nil
+}
+
+// parseDateTime parses a date and time formatted by formatDateTime
+func parseDateTime(s string) (time.Time, error) {
+	var zone *time.Location
+	if i := strings.LastIndex(s, "["); i >= 0 && strings.HasSuffix(s, "]") {
+		var err error
+		if zone, err = time.LoadLocation(s[i+1 : len(s)-1]); err != nil {
+			return time.Time{}, err
+		}
+		s = s[:i]
+	}
+	loc := time.Local
+	offset := 0
+	if i := strings.LastIndexAny(s, "+-Z"); i > len(dateLayout) {
+		zone := s[i:]
+		s = s[:i]
+		if zone == "Z" {
+			loc = time.UTC
+		} else {
+			var h, m, sec int
+			if _, err := fmt.Sscanf(zone[1:]+":0", "%d:%d:%d", &h, &m, &sec); err != nil {
+				return time.Time{}, fmt.Errorf("invalid offset %q", zone)
+			}
+			offset = h*3600 + m*60 + sec
+			if zone[0] == '-' {
+				offset = -offset
+			}
+			loc = time.FixedZone("", offset)
+		}
+	}
+	t, err := time.ParseInLocation(dateTimeLayout, s, time.UTC)
+	if err != nil {
+		return time.Time{}, err
+	}
+	t = time.Date(t.Year(), t.Month(), t.Day(), t.Hour(), t.Minute(), t.Second(), t.Nanosecond(), loc)
+	if zone != nil {
+		// the offset tells ambiguous local times of the zone apart
+		t = t.In(zone)
+	}
+	return t, nil
+}
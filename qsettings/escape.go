@@ -0,0 +1,264 @@
+package qsettings
+
+import (
+	"strconv"
+	"strings"
+	"unicode/utf16"
+)
+
+// Escaping rules ported from qtbase/src/corelib/io/qsettings.cpp
+
+const hexDigits = "0123456789ABCDEF"
+
+func escapeKey(key string) string {
+	var b strings.Builder
+	for _, ch := range utf16.Encode([]rune(key)) {
+		switch {
+		case ch == '/':
+			b.WriteByte('\\')
+		case (ch >= 'a' && ch <= 'z') || (ch >= 'A' && ch <= 'Z') || (ch >= '0' && ch <= '9') ||
+			ch == '_' || ch == '-' || ch == '.':
+			b.WriteByte(byte(ch))
+		case ch <= 0xFF:
+			b.WriteByte('%')
+			b.WriteByte(hexDigits[ch/16])
+			b.WriteByte(hexDigits[ch%16])
+		default:
+			b.WriteString("%U")
+			for shift := 12; shift >= 0; shift -= 4 {
+				b.WriteByte(hexDigits[(ch>>shift)&0xF])
+			}
+		}
+	}
+	return b.String()
+}
+
+func unescapeKey(key string) string {
+	var units []uint16
+	for i := 0; i < len(key); {
+		ch := key[i]
+		if ch == '\\' {
+			units = append(units, '/')
+			i++
+			continue
+		}
+		if ch != '%' || i == len(key)-1 {
+			j := i + 1
+			for j < len(key) && key[j] != '%' && key[j] != '\\' {
+				j++
+			}
+			units = append(units, utf16.Encode([]rune(key[i:j]))...)
+			i = j
+			continue
+		}
+		numDigits := 2
+		firstDigitPos := i + 1
+		if key[i+1] == 'U' {
+			firstDigitPos++
+			numDigits = 4
+		}
+		if firstDigitPos+numDigits > len(key) {
+			units = append(units, '%')
+			i++
+			continue
+		}
+		v, err := strconv.ParseUint(key[firstDigitPos:firstDigitPos+numDigits], 16, 16)
+		if err != nil {
+			units = append(units, '%')
+			i++
+			continue
+		}
+		units = append(units, uint16(v))
+		i = firstDigitPos + numDigits
+	}
+	return string(utf16.Decode(units))
+}
+
+var escapeCodes = map[rune]rune{
+	'a': '\a', 'b': '\b', 'f': '\f', 'n': '\n', 'r': '\r', 't': '\t', 'v': '\v',
+	'"': '"', '?': '?', '\'': '\'', '\\': '\\',
+}
+
+func isHexDigit(ch rune) bool {
+	return (ch >= '0' && ch <= '9') || (ch >= 'a' && ch <= 'f') || (ch >= 'A' && ch <= 'F')
+}
+
+func isSpace(ch rune) bool {
+	return ch == ' ' || ch == '\t'
+}
+
+// unescapeValue parses the value part of an INI line. A value containing
+// unquoted commas is a string list, otherwise it's a single string
+func unescapeValue(str string) (string, []string, bool) {
+	in := []rune(str)
+	var result []rune
+	var list []string
+	isList := false
+	inQuotes := false
+	quoted := false
+	chopLimit := 0
+
+	chop := func() {
+		n := len(result)
+		for n > chopLimit && isSpace(result[n-1]) {
+			n--
+		}
+		result = result[:n]
+	}
+	skipSpaces := func(i int) int {
+		for i < len(in) && isSpace(in[i]) {
+			i++
+		}
+		return i
+	}
+
+	i := skipSpaces(0)
+	chopLimit = len(result)
+	for i < len(in) {
+		switch in[i] {
+		case '\\':
+			i++
+			if i >= len(in) {
+				break
+			}
+			ch := in[i]
+			i++
+			if code, ok := escapeCodes[ch]; ok {
+				result = append(result, code)
+				chopLimit = len(result)
+				continue
+			}
+			switch {
+			case ch == 'x':
+				if i < len(in) && isHexDigit(in[i]) {
+					v := 0
+					for i < len(in) && isHexDigit(in[i]) {
+						d, _ := strconv.ParseUint(string(in[i]), 16, 8)
+						v = v<<4 + int(d)
+						i++
+					}
+					result = append(result, rune(uint16(v)))
+				}
+			case ch >= '0' && ch <= '7':
+				v := int(ch - '0')
+				for i < len(in) && in[i] >= '0' && in[i] <= '7' {
+					v = v<<3 + int(in[i]-'0')
+					i++
+				}
+				result = append(result, rune(uint16(v)))
+			case ch == '\n' || ch == '\r':
+				if i < len(in) && (in[i] == '\n' || in[i] == '\r') && in[i] != ch {
+					i++
+				}
+			}
+			chopLimit = len(result)
+		case '"':
+			i++
+			quoted = true
+			inQuotes = !inQuotes
+			if !inQuotes {
+				i = skipSpaces(i)
+				chopLimit = len(result)
+			}
+		case ',':
+			if !inQuotes {
+				if !quoted {
+					chop()
+				}
+				isList = true
+				list = append(list, string(result))
+				result = nil
+				quoted = false
+				i = skipSpaces(i + 1)
+				chopLimit = 0
+				continue
+			}
+			result = append(result, in[i])
+			i++
+		default:
+			j := i + 1
+			for j < len(in) && in[j] != '\\' && in[j] != '"' && in[j] != ',' {
+				j++
+			}
+			result = append(result, in[i:j]...)
+			i = j
+		}
+	}
+	if !quoted {
+		chop()
+	}
+	if isList {
+		list = append(list, string(result))
+		return "", list, true
+	}
+	return string(result), nil, false
+}
+
+// escapeValue escapes a string for writing into an INI file. Non-ASCII
+// characters are written as UTF-8, except for binary data where they are escaped
+func escapeValue(str string) string {
+	var b strings.Builder
+	binary := strings.HasPrefix(str, byteArrayPrefix) || strings.HasPrefix(str, variantPrefix) ||
+		strings.HasPrefix(str, dateTimePrefix)
+	needsQuotes := false
+	escapeNextIfDigit := false
+	for _, ch := range str {
+		if ch == ';' || ch == ',' || ch == '=' {
+			needsQuotes = true
+		}
+		if escapeNextIfDigit && isHexDigit(ch) {
+			b.WriteString("\\x")
+			b.WriteString(strconv.FormatInt(int64(ch), 16))
+			continue
+		}
+		escapeNextIfDigit = false
+		switch ch {
+		case 0:
+			b.WriteString("\\0")
+			escapeNextIfDigit = true
+		case '\a':
+			b.WriteString("\\a")
+		case '\b':
+			b.WriteString("\\b")
+		case '\f':
+			b.WriteString("\\f")
+		case '\n':
+			b.WriteString("\\n")
+		case '\r':
+			b.WriteString("\\r")
+		case '\t':
+			b.WriteString("\\t")
+		case '\v':
+			b.WriteString("\\v")
+		case '"':
+			b.WriteString("\\\"")
+		case '\\':
+			b.WriteString("\\\\")
+		default:
+			if ch <= 0x1F || (ch >= 0x7F && binary) {
+				b.WriteString("\\x")
+				b.WriteString(strconv.FormatInt(int64(ch), 16))
+				escapeNextIfDigit = true
+			} else {
+				b.WriteRune(ch)
+			}
+		}
+	}
+	result := b.String()
+	if needsQuotes || strings.HasPrefix(result, " ") || strings.HasSuffix(result, " ") {
+		return `"` + result + `"`
+	}
+	return result
+}
+
+func escapeValueList(list []string) string {
+	if len(list) == 0 {
+		// an empty list can't be distinguished from a single empty string otherwise
+		return invalidValue
+	}
+	escaped := make([]string, len(list))
+	for i, s := range list {
+		escaped[i] = escapeValue(s)
+	}
+	return strings.Join(escaped, ", ")
+}
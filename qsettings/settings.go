@@ -0,0 +1,468 @@
+// Package qsettings reads and writes QSettings INI files, including values
+// stored as @Variant, @ByteArray, @DateTime, @Rect, @Size and @Point.
+package qsettings
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/race-engineering-center/cutestream"
+)
+
+// Stream versions used by QSettings for embedded QDataStream data
+const (
+	VariantVersion  = cutestream.VersionQt4_0
+	DateTimeVersion = cutestream.VersionQt5_6
+)
+
+const (
+	byteArrayPrefix = "@ByteArray("
+	stringPrefix    = "@String("
+	variantPrefix   = "@Variant("
+	dateTimePrefix  = "@DateTime("
+	rectPrefix      = "@Rect("
+	sizePrefix      = "@Size("
+	pointPrefix     = "@Point("
+	invalidValue    = "@Invalid()"
+	generalSection  = "General"
+)
+
+// RawVariant is a @Variant or @DateTime value which couldn't be decoded.
+// It is written back unchanged
+type RawVariant struct {
+	Prefix string // @Variant( or @DateTime(
+	Data   []byte
+	Err    error // Decoding error
+}
+
+// Settings is the content of a QSettings INI file.
+// Keys are full paths, e.g. "group/subgroup/key" for key "subgroup\key" in section [group].
+// Keys of the [General] section have no group.
+//
+// Values have the following types:
+//   - string for plain values
+//   - []string for comma-separated lists
+//   - []interface{} for lists containing non-string values
+//   - []byte for @ByteArray
+//   - cutestream.QRect, cutestream.QSize, cutestream.QPoint for @Rect, @Size and @Point
+//   - cutestream.Variant for @Variant and @DateTime
+//   - RawVariant for @Variant and @DateTime which couldn't be decoded
+//   - nil for @Invalid()
+type Settings struct {
+	// Write QDateTime values as @Variant like Qt 5 does instead of @DateTime used since Qt 6
+	LegacyDateTime bool
+
+	keys   []string
+	values map[string]interface{}
+}
+
+// New creates empty settings
+func New() *Settings {
+	return &Settings{values: map[string]interface{}{}}
+}
+
+// Open reads settings from the specified INI file
+func Open(name string) (*Settings, error) {
+	file, err := os.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	return Parse(file)
+}
+
+// Parse reads settings in INI format
+func Parse(r io.Reader) (*Settings, error) {
+	s := New()
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(nil, 64<<20)
+	section := ""
+	line := ""
+	lineNumber := 0
+	for scanner.Scan() {
+		lineNumber++
+		line += scanner.Text()
+		// a backslash at the end of line continues the value on the next one
+		if strings.HasSuffix(line, "\\") && !strings.HasSuffix(line, "\\\\") {
+			line = line[:len(line)-1]
+			continue
+		}
+		text := strings.TrimSpace(stripComment(line))
+		line = ""
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+		if strings.HasPrefix(text, "[") {
+			end := strings.Index(text, "]")
+			if end < 0 {
+				return nil, fmt.Errorf("line %d: unterminated section name", lineNumber)
+			}
+			name := text[1:end]
+			switch {
+			case strings.EqualFold(name, generalSection):
+				section = ""
+			case strings.EqualFold(name, "%"+generalSection):
+				section = name[1:]
+			default:
+				section = unescapeKey(name)
+			}
+			continue
+		}
+		eq := strings.Index(text, "=")
+		if eq < 0 {
+			continue
+		}
+		key := unescapeKey(strings.TrimSpace(text[:eq]))
+		if section != "" {
+			key = section + "/" + key
+		}
+		str, list, isList := unescapeValue(text[eq+1:])
+		if isList {
+			s.SetValue(key, listToValue(list))
+		} else {
+			s.SetValue(key, stringToValue(str))
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// stripComment removes a comment started with a semicolon outside of quotes
+func stripComment(line string) string {
+	inQuotes := false
+	for i := 0; i < len(line); i++ {
+		switch line[i] {
+		case '\\':
+			i++
+		case '"':
+			inQuotes = !inQuotes
+		case ';':
+			if !inQuotes {
+				return line[:i]
+			}
+		}
+	}
+	return line
+}
+
+// Keys returns all keys in the order of their appearance
+func (s *Settings) Keys() []string {
+	return append([]string(nil), s.keys...)
+}
+
+// Value returns the value for the key
+func (s *Settings) Value(key string) (interface{}, bool) {
+	v, ok := s.values[key]
+	return v, ok
+}
+
+// Variant returns the metatype and the value of a @Variant or @DateTime entry
+func (s *Settings) Variant(key string) (cutestream.QMetaType, interface{}, bool) {
+	v, ok := s.values[key].(cutestream.Variant)
+	return v.Type, v.Value, ok
+}
+
+// SetValue sets the value for the key. Along with the types listed for Settings,
+// booleans, integers and doubles are accepted and stored as strings, like QSettings does,
+// and any other value supported by cutestream.Writer is stored as @Variant
+func (s *Settings) SetValue(key string, v interface{}) {
+	if _, ok := s.values[key]; !ok {
+		s.keys = append(s.keys, key)
+	}
+	s.values[key] = v
+}
+
+// Remove removes the key
+func (s *Settings) Remove(key string) {
+	if _, ok := s.values[key]; !ok {
+		return
+	}
+	delete(s.values, key)
+	for i, k := range s.keys {
+		if k == key {
+			s.keys = append(s.keys[:i], s.keys[i+1:]...)
+			break
+		}
+	}
+}
+
+// Save writes the settings to the specified INI file
+func (s *Settings) Save(name string) error {
+	var buf bytes.Buffer
+	if _, err := s.WriteTo(&buf); err != nil {
+		return err
+	}
+	return os.WriteFile(name, buf.Bytes(), 0644)
+}
+
+// WriteTo writes the settings in INI format. The [General] section goes first,
+// other sections and keys keep the order of their appearance
+func (s *Settings) WriteTo(w io.Writer) (int64, error) {
+	var sections []string
+	sectionKeys := map[string][]string{}
+	for _, key := range s.keys {
+		section, name := "", key
+		if slash := strings.Index(key, "/"); slash >= 0 {
+			section, name = key[:slash], key[slash+1:]
+		}
+		if _, ok := sectionKeys[section]; !ok {
+			if section == "" {
+				sections = append([]string{section}, sections...)
+			} else {
+				sections = append(sections, section)
+			}
+		}
+		sectionKeys[section] = append(sectionKeys[section], name)
+	}
+
+	var buf bytes.Buffer
+	for i, section := range sections {
+		if i > 0 {
+			buf.WriteString("\n")
+		}
+		switch {
+		case section == "":
+			buf.WriteString("[" + generalSection + "]\n")
+		case strings.EqualFold(escapeKey(section), generalSection):
+			buf.WriteString("[%" + escapeKey(section) + "]\n")
+		default:
+			buf.WriteString("[" + escapeKey(section) + "]\n")
+		}
+		for _, name := range sectionKeys[section] {
+			key := name
+			if section != "" {
+				key = section + "/" + name
+			}
+			value, err := s.formatValue(s.values[key])
+			if err != nil {
+				return 0, fmt.Errorf("%s: %w", key, err)
+			}
+			buf.WriteString(escapeKey(name) + "=" + value + "\n")
+		}
+	}
+	n, err := w.Write(buf.Bytes())
+	return int64(n), err
+}
+
+func listToValue(list []string) interface{} {
+	for _, str := range list {
+		if strings.HasPrefix(str, "@") && !strings.HasPrefix(str, "@@") {
+			values := make([]interface{}, len(list))
+			for i, item := range list {
+				values[i] = stringToValue(item)
+			}
+			return values
+		}
+	}
+	strs := make([]string, len(list))
+	for i, str := range list {
+		strs[i] = strings.TrimPrefix(str, "@")
+	}
+	return strs
+}
+
+func stringToValue(str string) interface{} {
+	if !strings.HasPrefix(str, "@") {
+		return str
+	}
+	switch {
+	case strings.HasPrefix(str, "@@"):
+		return str[1:]
+	case str == invalidValue:
+		return nil
+	case !strings.HasSuffix(str, ")"):
+		return str
+	case strings.HasPrefix(str, byteArrayPrefix):
+		if data, ok := latin1(str[len(byteArrayPrefix) : len(str)-1]); ok {
+			return data
+		}
+	case strings.HasPrefix(str, stringPrefix):
+		return str[len(stringPrefix) : len(str)-1]
+	case strings.HasPrefix(str, variantPrefix):
+		return decodeVariant(variantPrefix, str, VariantVersion)
+	case strings.HasPrefix(str, dateTimePrefix):
+		return decodeVariant(dateTimePrefix, str, DateTimeVersion)
+	case strings.HasPrefix(str, rectPrefix):
+		if args, ok := parseArgs(str[len(rectPrefix):len(str)-1], 4); ok {
+			return cutestream.QRect{X: args[0], Y: args[1], Width: args[2], Height: args[3]}
+		}
+	case strings.HasPrefix(str, sizePrefix):
+		if args, ok := parseArgs(str[len(sizePrefix):len(str)-1], 2); ok {
+			return cutestream.QSize{Width: args[0], Height: args[1]}
+		}
+	case strings.HasPrefix(str, pointPrefix):
+		if args, ok := parseArgs(str[len(pointPrefix):len(str)-1], 2); ok {
+			return cutestream.QPoint{X: args[0], Y: args[1]}
+		}
+	}
+	return str
+}
+
+func latin1(str string) ([]byte, bool) {
+	data := make([]byte, 0, len(str))
+	for _, ch := range str {
+		if ch > 0xFF {
+			return nil, false
+		}
+		data = append(data, byte(ch))
+	}
+	return data, true
+}
+
+func parseArgs(str string, count int) ([]int32, bool) {
+	fields := strings.Fields(str)
+	if len(fields) != count {
+		return nil, false
+	}
+	args := make([]int32, count)
+	for i, f := range fields {
+		v, err := strconv.ParseInt(f, 10, 32)
+		if err != nil {
+			return nil, false
+		}
+		args[i] = int32(v)
+	}
+	return args, true
+}
+
+func decodeVariant(prefix string, str string, version int) interface{} {
+	data, ok := latin1(str[len(prefix) : len(str)-1])
+	if !ok {
+		return str
+	}
+	r, err := cutestream.NewReaderWithVersion(bytes.NewReader(data), version)
+	if err != nil {
+		return RawVariant{Prefix: prefix, Data: data, Err: err}
+	}
+	t, v, err := r.ReadQVariant()
+	if err != nil {
+		return RawVariant{Prefix: prefix, Data: data, Err: err}
+	}
+	return cutestream.Variant{Type: t, Value: v}
+}
+
+func (s *Settings) formatValue(v interface{}) (string, error) {
+	switch value := v.(type) {
+	case []string:
+		strs := make([]string, len(value))
+		for i, str := range value {
+			strs[i] = formatString(str)
+		}
+		return escapeValueList(strs), nil
+	case []interface{}:
+		if len(value) == 1 {
+			// a single item list can't be distinguished from a single value
+			break
+		}
+		strs := make([]string, len(value))
+		for i, item := range value {
+			str, err := s.valueToString(item)
+			if err != nil {
+				return "", err
+			}
+			strs[i] = str
+		}
+		return escapeValueList(strs), nil
+	}
+	str, err := s.valueToString(v)
+	if err != nil {
+		return "", err
+	}
+	return escapeValue(str), nil
+}
+
+func formatString(str string) string {
+	if strings.HasPrefix(str, "@") {
+		return "@" + str
+	}
+	return str
+}
+
+func (s *Settings) valueToString(v interface{}) (string, error) {
+	switch value := v.(type) {
+	case nil:
+		return invalidValue, nil
+	case string:
+		if strings.ContainsRune(value, 0) {
+			return stringPrefix + value + ")", nil
+		}
+		return formatString(value), nil
+	case []byte:
+		return byteArrayPrefix + string(toLatin1Runes(value)) + ")", nil
+	case bool:
+		return strconv.FormatBool(value), nil
+	case int:
+		return strconv.Itoa(value), nil
+	case int32:
+		return strconv.FormatInt(int64(value), 10), nil
+	case int64:
+		return strconv.FormatInt(value, 10), nil
+	case uint32:
+		return strconv.FormatUint(uint64(value), 10), nil
+	case uint64:
+		return strconv.FormatUint(value, 10), nil
+	case float64:
+		return strconv.FormatFloat(value, 'g', -1, 64), nil
+	case cutestream.QRect:
+		return fmt.Sprintf("%s%d %d %d %d)", rectPrefix, value.X, value.Y, value.Width, value.Height), nil
+	case cutestream.QSize:
+		return fmt.Sprintf("%s%d %d)", sizePrefix, value.Width, value.Height), nil
+	case cutestream.QPoint:
+		return fmt.Sprintf("%s%d %d)", pointPrefix, value.X, value.Y), nil
+	case RawVariant:
+		return value.Prefix + string(toLatin1Runes(value.Data)) + ")", nil
+	case cutestream.Variant:
+		if value.Value != nil && isPlainType(value.Type) {
+			return s.valueToString(value.Value)
+		}
+		return s.encodeVariant(value.Type, value.Value)
+	}
+	t, err := cutestream.MetaTypeOf(v)
+	if err != nil {
+		return "", err
+	}
+	return s.encodeVariant(t, v)
+}
+
+// isPlainType tells whether QSettings writes the metatype without @Variant
+func isPlainType(t cutestream.QMetaType) bool {
+	switch t {
+	case cutestream.QMetaTypeQString, cutestream.QMetaTypeQByteArray, cutestream.QMetaTypeBool,
+		cutestream.QMetaTypeInt, cutestream.QMetaTypeUInt, cutestream.QMetaTypeLongLong,
+		cutestream.QMetaTypeULongLong, cutestream.QMetaTypeDouble,
+		cutestream.QMetaTypeQRect, cutestream.QMetaTypeQSize, cutestream.QMetaTypeQPoint:
+		return true
+	}
+	return false
+}
+
+func (s *Settings) encodeVariant(t cutestream.QMetaType, v interface{}) (string, error) {
+	prefix, version := variantPrefix, VariantVersion
+	if t == cutestream.QMetaTypeQDateTime && !s.LegacyDateTime {
+		prefix, version = dateTimePrefix, DateTimeVersion
+	}
+	var buf bytes.Buffer
+	w, err := cutestream.NewWriterWithVersion(&buf, version)
+	if err != nil {
+		return "", err
+	}
+	if err := w.WriteQVariant(t, v); err != nil {
+		return "", err
+	}
+	return prefix + string(toLatin1Runes(buf.Bytes())) + ")", nil
+}
+
+func toLatin1Runes(data []byte) []rune {
+	runes := make([]rune, len(data))
+	for i, b := range data {
+		runes[i] = rune(b)
+	}
+	return runes
+}
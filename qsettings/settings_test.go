@@ -0,0 +1,139 @@
+package qsettings
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/race-engineering-center/cutestream"
+	"github.com/stretchr/testify/assert"
+)
+
+const sampleIni = `[General]
+driver=Jane Doe
+geometry=@ByteArray(\x1\xd9\xd0\xcb\0\x3)
+tags=fast, "wet, cold",  slick
+window=@Rect(10 20 640 480)
+size=@Size(800 600)
+pos=@Point(-5 7)
+empty=@Invalid()
+at=@@home
+options=@Variant(\0\0\0\b\0\0\0\x1\0\0\0\x2\0\x61\0\0\0\x2\0\0\0\x1)
+
+[car]
+setup\front\wing=3 ; trailing comment
+%43amber=" quoted value "
+unicode=Nürburgring
+`
+
+func TestParse(t *testing.T) {
+	s, err := Parse(strings.NewReader(sampleIni))
+	assert.Nil(t, err)
+	assert.Equal(t, []string{
+		"driver", "geometry", "tags", "window", "size", "pos", "empty", "at", "options",
+		"car/setup/front/wing", "car/Camber", "car/unicode",
+	}, s.Keys())
+
+	check := func(key string, expected interface{}) {
+		v, ok := s.Value(key)
+		assert.True(t, ok, key)
+		assert.Equal(t, expected, v, key)
+	}
+	check("driver", "Jane Doe")
+	check("geometry", []byte{0x01, 0xd9, 0xd0, 0xcb, 0x00, 0x03})
+	check("tags", []string{"fast", "wet, cold", "slick"})
+	check("window", cutestream.QRect{X: 10, Y: 20, Width: 640, Height: 480})
+	check("size", cutestream.QSize{Width: 800, Height: 600})
+	check("pos", cutestream.QPoint{X: -5, Y: 7})
+	check("empty", nil)
+	check("at", "@home")
+	check("options", cutestream.Variant{
+		Type:  cutestream.QMetaTypeQVariantMap,
+		Value: map[string]interface{}{"a": int32(1)},
+	})
+	check("car/setup/front/wing", "3")
+	check("car/Camber", " quoted value ")
+	check("car/unicode", "Nürburgring")
+}
+
+func TestRoundTrip(t *testing.T) {
+	s, err := Parse(strings.NewReader(sampleIni))
+	assert.Nil(t, err)
+	var buf bytes.Buffer
+	_, err = s.WriteTo(&buf)
+	assert.Nil(t, err)
+	written := buf.String()
+	assert.Contains(t, written, `geometry=@ByteArray(\x1\xd9\xd0\xcb\0\x3)`)
+	assert.Contains(t, written, `options=@Variant(\0\0\0\b\0\0\0\x1\0\0\0\x2\0\x61\0\0\0\x2\0\0\0\x1)`)
+	assert.Contains(t, written, `tags=fast, "wet, cold", slick`)
+	assert.Contains(t, written, "[car]\nsetup\\front\\wing=3\nCamber=\" quoted value \"\n")
+
+	reparsed, err := Parse(&buf)
+	assert.Nil(t, err)
+	assert.Equal(t, s.Keys(), reparsed.Keys())
+	for _, key := range s.Keys() {
+		expected, _ := s.Value(key)
+		actual, _ := reparsed.Value(key)
+		assert.Equal(t, expected, actual, key)
+	}
+}
+
+func TestSetValue(t *testing.T) {
+	s := New()
+	s.SetValue("laps", 42)
+	s.SetValue("General/x", true)
+	s.SetValue("started", time.Date(2023, 5, 21, 14, 0, 0, 0, time.UTC))
+	s.SetValue("list", []interface{}{int32(1), "two"})
+	s.SetValue("mixed", []interface{}{float32(0.5), "@two"})
+	s.SetValue("nul", "a\x00b")
+	s.SetValue("removed", "x")
+	s.Remove("removed")
+
+	var buf bytes.Buffer
+	_, err := s.WriteTo(&buf)
+	assert.Nil(t, err)
+	assert.True(t, strings.HasPrefix(buf.String(), "[General]\nlaps=42\nstarted=@DateTime("))
+	assert.Contains(t, buf.String(), "[%General]\nx=true\n")
+	assert.NotContains(t, buf.String(), "removed")
+
+	reparsed, err := Parse(&buf)
+	assert.Nil(t, err)
+	v, _ := reparsed.Value("General/x")
+	assert.Equal(t, "true", v)
+	v, _ = reparsed.Value("list")
+	assert.Equal(t, []string{"1", "two"}, v)
+	v, _ = reparsed.Value("mixed")
+	assert.Equal(t, []interface{}{
+		cutestream.Variant{Type: cutestream.QMetaTypeFloat, Value: float32(0.5)},
+		"@two",
+	}, v)
+	v, _ = reparsed.Value("nul")
+	assert.Equal(t, "a\x00b", v)
+	typ, started, ok := reparsed.Variant("started")
+	assert.True(t, ok)
+	assert.Equal(t, cutestream.QMetaTypeQDateTime, typ)
+	assert.True(t, time.Date(2023, 5, 21, 14, 0, 0, 0, time.UTC).Equal(started.(time.Time)))
+
+	s.LegacyDateTime = true
+	buf.Reset()
+	_, err = s.WriteTo(&buf)
+	assert.Nil(t, err)
+	assert.Contains(t, buf.String(), "started=@Variant(\\0\\0\\0\\x10")
+}
+
+func TestUndecodableVariant(t *testing.T) {
+	// QModelIndex can't be decoded and is kept as raw data
+	const ini = "[General]\ncolor=@Variant(\\0\\0\\0*\\x1\\xff\\xff\\0\\0\\0\\0\\0\\0\\0\\0)\n"
+	s, err := Parse(strings.NewReader(ini))
+	assert.Nil(t, err)
+	v, _ := s.Value("color")
+	raw, ok := v.(RawVariant)
+	assert.True(t, ok)
+	assert.NotNil(t, raw.Err)
+
+	var buf bytes.Buffer
+	_, err = s.WriteTo(&buf)
+	assert.Nil(t, err)
+	assert.Equal(t, ini, buf.String())
+}
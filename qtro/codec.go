@@ -0,0 +1,153 @@
+package qtro
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+
+	"github.com/race-engineering-center/cutestream"
+)
+
+// Reader reads QtRO packets from a connection. Each packet is a quint32 size
+// followed by a quint16 packet type and the packet payload
+type Reader struct {
+	frames *cutestream.FrameReader
+}
+
+// NewReader creates a packet reader using the specified stream version,
+// StreamVersionQt5 or StreamVersionQt6
+func NewReader(r io.Reader, version int) (*Reader, error) {
+	frames := cutestream.NewFrameReader(r)
+	frames.DoublePrecision = true // QtRO keeps the QDataStream default
+	if err := frames.SetVersion(version); err != nil {
+		return nil, err
+	}
+	return &Reader{frames: frames}, nil
+}
+
+// Frames returns the underlying frame reader, e.g. to configure timeouts
+// or the maximum packet size
+func (r *Reader) Frames() *cutestream.FrameReader {
+	return r.frames
+}
+
+// ReadPacket reads the next packet
+func (r *Reader) ReadPacket() (Packet, error) {
+	stream, err := r.frames.Next()
+	if err != nil {
+		return nil, err
+	}
+	return decodePacket(&stream)
+}
+
+// Writer writes QtRO packets to a connection
+type Writer struct {
+	frames *cutestream.FrameWriter
+}
+
+// NewWriter creates a packet writer using the specified stream version,
+// StreamVersionQt5 or StreamVersionQt6
+func NewWriter(w io.Writer, version int) (*Writer, error) {
+	frames := cutestream.NewFrameWriter(w)
+	frames.DoublePrecision = true // QtRO keeps the QDataStream default
+	if err := frames.SetVersion(version); err != nil {
+		return nil, err
+	}
+	return &Writer{frames: frames}, nil
+}
+
+// Frames returns the underlying frame writer, e.g. to configure timeouts
+// or the maximum packet size
+func (w *Writer) Frames() *cutestream.FrameWriter {
+	return w.frames
+}
+
+// WritePacket writes a packet as a single frame
+func (w *Writer) WritePacket(p Packet) error {
+	return w.frames.WriteFrame(func(stream *cutestream.Writer) error {
+		return encodePacket(stream, p)
+	})
+}
+
+// Decode decodes a packet from its frame payload, i.e. the data following the packet size
+func Decode(data []byte, version int) (Packet, error) {
+	r, err := cutestream.NewReaderWithVersion(bytes.NewReader(data), version)
+	if err != nil {
+		return nil, err
+	}
+	r.DoublePrecision = true
+	return decodePacket(&r)
+}
+
+// Encode encodes a packet into its frame payload, i.e. the data following the packet size
+func Encode(p Packet, version int) ([]byte, error) {
+	var buf bytes.Buffer
+	w, err := cutestream.NewWriterWithVersion(&buf, version)
+	if err != nil {
+		return nil, err
+	}
+	w.DoublePrecision = true
+	if err := encodePacket(&w, p); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func decodePacket(r *cutestream.Reader) (Packet, error) {
+	t, err := r.ReadUint16()
+	if err != nil {
+		return nil, err
+	}
+	p, err := newPacket(PacketType(t))
+	if err != nil {
+		return nil, err
+	}
+	if err := p.decode(r); err != nil {
+		return nil, fmt.Errorf("can't decode packet type %d: %w", t, err)
+	}
+	return p, nil
+}
+
+func encodePacket(w *cutestream.Writer, p Packet) error {
+	if err := w.WriteUint16(uint16(p.Type())); err != nil {
+		return err
+	}
+	return p.encode(w)
+}
+
+func readVariant(r *cutestream.Reader) (cutestream.Variant, error) {
+	t, v, err := r.ReadQVariant()
+	return cutestream.Variant{Type: t, Value: v}, err
+}
+
+func readVariantList(r *cutestream.Reader) ([]cutestream.Variant, error) {
+	n, err := r.ReadUint32()
+	if err != nil {
+		return nil, err
+	}
+	var values []cutestream.Variant
+	for i := uint32(0); i < n; i++ {
+		v, err := readVariant(r)
+		if err != nil {
+			return nil, err
+		}
+		values = append(values, v)
+	}
+	return values, nil
+}
+
+func writeVariantList(w *cutestream.Writer, values []cutestream.Variant) error {
+	if err := w.WriteUint32(uint32(len(values))); err != nil {
+		return err
+	}
+	for _, v := range values {
+		if err := w.WriteQVariant(v.Type, v.Value); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func readRest(r *cutestream.Reader) ([]byte, error) {
+	return io.ReadAll(r.Reader)
+}
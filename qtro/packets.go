@@ -0,0 +1,383 @@
+// Package qtro implements the wire format of Qt Remote Objects (QtRO):
+// the packets exchanged between sources and replicas over QIODevice-based
+// connections, such as QTcpSocket or QLocalSocket.
+package qtro
+
+import (
+	"fmt"
+
+	"github.com/race-engineering-center/cutestream"
+)
+
+// PacketType identifies a QtRO packet.
+type PacketType uint16
+
+// From qtremoteobjects/src/remoteobjects/qremoteobjectpackets_p.h.
+const (
+	PacketTypeInvalid           PacketType = 0
+	PacketTypeHandshake         PacketType = 1
+	PacketTypeInitPacket        PacketType = 2
+	PacketTypeInitDynamicPacket PacketType = 3
+	PacketTypeAddObject         PacketType = 4
+	PacketTypeRemoveObject      PacketType = 5
+	PacketTypeInvokePacket      PacketType = 6
+	PacketTypeInvokeReplyPacket PacketType = 7
+	PacketTypePropertyChange    PacketType = 8
+	PacketTypeObjectList        PacketType = 9
+	PacketTypePing              PacketType = 10
+	PacketTypePong              PacketType = 11
+)
+
+// Protocol versions sent in the handshake packet
+const (
+	ProtocolVersionQt5 = "QtRO 1.3"
+	ProtocolVersionQt6 = "QtRO 2.0"
+)
+
+// QDataStream versions used for the packet payloads
+const (
+	StreamVersionQt5 = cutestream.VersionQt5_12
+	StreamVersionQt6 = cutestream.VersionQt6_0
+)
+
+// InvokeCall is the kind of call made by an InvokePacket, from QMetaObject::Call.
+// Only the values common to Qt 5 and Qt 6 are listed, QtRO uses just
+// InvokeMetaMethod and WriteProperty
+type InvokeCall int32
+
+const (
+	InvokeMetaMethod InvokeCall = 0
+	ReadProperty     InvokeCall = 1
+	WriteProperty    InvokeCall = 2
+	ResetProperty    InvokeCall = 3
+)
+
+// Packet is a single QtRO packet
+type Packet interface {
+	// Type returns the packet type written in the packet header
+	Type() PacketType
+	decode(r *cutestream.Reader) error
+	encode(w *cutestream.Writer) error
+}
+
+// HandshakePacket is the first packet sent by both peers
+type HandshakePacket struct {
+	ProtocolVersion string
+}
+
+// ObjectInfo describes a source object announced in an ObjectListPacket
+type ObjectInfo struct {
+	Name      string
+	TypeName  string
+	Signature []byte
+}
+
+// ObjectListPacket announces the source objects available on the node
+type ObjectListPacket struct {
+	Objects []ObjectInfo
+}
+
+// AddObjectPacket requests a replica of the named source
+type AddObjectPacket struct {
+	Name      string
+	IsDynamic bool
+}
+
+// RemoveObjectPacket tells that the named source or replica went away
+type RemoveObjectPacket struct {
+	Name string
+}
+
+// InitPacket carries the initial values of all properties of a source
+type InitPacket struct {
+	Name       string
+	Properties []cutestream.Variant
+}
+
+// InitDynamicPacket carries the class definition and the property values
+// of a source for a dynamic replica. The definition layout depends on the
+// Qt version, so everything after the name is kept as raw bytes
+type InitDynamicPacket struct {
+	Name string
+	Data []byte
+}
+
+// InvokePacket invokes a method or writes a property of a source
+type InvokePacket struct {
+	Name          string
+	Call          InvokeCall
+	Index         int32
+	Args          []cutestream.Variant
+	SerialID      int32
+	PropertyIndex int32
+}
+
+// InvokeReplyPacket returns the result of an InvokePacket with a non-negative serial id
+type InvokeReplyPacket struct {
+	Name     string
+	SerialID int32
+	Value    cutestream.Variant
+}
+
+// PropertyChangePacket notifies about a changed property value
+type PropertyChangePacket struct {
+	Name  string
+	Index int32
+	Value cutestream.Variant
+}
+
+// PingPacket is sent to check that the connection is alive
+type PingPacket struct {
+	Name string
+}
+
+// PongPacket is the answer to a PingPacket
+type PongPacket struct {
+	Name string
+}
+
+func (*HandshakePacket) Type() PacketType      { return PacketTypeHandshake }
+func (*ObjectListPacket) Type() PacketType     { return PacketTypeObjectList }
+func (*AddObjectPacket) Type() PacketType      { return PacketTypeAddObject }
+func (*RemoveObjectPacket) Type() PacketType   { return PacketTypeRemoveObject }
+func (*InitPacket) Type() PacketType           { return PacketTypeInitPacket }
+func (*InitDynamicPacket) Type() PacketType    { return PacketTypeInitDynamicPacket }
+func (*InvokePacket) Type() PacketType         { return PacketTypeInvokePacket }
+func (*InvokeReplyPacket) Type() PacketType    { return PacketTypeInvokeReplyPacket }
+func (*PropertyChangePacket) Type() PacketType { return PacketTypePropertyChange }
+func (*PingPacket) Type() PacketType           { return PacketTypePing }
+func (*PongPacket) Type() PacketType           { return PacketTypePong }
+
+func newPacket(t PacketType) (Packet, error) {
+	switch t {
+	case PacketTypeHandshake:
+		return &HandshakePacket{}, nil
+	case PacketTypeObjectList:
+		return &ObjectListPacket{}, nil
+	case PacketTypeAddObject:
+		return &AddObjectPacket{}, nil
+	case PacketTypeRemoveObject:
+		return &RemoveObjectPacket{}, nil
+	case PacketTypeInitPacket:
+		return &InitPacket{}, nil
+	case PacketTypeInitDynamicPacket:
+		return &InitDynamicPacket{}, nil
+	case PacketTypeInvokePacket:
+		return &InvokePacket{}, nil
+	case PacketTypeInvokeReplyPacket:
+		return &InvokeReplyPacket{}, nil
+	case PacketTypePropertyChange:
+		return &PropertyChangePacket{}, nil
+	case PacketTypePing:
+		return &PingPacket{}, nil
+	case PacketTypePong:
+		return &PongPacket{}, nil
+	default:
+		return nil, fmt.Errorf("unknown packet type %d", t)
+	}
+}
+
+func (p *HandshakePacket) decode(r *cutestream.Reader) (err error) {
+	p.ProtocolVersion, err = r.ReadQString()
+	return err
+}
+
+func (p *HandshakePacket) encode(w *cutestream.Writer) error {
+	return w.WriteQString(p.ProtocolVersion)
+}
+
+func (p *ObjectListPacket) decode(r *cutestream.Reader) error {
+	n, err := r.ReadUint32()
+	if err != nil {
+		return err
+	}
+	p.Objects = nil
+	for i := uint32(0); i < n; i++ {
+		var info ObjectInfo
+		if info.Name, err = r.ReadQString(); err != nil {
+			return err
+		}
+		if info.TypeName, err = r.ReadQString(); err != nil {
+			return err
+		}
+		if info.Signature, err = r.ReadQByteArray(); err != nil {
+			return err
+		}
+		p.Objects = append(p.Objects, info)
+	}
+	return nil
+}
+
+func (p *ObjectListPacket) encode(w *cutestream.Writer) error {
+	if err := w.WriteUint32(uint32(len(p.Objects))); err != nil {
+		return err
+	}
+	for _, info := range p.Objects {
+		if err := w.WriteQString(info.Name); err != nil {
+			return err
+		}
+		if err := w.WriteQString(info.TypeName); err != nil {
+			return err
+		}
+		if err := w.WriteQByteArray(info.Signature); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (p *AddObjectPacket) decode(r *cutestream.Reader) (err error) {
+	if p.Name, err = r.ReadQString(); err != nil {
+		return err
+	}
+	p.IsDynamic, err = r.ReadBool()
+	return err
+}
+
+func (p *AddObjectPacket) encode(w *cutestream.Writer) error {
+	if err := w.WriteQString(p.Name); err != nil {
+		return err
+	}
+	return w.WriteBool(p.IsDynamic)
+}
+
+func (p *RemoveObjectPacket) decode(r *cutestream.Reader) (err error) {
+	p.Name, err = r.ReadQString()
+	return err
+}
+
+func (p *RemoveObjectPacket) encode(w *cutestream.Writer) error {
+	return w.WriteQString(p.Name)
+}
+
+func (p *InitPacket) decode(r *cutestream.Reader) (err error) {
+	if p.Name, err = r.ReadQString(); err != nil {
+		return err
+	}
+	p.Properties, err = readVariantList(r)
+	return err
+}
+
+func (p *InitPacket) encode(w *cutestream.Writer) error {
+	if err := w.WriteQString(p.Name); err != nil {
+		return err
+	}
+	return writeVariantList(w, p.Properties)
+}
+
+func (p *InitDynamicPacket) decode(r *cutestream.Reader) (err error) {
+	if p.Name, err = r.ReadQString(); err != nil {
+		return err
+	}
+	p.Data, err = readRest(r)
+	return err
+}
+
+func (p *InitDynamicPacket) encode(w *cutestream.Writer) error {
+	if err := w.WriteQString(p.Name); err != nil {
+		return err
+	}
+	_, err := w.Writer.Write(p.Data)
+	return err
+}
+
+func (p *InvokePacket) decode(r *cutestream.Reader) (err error) {
+	if p.Name, err = r.ReadQString(); err != nil {
+		return err
+	}
+	call, err := r.ReadInt32()
+	if err != nil {
+		return err
+	}
+	p.Call = InvokeCall(call)
+	if p.Index, err = r.ReadInt32(); err != nil {
+		return err
+	}
+	if p.Args, err = readVariantList(r); err != nil {
+		return err
+	}
+	if p.SerialID, err = r.ReadInt32(); err != nil {
+		return err
+	}
+	p.PropertyIndex, err = r.ReadInt32()
+	return err
+}
+
+func (p *InvokePacket) encode(w *cutestream.Writer) error {
+	if err := w.WriteQString(p.Name); err != nil {
+		return err
+	}
+	if err := w.WriteInt32(int32(p.Call)); err != nil {
+		return err
+	}
+	if err := w.WriteInt32(p.Index); err != nil {
+		return err
+	}
+	if err := writeVariantList(w, p.Args); err != nil {
+		return err
+	}
+	if err := w.WriteInt32(p.SerialID); err != nil {
+		return err
+	}
+	return w.WriteInt32(p.PropertyIndex)
+}
+
+func (p *InvokeReplyPacket) decode(r *cutestream.Reader) (err error) {
+	if p.Name, err = r.ReadQString(); err != nil {
+		return err
+	}
+	if p.SerialID, err = r.ReadInt32(); err != nil {
+		return err
+	}
+	p.Value, err = readVariant(r)
+	return err
+}
+
+func (p *InvokeReplyPacket) encode(w *cutestream.Writer) error {
+	if err := w.WriteQString(p.Name); err != nil {
+		return err
+	}
+	if err := w.WriteInt32(p.SerialID); err != nil {
+		return err
+	}
+	return w.WriteQVariant(p.Value.Type, p.Value.Value)
+}
+
+func (p *PropertyChangePacket) decode(r *cutestream.Reader) (err error) {
+	if p.Name, err = r.ReadQString(); err != nil {
+		return err
+	}
+	if p.Index, err = r.ReadInt32(); err != nil {
+		return err
+	}
+	p.Value, err = readVariant(r)
+	return err
+}
+
+func (p *PropertyChangePacket) encode(w *cutestream.Writer) error {
+	if err := w.WriteQString(p.Name); err != nil {
+		return err
+	}
+	if err := w.WriteInt32(p.Index); err != nil {
+		return err
+	}
+	return w.WriteQVariant(p.Value.Type, p.Value.Value)
+}
+
+func (p *PingPacket) decode(r *cutestream.Reader) (err error) {
+	p.Name, err = r.ReadQString()
+	return err
+}
+
+func (p *PingPacket) encode(w *cutestream.Writer) error {
+	return w.WriteQString(p.Name)
+}
+
+func (p *PongPacket) decode(r *cutestream.Reader) (err error) {
+	p.Name, err = r.ReadQString()
+	return err
+}
+
+func (p *PongPacket) encode(w *cutestream.Writer) error {
+	return w.WriteQString(p.Name)
+}
@@ -0,0 +1,88 @@
+package qtro
+
+import (
+	"bytes"
+	"io"
+	"testing"
+
+	"github.com/race-engineering-center/cutestream"
+	"github.com/stretchr/testify/assert"
+)
+
+// PropertyChangePacket for property 2 of source "Car" set to double 1.5,
+// as sent by a Qt 5.15 source
+var propertyChangeCapture = []byte{
+	0x00, 0x00, 0x00, 0x1d, // size
+	0x00, 0x08, // PropertyChangePacket
+	0x00, 0x00, 0x00, 0x06, 0x00, 'C', 0x00, 'a', 0x00, 'r', // name
+	0x00, 0x00, 0x00, 0x02, // index
+	0x00, 0x00, 0x00, 0x06, 0x00, // QVariant(double)
+	0x3f, 0xf8, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00,
+}
+
+func TestReadPropertyChangeCapture(t *testing.T) {
+	r, err := NewReader(bytes.NewReader(propertyChangeCapture), StreamVersionQt5)
+	assert.Nil(t, err)
+	p, err := r.ReadPacket()
+	assert.Nil(t, err)
+	assert.Equal(t, &PropertyChangePacket{
+		Name:  "Car",
+		Index: 2,
+		Value: cutestream.Variant{Type: cutestream.QMetaTypeDouble, Value: 1.5},
+	}, p)
+	_, err = r.ReadPacket()
+	assert.Equal(t, io.EOF, err)
+}
+
+func TestWritePropertyChangeCapture(t *testing.T) {
+	var buf bytes.Buffer
+	w, err := NewWriter(&buf, StreamVersionQt5)
+	assert.Nil(t, err)
+	err = w.WritePacket(&PropertyChangePacket{
+		Name:  "Car",
+		Index: 2,
+		Value: cutestream.Variant{Type: cutestream.QMetaTypeDouble, Value: 1.5},
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, propertyChangeCapture, buf.Bytes())
+}
+
+func TestPacketRoundTrip(t *testing.T) {
+	packets := []Packet{
+		&HandshakePacket{ProtocolVersion: ProtocolVersionQt6},
+		&ObjectListPacket{Objects: []ObjectInfo{{Name: "Car", TypeName: "CarTelemetry", Signature: []byte("c0ffee")}}},
+		&AddObjectPacket{Name: "Car", IsDynamic: true},
+		&RemoveObjectPacket{Name: "Car"},
+		&InitPacket{Name: "Car", Properties: []cutestream.Variant{
+			{Type: cutestream.QMetaTypeInt, Value: int32(3)},
+			{Type: cutestream.QMetaTypeQString, Value: "Monza"},
+		}},
+		&InitDynamicPacket{Name: "Car", Data: []byte{1, 2, 3, 4}},
+		&InvokePacket{Name: "Car", Call: InvokeMetaMethod, Index: 5, Args: []cutestream.Variant{
+			{Type: cutestream.QMetaTypeBool, Value: true},
+		}, SerialID: 7, PropertyIndex: -1},
+		&InvokeReplyPacket{Name: "Car", SerialID: 7, Value: cutestream.Variant{Type: cutestream.QMetaTypeULongLong, Value: uint64(9)}},
+		&PingPacket{Name: "Car"},
+		&PongPacket{Name: "Car"},
+	}
+	for _, version := range []int{StreamVersionQt5, StreamVersionQt6} {
+		var buf bytes.Buffer
+		w, err := NewWriter(&buf, version)
+		assert.Nil(t, err)
+		for _, p := range packets {
+			assert.Nil(t, w.WritePacket(p))
+		}
+		r, err := NewReader(&buf, version)
+		assert.Nil(t, err)
+		for _, p := range packets {
+			read, err := r.ReadPacket()
+			assert.Nil(t, err)
+			assert.Equal(t, p, read)
+		}
+	}
+}
+
+func TestDecodeUnknownPacket(t *testing.T) {
+	_, err := Decode([]byte{0x00, 0x42}, StreamVersionQt6)
+	assert.NotNil(t, err)
+}
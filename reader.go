@@ -1,11 +1,14 @@
 package cutestream
 
 import (
+	"bytes"
+	"context"
 	"encoding/binary"
-	"encoding/hex"
+	"errors"
 	"fmt"
 	"io"
 	"net/url"
+	"strings"
 	"time"
 	"unicode/utf16"
 )
@@ -15,6 +18,7 @@ type QMetaType int
 
 // From qtbase/corelib/kernel/qmetatype.h.
 const (
+	QMetaTypeUnknown            QMetaType = 0
 	QMetaTypeBool               QMetaType = 1
 	QMetaTypeInt                QMetaType = 2
 	QMetaTypeUInt               QMetaType = 3
@@ -63,6 +67,14 @@ const (
 	QMetaTypeQJsonObject        QMetaType = 46
 	QMetaTypeQJsonArray         QMetaType = 47
 	QMetaTypeQJsonDocument      QMetaType = 48
+	QMetaTypeQByteArrayList     QMetaType = 49
+	QMetaTypeNullptr            QMetaType = 51
+	QMetaTypeQCborSimpleType    QMetaType = 52
+	QMetaTypeQCborValue         QMetaType = 53
+	QMetaTypeQCborArray         QMetaType = 54
+	QMetaTypeQCborMap           QMetaType = 55
+	QMetaTypeChar16             QMetaType = 56
+	QMetaTypeChar32             QMetaType = 57
 	QMetaTypeQFont              QMetaType = 64
 	QMetaTypeQPixmap            QMetaType = 65
 	QMetaTypeQBrush             QMetaType = 66
@@ -87,33 +99,69 @@ const (
 	QMetaTypeQQuaternion        QMetaType = 85
 	QMetaTypeQPolygonF          QMetaType = 86
 	QMetaTypeQSizePolicy        QMetaType = 121
+	// QMetaTypeUser is the metatype of all types registered by applications,
+	// QVariant streams them along with their type name
+	QMetaTypeUser QMetaType = 65536
+)
+
+var (
+	// ErrAllocLimit is returned when a value needs more memory than Reader.MaxAllocSize allows
+	ErrAllocLimit = errors.New("allocation limit exceeded")
+	// ErrTooDeep is returned when QVariants are nested deeper than MaxNestingDepth
+	ErrTooDeep = errors.New("maximum nesting depth exceeded")
+)
+
+const (
+	// DefaultMaxAllocSize is the allocation limit set by NewReader and NewReaderWithVersion
+	DefaultMaxAllocSize = 1 << 30
+	// MaxNestingDepth is the maximum nesting of QVariants in containers
+	MaxNestingDepth = 512
+
+	// maxPrealloc and maxPreallocCount cap the buffers and containers preallocated
+	// from sizes found in the stream, larger data grows them as it is read
+	maxPrealloc      = 1 << 20
+	maxPreallocCount = 1 << 12
 )
 
 type Reader struct {
 	Reader          io.Reader
 	ByteOrder       binary.ByteOrder
 	version         int
-	DoublePrecision bool // Use Double precision for floats. Set to `false` to use Single precision
+	DoublePrecision bool              // Use Double precision for floats. Set to `false` to use Single precision
+	UnknownTypes    UnknownTypePolicy // How to handle values of unimplemented types, FailOnUnknownType by default
+	MaxAllocSize    uint64            // Largest buffer allocated for a single value, e.g. decompressed data. 0 means no limit
+	NullStrings     bool              // Read QString values as NullQString instead of string
+	OrderedMaps     bool              // Read QVariantMap and QVariantHash values as OrderedMap and OrderedHash instead of map[string]interface{}
+	Context         context.Context   // Checked at container and record boundaries to cancel reading. nil means no cancellation
+	depth           int               // Nesting of the QVariant being read
+	counter         *countingReader   // Counts the bytes consumed for progress
+	progress        func(consumed int64)
 }
 
 // NewReader creates a new Reader object with the specified underlying reader,
-// big endian byte order and enabled double precision
+// big endian byte order, disabled double precision and DefaultMaxAllocSize
 func NewReader(reader io.Reader) Reader {
 	return Reader{
 		Reader:          reader,
 		ByteOrder:       binary.BigEndian,
 		version:         19,
 		DoublePrecision: false,
+		MaxAllocSize:    DefaultMaxAllocSize,
 	}
 }
 
+// SetProgress wraps the underlying reader to count the bytes consumed from now on and
+// sets a function called with that number at container and record boundaries.
+// Call it after DetectByteOrder, which buffers the underlying reader ahead, and
+// after replacing the underlying reader
+func (r *Reader) SetProgress(progress func(consumed int64)) {
+	r.counter = &countingReader{reader: r.Reader}
+	r.Reader = r.counter
+	r.progress = progress
+}
+
 func NewReaderWithVersion(reader io.Reader, version int) (Reader, error) {
-	r := Reader{
-		Reader:          reader,
-		ByteOrder:       binary.BigEndian,
-		version:         19,
-		DoublePrecision: false,
-	}
+	r := NewReader(reader)
 	err := r.SetVersion(version)
 	if err != nil {
 		return Reader{}, err
@@ -122,21 +170,130 @@ func NewReaderWithVersion(reader io.Reader, version int) (Reader, error) {
 }
 
 func (r *Reader) SetVersion(version int) error {
-	acceptedVersions := []int{19, 20, 21, 22}
-	found := false
-	for _, v := range acceptedVersions {
-		if v == version {
-			found = true
-			break
-		}
-	}
-	if !found {
-		return fmt.Errorf("%d is not a supported version, %v", version, acceptedVersions)
+	if err := checkVersion(version); err != nil {
+		return err
 	}
 	r.version = version
 	return nil
 }
 
+// Version returns the QDataStream version used by the reader
+func (r *Reader) Version() int {
+	return r.version
+}
+
+// QDataStream versions, from QDataStream::Version.
+// Versions not listed here are equal to the previous listed one
+const (
+	VersionQt4_0  = 7
+	VersionQt4_2  = 8
+	VersionQt4_3  = 9
+	VersionQt4_4  = 10
+	VersionQt4_5  = 11
+	VersionQt4_6  = 12
+	VersionQt5_0  = 13
+	VersionQt5_1  = 14
+	VersionQt5_2  = 15
+	VersionQt5_4  = 16
+	VersionQt5_6  = 17
+	VersionQt5_12 = 18
+	VersionQt5_13 = 19
+	VersionQt6_0  = 20
+	VersionQt6_6  = 21
+	VersionQt6_7  = 22
+)
+
+func checkVersion(version int) error {
+	if version < VersionQt4_0 || version > VersionQt6_7 {
+		return fmt.Errorf("%d is not a supported version, [%d..%d]", version, VersionQt4_0, VersionQt6_7)
+	}
+	return nil
+}
+
+// Qt 4 used different ids for some of the metatypes, these are converted
+// by QVariant when streaming with versions before Qt 5.0.
+// QMetaType::User was 1024 in Qt 5, QVariant streams it instead of the Qt 6 value before Qt 6.0
+const (
+	qt4UserType           = 127
+	qt5UserType           = 1024
+	qt4FirstExtCoreType   = 128
+	qt4ExtCoreTypeOffset  = 97
+	qt4QSizePolicy        = 75
+	qt4LastGuiTypeShifted = 86
+)
+
+func metaTypeFromQt4(t uint32) QMetaType {
+	switch {
+	case t == qt4UserType:
+		return QMetaTypeUser
+	case t >= qt4FirstExtCoreType:
+		return QMetaType(t - qt4ExtCoreTypeOffset)
+	case t == qt4QSizePolicy:
+		return QMetaTypeQSizePolicy
+	case t > qt4QSizePolicy && t <= qt4LastGuiTypeShifted:
+		return QMetaType(t - 1)
+	}
+	return QMetaType(t)
+}
+
+func metaTypeToQt4(t QMetaType) uint32 {
+	switch {
+	case t == QMetaTypeUser:
+		return qt4UserType
+	case t >= qt4FirstExtCoreType-qt4ExtCoreTypeOffset && t <= QMetaTypeQJsonDocument:
+		return uint32(t + qt4ExtCoreTypeOffset)
+	case t == QMetaTypeQSizePolicy:
+		return qt4QSizePolicy
+	case t >= QMetaTypeQKeySequence && t <= QMetaTypeQQuaternion:
+		return uint32(t + 1)
+	}
+	return uint32(t)
+}
+
+func metaTypeFromQt5(t uint32) QMetaType {
+	if t == qt5UserType {
+		return QMetaTypeUser
+	}
+	return QMetaType(t)
+}
+
+func metaTypeToQt5(t QMetaType) uint32 {
+	if t == QMetaTypeUser {
+		return qt5UserType
+	}
+	return uint32(t)
+}
+
+// Qt 6 moved the GUI metatypes from 64 to 0x1000 and QSizePolicy from 121 to 0x2000,
+// QVariant streams the Qt 6 ids since Qt 6.0. The metatypes of this package have the Qt 5 ids
+const (
+	qt5FirstGuiType = 64 // QFont
+	qt5LastGuiType  = 87 // QColorSpace
+	qt6FirstGuiType = 0x1000
+	qt6LastGuiType  = qt6FirstGuiType + qt5LastGuiType - qt5FirstGuiType
+	qt6QSizePolicy  = 0x2000
+)
+
+func metaTypeFromQt6(t uint32) QMetaType {
+	switch {
+	case t >= qt6FirstGuiType && t <= qt6LastGuiType:
+		return QMetaType(t - qt6FirstGuiType + qt5FirstGuiType)
+	case t == qt6QSizePolicy:
+		return QMetaTypeQSizePolicy
+	}
+	return QMetaType(t)
+}
+
+func metaTypeToQt6(t QMetaType) uint32 {
+	switch {
+	case t >= qt5FirstGuiType && t <= qt5LastGuiType:
+		return uint32(t - qt5FirstGuiType + qt6FirstGuiType)
+	case t == QMetaTypeQSizePolicy:
+		return qt6QSizePolicy
+	}
+	return uint32(t)
+}
+
 func (r *Reader) ReadBool() (bool, error) {
 	var v uint8
 	if err := binary.Read(r.Reader, r.ByteOrder, &v); err != nil {
@@ -185,8 +342,9 @@ func (r *Reader) ReadUint64() (uint64, error) {
 	return ReadNumber[uint64](r)
 }
 
+// ReadFloat reads a float. Starting with Qt 4.6 the size depends on the precision
 func (r *Reader) ReadFloat() (float32, error) {
-	if r.DoublePrecision {
+	if r.DoublePrecision && r.version >= VersionQt4_6 {
 		val, err := ReadNumber[float64](r)
 		if err != nil {
 			return 0, err
@@ -196,8 +354,9 @@ func (r *Reader) ReadFloat() (float32, error) {
 	return ReadNumber[float32](r)
 }
 
+// ReadDouble reads a double. Starting with Qt 4.6 the size depends on the precision
 func (r *Reader) ReadDouble() (float64, error) {
-	if !r.DoublePrecision {
+	if !r.DoublePrecision && r.version >= VersionQt4_6 {
 		val, err := ReadNumber[float32](r)
 		if err != nil {
 			return 0, err
@@ -207,34 +366,118 @@ func (r *Reader) ReadDouble() (float64, error) {
 	return ReadNumber[float64](r)
 }
 
+// readBytes reads n bytes. Large buffers grow as the data arrives,
+// so that a corrupted length doesn't allocate more memory than the stream holds
+func (r *Reader) readBytes(n uint64) ([]byte, error) {
+	if err := r.checkAlloc(n); err != nil {
+		return nil, err
+	}
+	if n <= maxPrealloc {
+		buf := make([]byte, n)
+		if _, err := io.ReadFull(r.Reader, buf); err != nil {
+			return nil, err
+		}
+		return buf, nil
+	}
+	var buf bytes.Buffer
+	buf.Grow(maxPrealloc)
+	read, err := io.CopyN(&buf, r.Reader, int64(n))
+	if err == io.EOF && read > 0 {
+		err = io.ErrUnexpectedEOF
+	}
+	if err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// checkAlloc checks the size of a buffer to be allocated against MaxAllocSize
+func (r *Reader) checkAlloc(n uint64) error {
+	if r.MaxAllocSize > 0 && n > r.MaxAllocSize {
+		return fmt.Errorf("%w: %d bytes", ErrAllocLimit, n)
+	}
+	return nil
+}
+
+// checkpoint is called at container and record boundaries:
+// it reports progress and returns the error of the context if it is done
+func (r *Reader) checkpoint() error {
+	if r.progress != nil {
+		r.progress(r.counter.n)
+	}
+	if r.Context == nil {
+		return nil
+	}
+	select {
+	case <-r.Context.Done():
+		return r.Context.Err()
+	default:
+		return nil
+	}
+}
+
+// subReader returns a reader with the settings of the reader over data already consumed from it.
+// The bytes of data were counted when they were consumed, so the sub reader doesn't report progress
+func (r *Reader) subReader(data []byte) Reader {
+	sub := *r
+	sub.Reader = bytes.NewReader(data)
+	sub.counter = nil
+	sub.progress = nil
+	return sub
+}
+
+// preallocCount returns the capacity to preallocate for a container count read from the stream
+func preallocCount(n uint32) int {
+	if n > maxPreallocCount {
+		return maxPreallocCount
+	}
+	return int(n)
+}
+
 func (r *Reader) ReadCString() (string, error) {
 	n, err := r.ReadUint32()
 	if err != nil {
 		return "", err
 	}
-	buf := make([]byte, n)
-	if err := binary.Read(r.Reader, r.ByteOrder, &buf); err != nil {
+	buf, err := r.readBytes(uint64(n))
+	if err != nil {
 		return "", err
 	}
 	return string(buf), nil
 }
 
+// ReadQBitArray reads a bit array. The number of bits is a quint32 before Qt 6 and a quint64 in Qt 6 streams
 func (r *Reader) ReadQBitArray() ([]bool, error) {
-	n, err := r.ReadUint32()
-	if err != nil {
+	var n uint64
+	if r.version < VersionQt6_0 {
+		n32, err := r.ReadUint32()
+		if err != nil {
+			return nil, err
+		}
+		n = uint64(n32)
+	} else {
+		var err error
+		if n, err = r.ReadUint64(); err != nil {
+			return nil, err
+		}
+	}
+	// a bool per bit is allocated in addition to the packed bits
+	if err := r.checkAlloc(n); err != nil {
 		return nil, err
 	}
-	buf := make([]byte, (n+7)/8)
-	if err := binary.Read(r.Reader, r.ByteOrder, &buf); err != nil {
+	buf, err := r.readBytes(n/8 + (n%8+7)/8)
+	if err != nil {
 		return nil, err
 	}
 	bits := make([]bool, n)
-	for i := int64(n - 1); i >= 0; i-- {
-		bits[i] = (((buf[i/8]) >> (7 - i%8)) & 0x1) == 0x1
+	for i := range bits {
+		bits[i] = (buf[i/8]>>(i%8))&0x1 == 0x1
 	}
 	return bits, nil
 }
 
+// ReadQByteArray reads a byte array. A null byte array is returned as nil,
+// an empty one as an empty non-nil slice
 func (r *Reader) ReadQByteArray() ([]byte, error) {
 	n, err := r.ReadUint32()
 	if err != nil {
@@ -243,17 +486,23 @@ func (r *Reader) ReadQByteArray() ([]byte, error) {
 	if n == 0xFFFFFFFF {
 		return nil, nil
 	}
-	buf := make([]byte, n)
-	if err := binary.Read(r.Reader, r.ByteOrder, &buf); err != nil {
-		return nil, err
-	}
-	return buf, nil
+	return r.readBytes(uint64(n))
 }
 
 func (r *Reader) ReadQDate() (time.Time, error) {
-	julian, err := r.ReadUint64()
-	if err != nil {
-		return time.Time{}, err
+	var julian uint64
+	if r.version < VersionQt5_0 {
+		jd, err := r.ReadUint32()
+		if err != nil {
+			return time.Time{}, err
+		}
+		julian = uint64(jd)
+	} else {
+		jd, err := r.ReadUint64()
+		if err != nil {
+			return time.Time{}, err
+		}
+		julian = jd
 	}
 	// ported from qdatetime.cpp
 	floordiv := func(a, b int) int {
@@ -280,19 +529,31 @@ func (r *Reader) ReadQDate() (time.Time, error) {
 	return time.Date(year, time.Month(month), day, 0, 0, 0, 0, time.UTC), nil
 }
 
+// ReadQString reads a string. Null and empty strings are both returned as "",
+// use ReadNullQString to tell them apart
 func (r *Reader) ReadQString() (string, error) {
+	s, err := r.ReadNullQString()
+	return s.String, err
+}
+
+// ReadNullQString reads a string keeping the distinction between null and empty strings
+func (r *Reader) ReadNullQString() (NullQString, error) {
 	n, err := r.ReadUint32()
 	if err != nil {
-		return "", err
+		return NullQString{}, err
 	}
 	if n == 0xFFFFFFFF {
-		return "", nil
+		return NullQString{}, nil
 	}
-	buf := make([]uint16, n/2)
-	if err := binary.Read(r.Reader, r.ByteOrder, &buf); err != nil {
-		return "", err
+	buf, err := r.readBytes(uint64(n / 2 * 2))
+	if err != nil {
+		return NullQString{}, err
+	}
+	units := make([]uint16, len(buf)/2)
+	for i := range units {
+		units[i] = r.ByteOrder.Uint16(buf[2*i:])
 	}
-	return string(utf16.Decode(buf)), nil
+	return NullQString{String: string(utf16.Decode(units)), Valid: true}, nil
 }
 
 func (r *Reader) ReadQTime() (time.Duration, error) { // msecs past midnight
@@ -303,31 +564,102 @@ func (r *Reader) ReadQTime() (time.Duration, error) { // msecs past midnight
 	return time.Millisecond * time.Duration(msecsMidnight), nil
 }
 
-func (r *Reader) ReadQUrl() (*url.URL, error) { // msecs past midnight
-	str, err := r.ReadQString()
+// ReadQUrl reads a URL. QUrl is serialized in its encoded form as a QByteArray.
+// A null encoded form is returned as nil, an empty one as an empty non-nil URL
+func (r *Reader) ReadQUrl() (*url.URL, error) {
+	buf, err := r.ReadQByteArray()
 	if err != nil {
 		return nil, err
 	}
-	if str == "" {
+	if buf == nil {
 		return nil, nil
 	}
-	return url.Parse(str)
+	return url.Parse(string(buf))
 }
 
-func (r *Reader) ReadQVariant() (QMetaType, interface{}, error) { // msecs past midnight
-	t, err := r.ReadUint32()
+// VariantHeader is the part of a serialized QVariant preceding its value
+type VariantHeader struct {
+	Type QMetaType
+	Null bool
+	Name string // Type name of user types
+}
+
+// ReadQVariantHeader reads the metatype and the null flag of a QVariant.
+// It allows reading the value separately, e.g. to keep the metatypes of container elements
+func (r *Reader) ReadQVariantHeader() (VariantHeader, error) {
+	id, err := r.ReadUint32()
 	if err != nil {
+		return VariantHeader{}, err
+	}
+	h := VariantHeader{Type: QMetaType(id)}
+	switch {
+	case r.version < VersionQt5_0:
+		h.Type = metaTypeFromQt4(id)
+	case r.version < VersionQt6_0:
+		h.Type = metaTypeFromQt5(id)
+	default:
+		h.Type = metaTypeFromQt6(id)
+	}
+	if r.version >= VersionQt4_2 {
+		if h.Null, err = r.ReadBool(); err != nil {
+			return VariantHeader{}, err
+		}
+	}
+	if h.Type == QMetaTypeUser {
+		name, err := r.ReadCString()
+		if err != nil {
+			return VariantHeader{}, err
+		}
+		h.Name = strings.TrimSuffix(name, "\x00")
+	}
+	return h, nil
+}
+
+// ReadVariantValue reads the value following a QVariant header.
+// The value is serialized even if the variant is null
+func (r *Reader) ReadVariantValue(h VariantHeader) (interface{}, error) {
+	if h.Type == QMetaTypeUser {
+		return r.readUserType(h.Name)
+	}
+	return r.ReadValue(h.Type)
+}
+
+// ReadQVariant reads a QVariant. Values of user types are returned with QMetaTypeUser,
+// only QVersionNumber and QTimeZone are supported among them
+func (r *Reader) ReadQVariant() (QMetaType, interface{}, error) {
+	if r.depth >= MaxNestingDepth {
+		return 0, nil, ErrTooDeep
+	}
+	if err := r.checkpoint(); err != nil {
 		return 0, nil, err
 	}
-	if null, err := r.ReadBool(); err != nil {
+	r.depth++
+	defer func() { r.depth-- }()
+	h, err := r.ReadQVariantHeader()
+	if err != nil {
 		return 0, nil, err
-	} else if null {
-		return QMetaType(t), nil, nil
 	}
+	v, err := r.ReadVariantValue(h)
+	if h.Null {
+		v = nil
+	}
+	return h.Type, v, err
+}
 
+// ReadVariant reads a QVariant keeping its metatype along with the value
+func (r *Reader) ReadVariant() (Variant, error) {
+	t, v, err := r.ReadQVariant()
+	return Variant{Type: t, Value: v}, err
+}
+
+// ReadValue reads a bare value of the specified metatype, i.e. a value
+// that is not wrapped into a QVariant
+func (r *Reader) ReadValue(t QMetaType) (interface{}, error) {
 	var v interface{}
-	err = nil
-	switch QMetaType(t) {
+	var err error
+	switch t {
+	case QMetaTypeUnknown:
+		// invalid QVariant, no value is serialized
 	case QMetaTypeBool:
 		v, err = r.ReadBool()
 	case QMetaTypeInt:
@@ -342,8 +674,18 @@ func (r *Reader) ReadQVariant() (QMetaType, interface{}, error) { // msecs past
 		v, err = r.ReadDouble()
 	case QMetaTypeFloat:
 		v, err = r.ReadFloat()
-	case QMetaTypeQChar, QMetaTypeChar:
+	case QMetaTypeQChar, QMetaTypeChar16:
+		v, err = r.ReadQChar()
+	case QMetaTypeChar32:
+		var c int32
+		c, err = r.ReadInt32()
+		v = rune(c)
+	case QMetaTypeChar, QMetaTypeUChar:
 		v, err = r.ReadUint8()
+	case QMetaTypeNullptr:
+		// no value is serialized
+	case QMetaTypeQByteArrayList:
+		v, err = r.ReadQByteArrayList()
 	case QMetaTypeSChar:
 		v, err = r.ReadInt8()
 	case QMetaTypeShort:
@@ -353,15 +695,30 @@ func (r *Reader) ReadQVariant() (QMetaType, interface{}, error) { // msecs past
 	case QMetaTypeQBitArray:
 		v, err = r.ReadQBitArray()
 	case QMetaTypeQVariantMap, QMetaTypeQVariantHash:
-		v, err = r.ReadQStringQVariantAssociative()
+		switch {
+		case !r.OrderedMaps:
+			v, err = r.ReadQStringQVariantAssociative()
+		case t == QMetaTypeQVariantHash:
+			var m OrderedMap
+			m, err = r.ReadQStringQVariantOrderedMap()
+			v = OrderedHash(m)
+		default:
+			v, err = r.ReadQStringQVariantOrderedMap()
+		}
 	case QMetaTypeQUuid:
 		v, err = r.ReadQUuid()
 	case QMetaTypeQVariantList:
 		v, err = r.ReadQStringQVariantList()
+	case QMetaTypeQVariant:
+		v, err = r.ReadVariant()
 	case QMetaTypeQByteArray:
 		v, err = r.ReadQByteArray()
 	case QMetaTypeQString:
-		v, err = r.ReadQString()
+		if r.NullStrings {
+			v, err = r.ReadNullQString()
+		} else {
+			v, err = r.ReadQString()
+		}
 	case QMetaTypeQStringList:
 		v, err = r.ReadQStringQStringList()
 	case QMetaTypeQDate:
@@ -372,12 +729,119 @@ func (r *Reader) ReadQVariant() (QMetaType, interface{}, error) { // msecs past
 		v, err = r.ReadQDateTime()
 	case QMetaTypeQUrl:
 		v, err = r.ReadQUrl()
+	case QMetaTypeQPoint:
+		v, err = r.ReadQPoint()
+	case QMetaTypeQPointF:
+		v, err = r.ReadQPointF()
+	case QMetaTypeQSize:
+		v, err = r.ReadQSize()
+	case QMetaTypeQSizeF:
+		v, err = r.ReadQSizeF()
+	case QMetaTypeQRect:
+		v, err = r.ReadQRect()
+	case QMetaTypeQRectF:
+		v, err = r.ReadQRectF()
+	case QMetaTypeQLine:
+		v, err = r.ReadQLine()
+	case QMetaTypeQLineF:
+		v, err = r.ReadQLineF()
+	case QMetaTypeQFont:
+		v, err = r.ReadQFont()
+	case QMetaTypeQBrush:
+		v, err = r.ReadQBrush()
+	case QMetaTypeQColor:
+		v, err = r.ReadQColor()
+	case QMetaTypeQPen:
+		v, err = r.ReadQPen()
+	case QMetaTypeQTransform:
+		v, err = r.ReadQTransform()
+	case QMetaTypeQMatrix:
+		v, err = r.ReadQMatrix()
+	case QMetaTypeQMatrix4x4:
+		v, err = r.ReadQMatrix4x4()
+	case QMetaTypeQVector2D:
+		v, err = r.ReadQVector2D()
+	case QMetaTypeQVector3D:
+		v, err = r.ReadQVector3D()
+	case QMetaTypeQVector4D:
+		v, err = r.ReadQVector4D()
+	case QMetaTypeQQuaternion:
+		v, err = r.ReadQQuaternion()
+	case QMetaTypeQPolygon:
+		v, err = r.ReadQPolygon()
+	case QMetaTypeQPolygonF:
+		v, err = r.ReadQPolygonF()
+	case QMetaTypeQRegion:
+		v, err = r.ReadQRegion()
+	case QMetaTypeQLocale:
+		v, err = r.ReadQLocale()
+	case QMetaTypeQRegExp:
+		v, err = r.ReadQRegExp()
+	case QMetaTypeQRegularExpression:
+		v, err = r.ReadQRegularExpression()
+	case QMetaTypeQEasingCurve:
+		v, err = r.ReadQEasingCurve()
+	case QMetaTypeQImage:
+		v, err = r.ReadQImage()
+	case QMetaTypeQPixmap, QMetaTypeQBitmap:
+		v, err = r.ReadQPixmap()
+	case QMetaTypeQKeySequence:
+		v, err = r.ReadQKeySequence()
+	case QMetaTypeQCursor:
+		v, err = r.ReadQCursor()
+	case QMetaTypeQSizePolicy:
+		v, err = r.ReadQSizePolicy()
+	case QMetaTypeQTextLength:
+		v, err = r.ReadQTextLength()
+	case QMetaTypeQTextFormat:
+		v, err = r.ReadQTextFormat()
+	case QMetaTypeQPalette:
+		v, err = r.ReadQPalette()
+	case QMetaTypeQIcon:
+		v, err = r.ReadQIcon()
+	case QMetaTypeUser:
+		return nil, fmt.Errorf("user types can only be read as a QVariant")
 	default:
-		return QMetaType(t), nil, fmt.Errorf("unimplemented type %d", t)
+		return r.unknownType(t, "")
+	}
+	return v, err
+}
+
+// Qt::TimeSpec values
+const (
+	timeSpecLocalTime     = 0
+	timeSpecUTC           = 1
+	timeSpecOffsetFromUTC = 2
+	timeSpecTimeZone      = 3
+)
+
+// QDateTimePrivate::Spec values used by Qt 4.0 - 5.1 streams
+const (
+	legacySpecLocalUnknown = 0xFF // -1
+	legacySpecUTC          = 2
+)
+
+func readLegacyDateTime(d time.Time, t time.Duration, spec uint8, version int) time.Time {
+	utc := spec == legacySpecUTC
+	if version == VersionQt5_0 {
+		// Qt 5.0 serialized all datetimes as UTC followed by Qt::TimeSpec
+		return time.Date(d.Year(), d.Month(), d.Day(), 0, 0, 0, 0, time.UTC).Add(t).In(timeSpecLocation(spec))
 	}
-	return QMetaType(t), v, err
+	if utc {
+		return time.Date(d.Year(), d.Month(), d.Day(), 0, 0, 0, 0, time.UTC).Add(t)
+	}
+	return time.Date(d.Year(), d.Month(), d.Day(), 0, 0, 0, 0, time.Local).Add(t)
 }
 
+func timeSpecLocation(spec uint8) *time.Location {
+	if spec == timeSpecLocalTime {
+		return time.Local
+	}
+	return time.UTC
+}
+
+// ReadQDateTime reads a date and time. Times with an offset from UTC get a fixed zone,
+// times in a QTimeZone get the location of the time zone
 func (r *Reader) ReadQDateTime() (time.Time, error) {
 	d, err := r.ReadQDate()
 	if err != nil {
@@ -391,11 +855,31 @@ func (r *Reader) ReadQDateTime() (time.Time, error) {
 	if err != nil {
 		return time.Time{}, err
 	}
+	if r.version < VersionQt5_2 {
+		return readLegacyDateTime(d, t, u, r.version), nil
+	}
 	var z *time.Location
-	if u == 0 {
+	switch u {
+	case timeSpecLocalTime:
 		z = time.Local
-	} else {
+	case timeSpecUTC:
 		z = time.UTC
+	case timeSpecOffsetFromUTC:
+		offset, err := r.ReadInt32()
+		if err != nil {
+			return time.Time{}, err
+		}
+		z = time.FixedZone("", int(offset))
+	case timeSpecTimeZone:
+		tz, err := r.ReadQTimeZone()
+		if err != nil {
+			return time.Time{}, err
+		}
+		if z, err = tz.Location(); err != nil {
+			return time.Time{}, fmt.Errorf("time zone %q: %w", tz.ID, err)
+		}
+	default:
+		return time.Time{}, fmt.Errorf("unknown time spec %d", u)
 	}
 	return time.Date(d.Year(), d.Month(), d.Day(), 0, 0, 0, 0, z).Add(t), nil
 }
@@ -405,13 +889,17 @@ func (r *Reader) ReadQStringQVariantList() ([]interface{}, error) {
 	if err != nil {
 		return nil, err
 	}
-	m := make([]interface{}, n)
-	for i := range m {
+	m := make([]interface{}, 0, preallocCount(n))
+	for i := uint32(0); i < n; i++ {
+		// ReadQVariant checks the context and reports progress once per element
 		_, v, err := r.ReadQVariant()
 		if err != nil {
+			if r.keepPartial(err) {
+				return append(m, v), err
+			}
 			return nil, err
 		}
-		m[i] = v
+		m = append(m, v)
 	}
 	return m, nil
 }
@@ -421,13 +909,16 @@ func (r *Reader) ReadQStringQStringList() ([]string, error) {
 	if err != nil {
 		return nil, err
 	}
-	m := make([]string, n)
-	for i := range m {
+	m := make([]string, 0, preallocCount(n))
+	for i := uint32(0); i < n; i++ {
+		if err := r.checkpoint(); err != nil {
+			return nil, err
+		}
 		v, err := r.ReadQString()
 		if err != nil {
 			return nil, err
 		}
-		m[i] = v
+		m = append(m, v)
 	}
 	return m, nil
 }
@@ -445,6 +936,9 @@ func (r *Reader) ReadQStringQVariantAssociative() (map[string]interface{}, error
 		}
 		_, v, err := r.ReadQVariant()
 		if err != nil {
+			if r.keepPartial(err) {
+				m[k] = v
+			}
 			return m, err
 		}
 		m[k] = v
@@ -452,10 +946,10 @@ func (r *Reader) ReadQStringQVariantAssociative() (map[string]interface{}, error
 	return m, nil
 }
 
-func (r *Reader) ReadQUuid() (string, error) {
-	bytes, err := io.ReadAll(io.LimitReader(r.Reader, 16))
-	if err != nil {
-		return "", err
+func (r *Reader) ReadQUuid() (QUuid, error) {
+	var u QUuid
+	if _, err := io.ReadFull(r.Reader, u[:]); err != nil {
+		return QUuid{}, err
 	}
-	return hex.EncodeToString(bytes), nil
+	return u.reorder(r.ByteOrder, binary.BigEndian), nil
 }
@@ -3,12 +3,15 @@ package cutestream
 import (
 	"bytes"
 	"encoding/base64"
+	"encoding/hex"
 	"encoding/json"
 	"io"
+	"net/url"
 	"os"
 	"reflect"
 	"strconv"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 )
@@ -331,7 +334,99 @@ func TestUuid(t *testing.T) {
 			assert.Nil(t, err)
 			uuid, err := reader.ReadQUuid()
 			assert.Nil(t, err)
-			assert.Equal(t, u.Value, uuid)
+			assert.Equal(t, u.Value, uuid.Hex())
 		}
 	}
 }
+
+func TestReadNullQVariant(t *testing.T) {
+	// QVariant(QVariant::Int), QVariant(QVariant::String) and QVariant(7) with Qt 5.15:
+	// the value of a null variant is serialized as well
+	data, _ := hex.DecodeString("0000000201" + "00000000" + "0000000a01" + "ffffffff" + "0000000200" + "00000007")
+	reader := NewReader(bytes.NewReader(data))
+	for _, expected := range []Variant{{QMetaTypeInt, nil}, {QMetaTypeQString, nil}, {QMetaTypeInt, int32(7)}} {
+		readType, v, err := reader.ReadQVariant()
+		assert.Nil(t, err)
+		assert.Equal(t, expected, Variant{readType, v})
+	}
+}
+
+func TestReadQUrl(t *testing.T) {
+	// QVariant(QUrl("https://example.com/a b")) with Qt 5.15, QUrl is serialized encoded as a QByteArray
+	data, _ := hex.DecodeString("0000001100" + "00000019" + hex.EncodeToString([]byte("https://example.com/a%20b")))
+	reader := NewReader(bytes.NewReader(data))
+	readType, v, err := reader.ReadQVariant()
+	assert.Nil(t, err)
+	assert.Equal(t, QMetaTypeQUrl, readType)
+	assert.Equal(t, "/a b", v.(*url.URL).Path)
+
+	var buf bytes.Buffer
+	writer := NewWriter(&buf)
+	assert.Nil(t, writer.WriteQVariant(readType, v))
+	assert.Equal(t, data, buf.Bytes())
+}
+
+func TestReadCharTypes(t *testing.T) {
+	// QVariant::fromValue(char('A')), QVariant::fromValue(uchar(200)) and QVariant::fromValue(qint8(-1)) with Qt 5.15
+	data, _ := hex.DecodeString("0000002200" + "41" + "0000002500" + "c8" + "0000002800" + "ff")
+	reader := NewReader(bytes.NewReader(data))
+	expected := []Variant{{QMetaTypeChar, uint8('A')}, {QMetaTypeUChar, uint8(200)}, {QMetaTypeSChar, int8(-1)}}
+	var buf bytes.Buffer
+	writer := NewWriter(&buf)
+	for _, e := range expected {
+		readType, v, err := reader.ReadQVariant()
+		assert.Nil(t, err)
+		assert.Equal(t, e, Variant{readType, v})
+		assert.Nil(t, writer.WriteQVariant(readType, v))
+	}
+	assert.Equal(t, data, buf.Bytes())
+}
+
+func TestReadOffsetDateTime(t *testing.T) {
+	// QVariant(QDateTime(QDate(2023, 5, 21), QTime(14, 3, 1, 5), Qt::OffsetFromUTC, 7200)) with Qt 5.15
+	data, _ := hex.DecodeString("0000001000" + "00000000002589b6" + "0303ce0d" + "02" + "00001c20")
+	reader := NewReader(bytes.NewReader(data))
+	readType, v, err := reader.ReadQVariant()
+	assert.Nil(t, err)
+	assert.Equal(t, QMetaTypeQDateTime, readType)
+	datetime := v.(time.Time)
+	assert.True(t, time.Date(2023, 5, 21, 12, 3, 1, 5e6, time.UTC).Equal(datetime))
+	_, offset := datetime.Zone()
+	assert.Equal(t, 7200, offset)
+
+	var buf bytes.Buffer
+	writer := NewWriter(&buf)
+	assert.Nil(t, writer.WriteQVariant(readType, v))
+	assert.Equal(t, data, buf.Bytes())
+}
+
+func TestReadQBitArray(t *testing.T) {
+	// QVariant(QBitArray) with the bits 0, 2, 3 of 4 and with the bits 0, 2, 9 of 10 set, Qt 5.15:
+	// QBitArray stores the first bit in the least significant bit of the first byte
+	data, _ := hex.DecodeString("0000000d00" + "00000004" + "0d" + "0000000d00" + "0000000a" + "0502")
+	reader := NewReader(bytes.NewReader(data))
+	for _, expected := range [][]bool{
+		{true, false, true, true},
+		{true, false, true, false, false, false, false, false, false, true},
+	} {
+		readType, v, err := reader.ReadQVariant()
+		assert.Nil(t, err)
+		assert.Equal(t, QMetaTypeQBitArray, readType)
+		assert.Equal(t, expected, v)
+	}
+}
+
+func TestQBitArrayQt6(t *testing.T) {
+	// QVariant(QBitArray) with the bits 0, 2, 3 of 4 set, Qt 6.0 streams the size as a quint64
+	data, _ := hex.DecodeString("0000000d00" + "0000000000000004" + "0d")
+	reader, _ := NewReaderWithVersion(bytes.NewReader(data), VersionQt6_0)
+	readType, v, err := reader.ReadQVariant()
+	assert.Nil(t, err)
+	assert.Equal(t, QMetaTypeQBitArray, readType)
+	assert.Equal(t, []bool{true, false, true, true}, v)
+
+	var buf bytes.Buffer
+	writer, _ := NewWriterWithVersion(&buf, VersionQt6_0)
+	assert.Nil(t, writer.WriteQVariant(readType, v))
+	assert.Equal(t, data, buf.Bytes())
+}
@@ -0,0 +1,140 @@
+package cutestream
+
+import (
+	"errors"
+	"fmt"
+	"io"
+)
+
+// ErrTruncatedRecord is reported by RecordScanner when the stream ends
+// in the middle of a record
+var ErrTruncatedRecord = errors.New("truncated record")
+
+// RecordDecoder decodes a single record from the reader
+type RecordDecoder func(r *Reader) (interface{}, error)
+
+// Field describes a single named value of a record
+type Field struct {
+	Name string
+	Type QMetaType
+}
+
+// Schema describes a record as a sequence of bare (non-QVariant) values
+type Schema []Field
+
+// Decode reads a record described by the schema and returns it
+// as a map from field names to values.
+// Decode can be used as a RecordDecoder.
+func (s Schema) Decode(r *Reader) (interface{}, error) {
+	record := make(map[string]interface{}, len(s))
+	for _, f := range s {
+		v, err := r.ReadValue(f.Type)
+		if err != nil {
+			return record, err
+		}
+		record[f.Name] = v
+	}
+	return record, nil
+}
+
+// RecordScanner reads a sequence of records until the end of the stream.
+// Usage is similar to bufio.Scanner:
+//
+//	s := NewRecordScanner(&reader, decoder)
+//	for s.Next() {
+//		record := s.Record()
+//	}
+//	if err := s.Err(); err != nil {
+//		...
+//	}
+//
+// A stream ending exactly at a record boundary is not an error,
+// while a stream ending inside a record is reported as ErrTruncatedRecord.
+type RecordScanner struct {
+	reader  Reader
+	counter *countingReader
+	decode  RecordDecoder
+	record  interface{}
+	index   int
+	err     error
+}
+
+// NewRecordScanner creates a scanner reading records from the specified
+// reader with the specified decoder. Reader settings (byte order, version, precision)
+// are captured at the moment of the call.
+func NewRecordScanner(r *Reader, decode RecordDecoder) *RecordScanner {
+	counter := &countingReader{reader: r.Reader}
+	reader := *r
+	reader.Reader = counter
+	return &RecordScanner{
+		reader:  reader,
+		counter: counter,
+		decode:  decode,
+	}
+}
+
+// Next decodes the next record. It returns false when the stream is over
+// or an error occurred, in which case Err should be checked
+func (s *RecordScanner) Next() bool {
+	if s.err != nil {
+		return false
+	}
+	start := s.counter.n
+	if err := s.reader.checkpoint(); err != nil {
+		s.record = nil
+		s.err = fmt.Errorf("record %d at offset %d: %w", s.index, start, err)
+		return false
+	}
+	record, err := s.decode(&s.reader)
+	if err != nil {
+		s.record = nil
+		consumed := s.counter.n - start
+		switch {
+		case errors.Is(err, io.EOF) && consumed == 0:
+			s.err = io.EOF
+		case errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF):
+			s.err = fmt.Errorf("record %d at offset %d: %w", s.index, start, ErrTruncatedRecord)
+		default:
+			s.err = fmt.Errorf("record %d at offset %d: %w", s.index, start, err)
+		}
+		return false
+	}
+	s.record = record
+	s.index++
+	return true
+}
+
+// Record returns the record decoded by the last call to Next
+func (s *RecordScanner) Record() interface{} {
+	return s.record
+}
+
+// Count returns the number of records decoded so far
+func (s *RecordScanner) Count() int {
+	return s.index
+}
+
+// Offset returns the number of bytes consumed so far
+func (s *RecordScanner) Offset() int64 {
+	return s.counter.n
+}
+
+// Err returns the first error encountered by the scanner,
+// or nil if the stream ended cleanly at a record boundary
+func (s *RecordScanner) Err() error {
+	if s.err == io.EOF {
+		return nil
+	}
+	return s.err
+}
+
+type countingReader struct {
+	reader io.Reader
+	n      int64
+}
+
+func (c *countingReader) Read(p []byte) (int, error) {
+	n, err := c.reader.Read(p)
+	c.n += int64(n)
+	return n, err
+}
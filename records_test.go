@@ -0,0 +1,141 @@
+package cutestream
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func makeRecords(count int) []byte {
+	var buf bytes.Buffer
+	for i := 0; i < count; i++ {
+		_ = binary.Write(&buf, binary.BigEndian, int32(i))
+		_ = binary.Write(&buf, binary.BigEndian, uint16(i*2))
+	}
+	return buf.Bytes()
+}
+
+func TestRecordScannerSchema(t *testing.T) {
+	schema := Schema{{"index", QMetaTypeInt}, {"double", QMetaTypeUShort}}
+	reader := NewReader(bytes.NewReader(makeRecords(5)))
+	s := NewRecordScanner(&reader, schema.Decode)
+	count := 0
+	for s.Next() {
+		record := s.Record().(map[string]interface{})
+		assert.Equal(t, int32(count), record["index"])
+		assert.Equal(t, uint16(count*2), record["double"])
+		count++
+	}
+	assert.Nil(t, s.Err())
+	assert.Equal(t, 5, count)
+	assert.Equal(t, 5, s.Count())
+	assert.Equal(t, int64(30), s.Offset())
+}
+
+func TestRecordScannerEmpty(t *testing.T) {
+	reader := NewReader(bytes.NewReader(nil))
+	s := NewRecordScanner(&reader, func(r *Reader) (interface{}, error) {
+		return r.ReadInt32()
+	})
+	assert.False(t, s.Next())
+	assert.Nil(t, s.Err())
+}
+
+func TestRecordScannerTruncated(t *testing.T) {
+	data := makeRecords(3)
+	for _, cut := range []int{1, 4, 5} {
+		reader := NewReader(bytes.NewReader(data[:len(data)-cut]))
+		s := NewRecordScanner(&reader, Schema{{"index", QMetaTypeInt}, {"double", QMetaTypeUShort}}.Decode)
+		count := 0
+		for s.Next() {
+			count++
+		}
+		assert.Equal(t, 2, count)
+		assert.True(t, errors.Is(s.Err(), ErrTruncatedRecord), "cut %d: %v", cut, s.Err())
+	}
+}
+
+func TestRecordScannerDecoderError(t *testing.T) {
+	decodeErr := errors.New("bad record")
+	reader := NewReader(bytes.NewReader(makeRecords(2)))
+	s := NewRecordScanner(&reader, func(r *Reader) (interface{}, error) {
+		if _, err := r.ReadInt32(); err != nil {
+			return nil, err
+		}
+		return nil, decodeErr
+	})
+	assert.False(t, s.Next())
+	assert.True(t, errors.Is(s.Err(), decodeErr))
+	assert.False(t, errors.Is(s.Err(), ErrTruncatedRecord))
+}
+
+func TestRecordScannerCancel(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	reader := NewReader(bytes.NewReader(makeRecords(5)))
+	reader.Context = ctx
+	var progress []int64
+	reader.SetProgress(func(consumed int64) { progress = append(progress, consumed) })
+	s := NewRecordScanner(&reader, Schema{{"index", QMetaTypeInt}, {"double", QMetaTypeUShort}}.Decode)
+	for s.Next() {
+		if s.Count() == 2 {
+			cancel()
+		}
+	}
+	assert.True(t, errors.Is(s.Err(), context.Canceled))
+	assert.Equal(t, 2, s.Count())
+	assert.Equal(t, []int64{0, 6, 12}, progress)
+}
+
+func TestCancelContainers(t *testing.T) {
+	var buf bytes.Buffer
+	w := NewWriter(&buf)
+	assert.Nil(t, w.WriteQStringQVariantList([]interface{}{int32(1), []interface{}{"a", "b"}, int32(3)}))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	reader := NewReader(bytes.NewReader(buf.Bytes()))
+	reader.Context = ctx
+	var progress []int64
+	reader.SetProgress(func(consumed int64) {
+		progress = append(progress, consumed)
+		if len(progress) == 3 {
+			cancel()
+		}
+	})
+	_, err := reader.ReadQStringQVariantList()
+	assert.True(t, errors.Is(err, context.Canceled))
+	// progress is reported once before every QVariant, including those of nested lists
+	assert.Equal(t, []int64{4, 13, 22}, progress)
+
+	_, err = ReadList(&reader, (*Reader).ReadInt32)
+	assert.True(t, errors.Is(err, context.Canceled))
+}
+
+func TestProgressCompressed(t *testing.T) {
+	var buf bytes.Buffer
+	w := NewWriter(&buf)
+	assert.Nil(t, w.WriteQCompressed(func(w *Writer) error {
+		return w.WriteQStringQVariantList([]interface{}{int32(1), int32(2), int32(3)})
+	}))
+	size := int64(buf.Len())
+	assert.Nil(t, w.WriteQStringQVariantList([]interface{}{int32(4)}))
+
+	reader := NewReader(bytes.NewReader(buf.Bytes()))
+	var progress []int64
+	reader.SetProgress(func(consumed int64) { progress = append(progress, consumed) })
+	uncompressed, err := reader.ReadQCompressed()
+	assert.Nil(t, err)
+	// the decompressed data doesn't report the stale count of the compressed stream
+	list, err := uncompressed.ReadQStringQVariantList()
+	assert.Nil(t, err)
+	assert.Len(t, list, 3)
+	assert.Empty(t, progress)
+
+	_, err = reader.ReadQStringQVariantList()
+	assert.Nil(t, err)
+	assert.Equal(t, []int64{size + 4}, progress)
+}
@@ -0,0 +1,191 @@
+package cutestream
+
+import (
+	"bytes"
+	"fmt"
+)
+
+// QPolygon is a polygon with integer coordinates
+type QPolygon []QPoint
+
+// QPolygonF is a polygon with floating point coordinates
+type QPolygonF []QPointF
+
+// QRegion is an area made of rectangles. Qt keeps them non-overlapping
+type QRegion []QRect
+
+// PathElementType is the type of a QPainterPath element (QPainterPath::ElementType)
+type PathElementType int32
+
+const (
+	MoveToElement      PathElementType = 0
+	LineToElement      PathElementType = 1
+	CurveToElement     PathElementType = 2 // Followed by two CurveToDataElement: the second control point and the end point
+	CurveToDataElement PathElementType = 3
+)
+
+// PathElement is a single element of a QPainterPath
+type PathElement struct {
+	Type PathElementType
+	X, Y float64
+}
+
+// QPainterPath is a sequence of drawing operations
+type QPainterPath struct {
+	Elements []PathElement
+	CStart   int32 // Index of the element starting the current subpath
+	FillRule int32 // Qt::FillRule: 0 for OddEvenFill, 1 for WindingFill
+}
+
+// QRegion serialization commands, from qregion.cpp
+const (
+	regionSetRect           = 1
+	regionSetEllipse        = 2
+	regionSetPolygonOddEven = 3
+	regionSetPolygonWinding = 4
+	regionTranslate         = 5
+	regionOr                = 6
+	regionAnd               = 7
+	regionSub               = 8
+	regionXor               = 9
+	regionRects             = 10
+)
+
+func (r *Reader) ReadQPolygon() (QPolygon, error) {
+	return ReadList(r, (*Reader).ReadQPoint)
+}
+
+func (r *Reader) ReadQPolygonF() (QPolygonF, error) {
+	return ReadList(r, (*Reader).ReadQPointF)
+}
+
+// ReadQRegion reads a region. Qt serializes regions as a byte array of commands,
+// only the list of rectangles written by Qt 2 and later and unions of rectangles are supported
+func (r *Reader) ReadQRegion() (QRegion, error) {
+	data, err := r.ReadQByteArray()
+	if err != nil {
+		return nil, err
+	}
+	if len(data) == 0 {
+		return nil, nil
+	}
+	// unions nest byte arrays of commands
+	if r.depth >= MaxNestingDepth {
+		return nil, ErrTooDeep
+	}
+	commands := r.subReader(data)
+	commands.depth++
+	return commands.readRegionCommand()
+}
+
+func (r *Reader) readRegionCommand() (QRegion, error) {
+	command, err := r.ReadInt32()
+	if err != nil {
+		return nil, err
+	}
+	switch command {
+	case regionSetRect:
+		rect, err := r.ReadQRect()
+		if err != nil {
+			return nil, err
+		}
+		return QRegion{rect}, nil
+	case regionRects:
+		return ReadList(r, (*Reader).ReadQRect)
+	case regionOr:
+		var region QRegion
+		for i := 0; i < 2; i++ {
+			operand, err := r.ReadQRegion()
+			if err != nil {
+				return nil, err
+			}
+			region = append(region, operand...)
+		}
+		return region, nil
+	default:
+		return nil, fmt.Errorf("unsupported region command %d", command)
+	}
+}
+
+func (r *Reader) readPathElement() (PathElement, error) {
+	t, err := r.ReadInt32()
+	if err != nil {
+		return PathElement{}, err
+	}
+	x, err := r.ReadDouble()
+	if err != nil {
+		return PathElement{}, err
+	}
+	y, err := r.ReadDouble()
+	if err != nil {
+		return PathElement{}, err
+	}
+	return PathElement{PathElementType(t), x, y}, nil
+}
+
+// ReadQPainterPath reads a painter path. An empty path has no elements
+func (r *Reader) ReadQPainterPath() (QPainterPath, error) {
+	var p QPainterPath
+	var err error
+	if p.Elements, err = ReadList(r, (*Reader).readPathElement); err != nil {
+		return QPainterPath{}, err
+	}
+	if len(p.Elements) == 0 {
+		return p, nil
+	}
+	if p.CStart, err = r.ReadInt32(); err != nil {
+		return QPainterPath{}, err
+	}
+	if p.FillRule, err = r.ReadInt32(); err != nil {
+		return QPainterPath{}, err
+	}
+	return p, nil
+}
+
+func (w *Writer) WriteQPolygon(v QPolygon) error {
+	return WriteList(w, v, (*Writer).WriteQPoint)
+}
+
+func (w *Writer) WriteQPolygonF(v QPolygonF) error {
+	return WriteList(w, v, (*Writer).WriteQPointF)
+}
+
+// WriteQRegion writes a region as a list of rectangles. An empty region is written as an empty byte array
+func (w *Writer) WriteQRegion(v QRegion) error {
+	if len(v) == 0 {
+		return w.WriteQByteArray([]byte{})
+	}
+	var buf bytes.Buffer
+	commands := *w
+	commands.Writer = &buf
+	if err := commands.WriteInt32(regionRects); err != nil {
+		return err
+	}
+	if err := WriteList(&commands, v, (*Writer).WriteQRect); err != nil {
+		return err
+	}
+	return w.WriteQByteArray(buf.Bytes())
+}
+
+func (w *Writer) writePathElement(v PathElement) error {
+	if err := w.WriteInt32(int32(v.Type)); err != nil {
+		return err
+	}
+	if err := w.WriteDouble(v.X); err != nil {
+		return err
+	}
+	return w.WriteDouble(v.Y)
+}
+
+func (w *Writer) WriteQPainterPath(v QPainterPath) error {
+	if err := WriteList(w, v.Elements, (*Writer).writePathElement); err != nil {
+		return err
+	}
+	if len(v.Elements) == 0 {
+		return nil
+	}
+	if err := w.WriteInt32(v.CStart); err != nil {
+		return err
+	}
+	return w.WriteInt32(v.FillRule)
+}
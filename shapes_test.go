@@ -0,0 +1,87 @@
+package cutestream
+
+import (
+	"bytes"
+	"encoding/hex"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestReadQRegion(t *testing.T) {
+	// QVariant(QRegion(0, 0, 10, 10)) with Qt 5.15
+	data, _ := hex.DecodeString("0000004800" + "00000018" + "0000000a" + "00000001" + "00000000000000000000000900000009")
+	reader := NewReader(bytes.NewReader(data))
+	readType, v, err := reader.ReadQVariant()
+	assert.Nil(t, err)
+	assert.Equal(t, QMetaTypeQRegion, readType)
+	assert.Equal(t, QRegion{{0, 0, 10, 10}}, v)
+
+	var buf bytes.Buffer
+	writer := NewWriter(&buf)
+	assert.Nil(t, writer.WriteQVariant(QMetaTypeQRegion, v))
+	assert.Equal(t, data, buf.Bytes())
+
+	// union of two rectangles as written by Qt 1
+	data, _ = hex.DecodeString("00000034" + "00000006" +
+		"00000014" + "00000001" + "00000000000000000000000900000009" +
+		"00000014" + "00000001" + "00000014000000000000001d00000009")
+	reader = NewReader(bytes.NewReader(data))
+	region, err := reader.ReadQRegion()
+	assert.Nil(t, err)
+	assert.Equal(t, QRegion{{0, 0, 10, 10}, {20, 0, 10, 10}}, region)
+
+	data, _ = hex.DecodeString("00000014" + "00000002" + "00000000000000000000000900000009")
+	reader = NewReader(bytes.NewReader(data))
+	_, err = reader.ReadQRegion()
+	assert.NotNil(t, err)
+}
+
+func TestShapesRoundTrip(t *testing.T) {
+	path := QPainterPath{
+		Elements: []PathElement{
+			{MoveToElement, 0, 0},
+			{LineToElement, 10, 0},
+			{CurveToElement, 10, 5},
+			{CurveToDataElement, 5, 10},
+			{CurveToDataElement, 0, 10},
+		},
+		FillRule: 1,
+	}
+	values := []struct {
+		t QMetaType
+		v interface{}
+	}{
+		{QMetaTypeQPolygon, QPolygon{{0, 0}, {10, 0}, {10, -10}}},
+		{QMetaTypeQPolygonF, QPolygonF{{0.5, 0.25}, {-100.5, 3}}},
+		{QMetaTypeQRegion, QRegion{{0, 0, 10, 5}, {0, 5, 20, 5}}},
+		{QMetaTypeQRegion, nil},
+		{QMetaTypeQVariantList, []interface{}{QPolygonF{{1, 2}}, QRegion{{1, 2, 3, 4}}}},
+	}
+	for _, precision := range []bool{false, true} {
+		var buf bytes.Buffer
+		writer := NewWriter(&buf)
+		writer.DoublePrecision = precision
+		for _, v := range values {
+			assert.Nil(t, writer.WriteQVariant(v.t, v.v))
+		}
+		assert.Nil(t, writer.WriteQPainterPath(path))
+		assert.Nil(t, writer.WriteQPainterPath(QPainterPath{}))
+
+		reader := NewReader(&buf)
+		reader.DoublePrecision = precision
+		for _, v := range values {
+			readType, readValue, err := reader.ReadQVariant()
+			assert.Nil(t, err)
+			assert.Equal(t, v.t, readType)
+			assert.Equal(t, v.v, readValue)
+		}
+		p, err := reader.ReadQPainterPath()
+		assert.Nil(t, err)
+		assert.Equal(t, path, p)
+		p, err = reader.ReadQPainterPath()
+		assert.Nil(t, err)
+		assert.Empty(t, p.Elements)
+		assert.Equal(t, 0, buf.Len())
+	}
+}
@@ -0,0 +1,222 @@
+package cutestream
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// QLocale is a locale name as returned by QLocale::name(), e.g. "en_US" or "C"
+type QLocale string
+
+// BCP47 returns the locale as a BCP 47 language tag, e.g. "en-US".
+// The C locale is returned as "en" like QLocale::bcp47Name() does
+func (l QLocale) BCP47() string {
+	if l == "C" {
+		return "en"
+	}
+	return strings.ReplaceAll(string(l), "_", "-")
+}
+
+// CaseSensitivity is Qt::CaseSensitivity
+type CaseSensitivity uint8
+
+const (
+	CaseInsensitive CaseSensitivity = 0
+	CaseSensitive   CaseSensitivity = 1
+)
+
+// PatternSyntax is the syntax of a QRegExp pattern (QRegExp::PatternSyntax)
+type PatternSyntax uint8
+
+const (
+	PatternSyntaxRegExp         PatternSyntax = 0
+	PatternSyntaxWildcard       PatternSyntax = 1
+	PatternSyntaxFixedString    PatternSyntax = 2
+	PatternSyntaxRegExp2        PatternSyntax = 3
+	PatternSyntaxWildcardUnix   PatternSyntax = 4
+	PatternSyntaxW3CXmlSchema11 PatternSyntax = 5
+)
+
+// QRegExp is a Qt 5 regular expression
+type QRegExp struct {
+	Pattern         string
+	CaseSensitivity CaseSensitivity
+	Syntax          PatternSyntax
+	Minimal         bool // Non-greedy matching
+}
+
+// Regexp converts the expression to a Go regular expression on a best-effort basis.
+// Wildcard patterns are converted to match whole strings, like QRegExp::exactMatch() would
+func (re QRegExp) Regexp() (*regexp.Regexp, error) {
+	var pattern string
+	switch re.Syntax {
+	case PatternSyntaxRegExp, PatternSyntaxRegExp2:
+		pattern = re.Pattern
+	case PatternSyntaxFixedString:
+		pattern = regexp.QuoteMeta(re.Pattern)
+	case PatternSyntaxWildcard, PatternSyntaxWildcardUnix:
+		pattern = "^" + wildcardToRegexp(re.Pattern, re.Syntax == PatternSyntaxWildcardUnix) + "$"
+	default:
+		return nil, fmt.Errorf("unsupported pattern syntax %d", re.Syntax)
+	}
+	flags := ""
+	if re.CaseSensitivity == CaseInsensitive {
+		flags += "i"
+	}
+	if re.Minimal {
+		flags += "U"
+	}
+	if flags != "" {
+		pattern = "(?" + flags + ")" + pattern
+	}
+	return regexp.Compile(pattern)
+}
+
+// wildcardToRegexp converts a shell-like wildcard pattern to a regular expression.
+// Backslash escapes are only recognized in the Unix flavour
+func wildcardToRegexp(pattern string, unix bool) string {
+	var b strings.Builder
+	runes := []rune(pattern)
+	for i := 0; i < len(runes); i++ {
+		switch c := runes[i]; c {
+		case '*':
+			b.WriteString(".*")
+		case '?':
+			b.WriteString(".")
+		case '[':
+			end := i + 1
+			for end < len(runes) && runes[end] != ']' {
+				end++
+			}
+			if end == len(runes) {
+				b.WriteString(`\[`)
+				continue
+			}
+			class := string(runes[i+1 : end])
+			if strings.HasPrefix(class, "!") {
+				class = "^" + class[1:]
+			}
+			b.WriteString("[" + strings.ReplaceAll(class, `\`, `\\`) + "]")
+			i = end
+		case '\\':
+			if unix && i+1 < len(runes) {
+				i++
+				b.WriteString(regexp.QuoteMeta(string(runes[i])))
+			} else {
+				b.WriteString(`\\`)
+			}
+		default:
+			b.WriteString(regexp.QuoteMeta(string(c)))
+		}
+	}
+	return b.String()
+}
+
+// QRegularExpression pattern options (QRegularExpression::PatternOption)
+const (
+	RegularExpressionCaseInsensitive       = 0x0001
+	RegularExpressionDotMatchesEverything  = 0x0002
+	RegularExpressionMultiline             = 0x0004
+	RegularExpressionExtendedPatternSyntax = 0x0008
+	RegularExpressionInvertedGreediness    = 0x0010
+	RegularExpressionDontCapture           = 0x0020
+	RegularExpressionUseUnicodeProperties  = 0x0040
+)
+
+// QRegularExpression is a Perl-compatible regular expression
+type QRegularExpression struct {
+	Pattern string
+	Options uint32
+}
+
+// Regexp converts the expression to a Go regular expression on a best-effort basis.
+// Patterns using PCRE features missing in RE2, like lookarounds and backreferences,
+// and the extended pattern syntax result in an error
+func (re QRegularExpression) Regexp() (*regexp.Regexp, error) {
+	if re.Options&RegularExpressionExtendedPatternSyntax != 0 {
+		return nil, fmt.Errorf("extended pattern syntax is not supported")
+	}
+	flags := ""
+	for _, f := range []struct {
+		option uint32
+		flag   string
+	}{
+		{RegularExpressionCaseInsensitive, "i"},
+		{RegularExpressionDotMatchesEverything, "s"},
+		{RegularExpressionMultiline, "m"},
+		{RegularExpressionInvertedGreediness, "U"},
+	} {
+		if re.Options&f.option != 0 {
+			flags += f.flag
+		}
+	}
+	pattern := re.Pattern
+	if flags != "" {
+		pattern = "(?" + flags + ")" + pattern
+	}
+	return regexp.Compile(pattern)
+}
+
+func (r *Reader) ReadQLocale() (QLocale, error) {
+	name, err := r.ReadQString()
+	return QLocale(name), err
+}
+
+func (r *Reader) ReadQRegExp() (QRegExp, error) {
+	var re QRegExp
+	var err error
+	if re.Pattern, err = r.ReadQString(); err != nil {
+		return QRegExp{}, err
+	}
+	cs, err := r.ReadUint8()
+	if err != nil {
+		return QRegExp{}, err
+	}
+	re.CaseSensitivity = CaseSensitivity(cs)
+	syntax, err := r.ReadUint8()
+	if err != nil {
+		return QRegExp{}, err
+	}
+	re.Syntax = PatternSyntax(syntax)
+	if re.Minimal, err = r.ReadBool(); err != nil {
+		return QRegExp{}, err
+	}
+	return re, nil
+}
+
+func (r *Reader) ReadQRegularExpression() (QRegularExpression, error) {
+	pattern, err := r.ReadQString()
+	if err != nil {
+		return QRegularExpression{}, err
+	}
+	options, err := r.ReadUint32()
+	if err != nil {
+		return QRegularExpression{}, err
+	}
+	return QRegularExpression{pattern, options}, nil
+}
+
+func (w *Writer) WriteQLocale(v QLocale) error {
+	return w.WriteQString(string(v))
+}
+
+func (w *Writer) WriteQRegExp(v QRegExp) error {
+	if err := w.WriteQString(v.Pattern); err != nil {
+		return err
+	}
+	if err := w.WriteUint8(uint8(v.CaseSensitivity)); err != nil {
+		return err
+	}
+	if err := w.WriteUint8(uint8(v.Syntax)); err != nil {
+		return err
+	}
+	return w.WriteBool(v.Minimal)
+}
+
+func (w *Writer) WriteQRegularExpression(v QRegularExpression) error {
+	if err := w.WriteQString(v.Pattern); err != nil {
+		return err
+	}
+	return w.WriteUint32(v.Options)
+}
@@ -0,0 +1,94 @@
+package cutestream
+
+import (
+	"bytes"
+	"encoding/hex"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestReadQRegularExpression(t *testing.T) {
+	// QVariant(QRegularExpression("lap\\d", QRegularExpression::CaseInsensitiveOption)) with Qt 5.15
+	data, _ := hex.DecodeString("0000002c00" + "0000000a006c00610070005c0064" + "00000001")
+	reader := NewReader(bytes.NewReader(data))
+	readType, v, err := reader.ReadQVariant()
+	assert.Nil(t, err)
+	assert.Equal(t, QMetaTypeQRegularExpression, readType)
+	re := v.(QRegularExpression)
+	assert.Equal(t, QRegularExpression{`lap\d`, RegularExpressionCaseInsensitive}, re)
+
+	goRe, err := re.Regexp()
+	assert.Nil(t, err)
+	assert.True(t, goRe.MatchString("LAP3"))
+	assert.False(t, goRe.MatchString("lap"))
+
+	re = QRegularExpression{"a.+b", RegularExpressionDotMatchesEverything | RegularExpressionInvertedGreediness}
+	goRe, err = re.Regexp()
+	assert.Nil(t, err)
+	assert.Equal(t, "a\nb", goRe.FindString("a\nbb"))
+	_, err = QRegularExpression{`(?=lap)`, 0}.Regexp()
+	assert.NotNil(t, err)
+	_, err = QRegularExpression{"a b", RegularExpressionExtendedPatternSyntax}.Regexp()
+	assert.NotNil(t, err)
+}
+
+func TestQRegExp(t *testing.T) {
+	matches := []struct {
+		re      QRegExp
+		match   []string
+		noMatch []string
+	}{
+		{QRegExp{`sector\d`, CaseSensitive, PatternSyntaxRegExp, false}, []string{"sector1"}, []string{"Sector1"}},
+		{QRegExp{`a.c`, CaseInsensitive, PatternSyntaxFixedString, false}, []string{"xA.Cx"}, []string{"abc"}},
+		{QRegExp{`*.qds`, CaseSensitive, PatternSyntaxWildcard, false}, []string{"lap.qds"}, []string{"lap.qds.bak"}},
+		{QRegExp{`lap[!0-4]?.[ch]`, CaseSensitive, PatternSyntaxWildcard, false}, []string{"lap5x.c"}, []string{"lap3x.c"}},
+		{QRegExp{`lap\*`, CaseSensitive, PatternSyntaxWildcardUnix, false}, []string{"lap*"}, []string{"lap1"}},
+		{QRegExp{`[lap`, CaseSensitive, PatternSyntaxWildcard, false}, []string{"[lap"}, []string{"l"}},
+	}
+	for _, m := range matches {
+		re, err := m.re.Regexp()
+		assert.Nil(t, err, m.re.Pattern)
+		for _, s := range m.match {
+			assert.True(t, re.MatchString(s), "%s should match %s", m.re.Pattern, s)
+		}
+		for _, s := range m.noMatch {
+			assert.False(t, re.MatchString(s), "%s should not match %s", m.re.Pattern, s)
+		}
+	}
+	re, err := QRegExp{`<.+>`, CaseSensitive, PatternSyntaxRegExp2, true}.Regexp()
+	assert.Nil(t, err)
+	assert.Equal(t, "<a>", re.FindString("<a><b>"))
+	_, err = QRegExp{`a`, CaseSensitive, PatternSyntaxW3CXmlSchema11, false}.Regexp()
+	assert.NotNil(t, err)
+}
+
+func TestQLocale(t *testing.T) {
+	assert.Equal(t, "de-DE", QLocale("de_DE").BCP47())
+	assert.Equal(t, "en", QLocale("C").BCP47())
+}
+
+func TestTextRoundTrip(t *testing.T) {
+	values := []struct {
+		t QMetaType
+		v interface{}
+	}{
+		{QMetaTypeQLocale, QLocale("fr_CA")},
+		{QMetaTypeQRegExp, QRegExp{`^\w+$`, CaseInsensitive, PatternSyntaxRegExp2, true}},
+		{QMetaTypeQRegularExpression, QRegularExpression{`(\d+):(\d+)`, RegularExpressionMultiline}},
+		{QMetaTypeQVariantList, []interface{}{QLocale("C"), QRegularExpression{"x", 0}}},
+	}
+	var buf bytes.Buffer
+	writer := NewWriter(&buf)
+	for _, v := range values {
+		assert.Nil(t, writer.WriteQVariant(v.t, v.v))
+	}
+	reader := NewReader(&buf)
+	for _, v := range values {
+		readType, readValue, err := reader.ReadQVariant()
+		assert.Nil(t, err)
+		assert.Equal(t, v.t, readType)
+		assert.Equal(t, v.v, readValue)
+	}
+	assert.Equal(t, 0, buf.Len())
+}
@@ -0,0 +1,112 @@
+package cutestream
+
+import (
+	"errors"
+	"fmt"
+	"io"
+)
+
+// UnknownTypePolicy defines how Reader handles QVariant values of unimplemented types
+type UnknownTypePolicy int
+
+const (
+	// FailOnUnknownType makes reading fail with an *UnknownTypeError
+	FailOnUnknownType UnknownTypePolicy = iota
+	// KeepUnknownType reads values of unimplemented types as UnknownVariant.
+	// Payloads of a known size are skipped and reading goes on. Otherwise reading stops:
+	// the QVariant is returned along with an *UnknownTypeError, and so are the containers
+	// being read with the elements read so far
+	KeepUnknownType
+)
+
+// UnknownVariant is a placeholder for a QVariant value of an unimplemented type
+type UnknownVariant struct {
+	Type QMetaType
+	Name string // Type name of user types
+}
+
+// UnknownTypeError is returned when a value of an unimplemented type can't be read.
+// The remaining data of the stream can't be read after it
+type UnknownTypeError struct {
+	Type QMetaType
+	Name string
+}
+
+func (e *UnknownTypeError) Error() string {
+	if e.Name != "" {
+		return fmt.Sprintf("unimplemented user type %q", e.Name)
+	}
+	return fmt.Sprintf("unimplemented type %d", e.Type)
+}
+
+// QJsonValue::Type values
+const (
+	jsonNull   = 0
+	jsonBool   = 1
+	jsonDouble = 2
+	jsonString = 3
+	jsonArray  = 4
+	jsonObject = 5
+)
+
+// unknownType handles a value of an unimplemented type according to the policy
+func (r *Reader) unknownType(t QMetaType, name string) (interface{}, error) {
+	if r.UnknownTypes != KeepUnknownType {
+		return nil, &UnknownTypeError{t, name}
+	}
+	v := UnknownVariant{t, name}
+	if name == "" {
+		if skipped, err := r.skipValue(t); skipped {
+			return v, err
+		}
+	}
+	return v, &UnknownTypeError{t, name}
+}
+
+// keepPartial reports whether the value read along with err is a partial result to be kept
+func (r *Reader) keepPartial(err error) bool {
+	var unknown *UnknownTypeError
+	return r.UnknownTypes == KeepUnknownType && errors.As(err, &unknown)
+}
+
+// skipValue skips the payload of an unimplemented type if its size can be derived from the stream
+func (r *Reader) skipValue(t QMetaType) (bool, error) {
+	switch t {
+	case QMetaTypeLong, QMetaTypeULong:
+		// streamed as qlonglong
+		return true, r.skip(8)
+	case QMetaTypeQCborSimpleType:
+		return true, r.skip(1)
+	case QMetaTypeQJsonDocument, QMetaTypeQJsonObject, QMetaTypeQJsonArray,
+		QMetaTypeQCborValue, QMetaTypeQCborArray, QMetaTypeQCborMap:
+		// streamed as JSON or CBOR byte arrays
+		_, err := r.ReadQByteArray()
+		return true, err
+	case QMetaTypeQJsonValue:
+		jsonType, err := r.ReadUint8()
+		if err != nil {
+			return true, err
+		}
+		switch jsonType {
+		case jsonBool:
+			_, err = r.ReadBool()
+		case jsonDouble:
+			_, err = r.ReadDouble()
+		case jsonString:
+			_, err = r.ReadQString()
+		case jsonArray, jsonObject:
+			_, err = r.ReadQByteArray()
+		}
+		return true, err
+	default:
+		return false, nil
+	}
+}
+
+func (r *Reader) skip(n int64) error {
+	_, err := io.CopyN(io.Discard, r.Reader, n)
+	if err == io.EOF {
+		err = io.ErrUnexpectedEOF
+	}
+	return err
+}
@@ -0,0 +1,81 @@
+package cutestream
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// writeUnknownTypesMap writes a QVariantMap with values of unimplemented types,
+// the last one of which can't be skipped
+func writeUnknownTypesMap(t *testing.T) []byte {
+	var buf bytes.Buffer
+	writer := NewWriter(&buf)
+	assert.Nil(t, writer.WriteUint32(6))
+	assert.Nil(t, writer.WriteQString("a"))
+	assert.Nil(t, writer.WriteQVariant(QMetaTypeInt, int32(1)))
+	// QJsonDocument is streamed as a byte array
+	assert.Nil(t, writer.WriteQString("doc"))
+	assert.Nil(t, writer.WriteUint32(uint32(QMetaTypeQJsonDocument)))
+	assert.Nil(t, writer.WriteBool(false))
+	assert.Nil(t, writer.WriteQByteArray([]byte(`{"k":1}`)))
+	// QJsonValue holding a string
+	assert.Nil(t, writer.WriteQString("json"))
+	assert.Nil(t, writer.WriteUint32(uint32(QMetaTypeQJsonValue)))
+	assert.Nil(t, writer.WriteBool(false))
+	assert.Nil(t, writer.WriteUint8(jsonString))
+	assert.Nil(t, writer.WriteQString("v"))
+	// list stopped by a user type
+	assert.Nil(t, writer.WriteQString("list"))
+	assert.Nil(t, writer.WriteUint32(uint32(QMetaTypeQVariantList)))
+	assert.Nil(t, writer.WriteBool(false))
+	assert.Nil(t, writer.WriteUint32(3))
+	assert.Nil(t, writer.WriteQVariant(QMetaTypeQString, "x"))
+	assert.Nil(t, writer.WriteUint32(qt5UserType))
+	assert.Nil(t, writer.WriteBool(false))
+	assert.Nil(t, writer.WriteCString("MyType\x00"))
+	assert.Nil(t, writer.writeRaw([]byte{1, 2, 3}))
+	return buf.Bytes()
+}
+
+func TestUnknownTypeFail(t *testing.T) {
+	reader := NewReader(bytes.NewReader(writeUnknownTypesMap(t)))
+	_, err := reader.ReadQStringQVariantAssociative()
+	var unknown *UnknownTypeError
+	assert.True(t, errors.As(err, &unknown))
+	assert.Equal(t, QMetaTypeQJsonDocument, unknown.Type)
+}
+
+func TestUnknownTypeKeep(t *testing.T) {
+	reader := NewReader(bytes.NewReader(writeUnknownTypesMap(t)))
+	reader.UnknownTypes = KeepUnknownType
+	m, err := reader.ReadQStringQVariantAssociative()
+	var unknown *UnknownTypeError
+	assert.True(t, errors.As(err, &unknown))
+	assert.Equal(t, &UnknownTypeError{QMetaTypeUser, "MyType"}, unknown)
+	assert.Equal(t, map[string]interface{}{
+		"a":    int32(1),
+		"doc":  UnknownVariant{Type: QMetaTypeQJsonDocument},
+		"json": UnknownVariant{Type: QMetaTypeQJsonValue},
+		"list": []interface{}{"x", UnknownVariant{QMetaTypeUser, "MyType"}},
+	}, m)
+}
+
+func TestUnknownTypeKeepInList(t *testing.T) {
+	var buf bytes.Buffer
+	writer := NewWriter(&buf)
+	assert.Nil(t, writer.WriteUint32(3))
+	assert.Nil(t, writer.WriteQVariant(QMetaTypeInt, int32(1)))
+	assert.Nil(t, writer.WriteUint32(uint32(QMetaTypeQModelIndex)))
+	assert.Nil(t, writer.WriteBool(false))
+	reader := NewReader(&buf)
+	reader.UnknownTypes = KeepUnknownType
+	list, err := ReadList(&reader, (*Reader).ReadVariant)
+	assert.NotNil(t, err)
+	assert.Equal(t, []Variant{
+		{QMetaTypeInt, int32(1)},
+		{QMetaTypeQModelIndex, UnknownVariant{Type: QMetaTypeQModelIndex}},
+	}, list)
+}
@@ -0,0 +1,146 @@
+// Package widgetstate decodes and encodes the state blobs saved by Qt widgets,
+// such as QWidget::saveGeometry() and QMainWindow::saveState().
+package widgetstate
+
+import (
+	"bytes"
+	"fmt"
+
+	"github.com/race-engineering-center/cutestream"
+)
+
+// GeometryMagic is the magic number starting QWidget::saveGeometry() data
+const GeometryMagic = 0x1D9D0CB
+
+// Qt::WindowState flags
+const (
+	windowMaximized  = 0x2
+	windowFullScreen = 0x4
+)
+
+// geometryVersion is the QDataStream version used by QWidget::saveGeometry()
+const geometryVersion = cutestream.VersionQt4_0
+
+// Geometry is the state saved by QWidget::saveGeometry().
+// Format history:
+//   - 1.0: Qt 4.2 - 5.3
+//   - 2.0: Qt 5.4, adds ScreenWidth
+//   - 3.0: Qt 5.12, adds Geometry
+type Geometry struct {
+	MajorVersion   uint16
+	MinorVersion   uint16
+	FrameGeometry  cutestream.QRect
+	NormalGeometry cutestream.QRect
+	Screen         int32
+	Maximized      bool
+	FullScreen     bool
+	ScreenWidth    int32            // Since 2.0
+	Geometry       cutestream.QRect // Since 3.0
+}
+
+// DecodeGeometry decodes data returned by QWidget::saveGeometry()
+func DecodeGeometry(data []byte) (*Geometry, error) {
+	r, err := cutestream.NewReaderWithVersion(bytes.NewReader(data), geometryVersion)
+	if err != nil {
+		return nil, err
+	}
+	magic, err := r.ReadUint32()
+	if err != nil {
+		return nil, err
+	}
+	if magic != GeometryMagic {
+		return nil, fmt.Errorf("invalid geometry magic 0x%x", magic)
+	}
+	var g Geometry
+	if g.MajorVersion, err = r.ReadUint16(); err != nil {
+		return nil, err
+	}
+	if g.MinorVersion, err = r.ReadUint16(); err != nil {
+		return nil, err
+	}
+	if g.MajorVersion < 1 || g.MajorVersion > 3 {
+		return nil, fmt.Errorf("unsupported geometry version %d.%d", g.MajorVersion, g.MinorVersion)
+	}
+	if g.FrameGeometry, err = r.ReadQRect(); err != nil {
+		return nil, err
+	}
+	if g.NormalGeometry, err = r.ReadQRect(); err != nil {
+		return nil, err
+	}
+	if g.Screen, err = r.ReadInt32(); err != nil {
+		return nil, err
+	}
+	if g.Maximized, err = r.ReadBool(); err != nil {
+		return nil, err
+	}
+	if g.FullScreen, err = r.ReadBool(); err != nil {
+		return nil, err
+	}
+	if g.MajorVersion > 1 {
+		if g.ScreenWidth, err = r.ReadInt32(); err != nil {
+			return nil, err
+		}
+	}
+	if g.MajorVersion > 2 {
+		if g.Geometry, err = r.ReadQRect(); err != nil {
+			return nil, err
+		}
+	}
+	return &g, nil
+}
+
+// Encode encodes the geometry in the format of QWidget::saveGeometry().
+// Fields not present in the geometry version are omitted
+func (g *Geometry) Encode() ([]byte, error) {
+	var buf bytes.Buffer
+	w, err := cutestream.NewWriterWithVersion(&buf, geometryVersion)
+	if err != nil {
+		return nil, err
+	}
+	if g.MajorVersion < 1 || g.MajorVersion > 3 {
+		return nil, fmt.Errorf("unsupported geometry version %d.%d", g.MajorVersion, g.MinorVersion)
+	}
+	if err := w.WriteUint32(GeometryMagic); err != nil {
+		return nil, err
+	}
+	if err := w.WriteUint16(g.MajorVersion); err != nil {
+		return nil, err
+	}
+	if err := w.WriteUint16(g.MinorVersion); err != nil {
+		return nil, err
+	}
+	if err := w.WriteQRect(g.FrameGeometry); err != nil {
+		return nil, err
+	}
+	if err := w.WriteQRect(g.NormalGeometry); err != nil {
+		return nil, err
+	}
+	if err := w.WriteInt32(g.Screen); err != nil {
+		return nil, err
+	}
+	// Qt writes the masked Qt::WindowState values instead of booleans
+	if err := w.WriteUint8(flag(g.Maximized, windowMaximized)); err != nil {
+		return nil, err
+	}
+	if err := w.WriteUint8(flag(g.FullScreen, windowFullScreen)); err != nil {
+		return nil, err
+	}
+	if g.MajorVersion > 1 {
+		if err := w.WriteInt32(g.ScreenWidth); err != nil {
+			return nil, err
+		}
+	}
+	if g.MajorVersion > 2 {
+		if err := w.WriteQRect(g.Geometry); err != nil {
+			return nil, err
+		}
+	}
+	return buf.Bytes(), nil
+}
+
+func flag(set bool, value uint8) uint8 {
+	if set {
+		return value
+	}
+	return 0
+}
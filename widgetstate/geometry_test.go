@@ -0,0 +1,54 @@
+package widgetstate
+
+import (
+	"encoding/hex"
+	"testing"
+
+	"github.com/race-engineering-center/cutestream"
+	"github.com/stretchr/testify/assert"
+)
+
+// QWidget::saveGeometry() of a maximized window on a 1920 pixels wide screen, Qt 5.15
+const geometryHex = "01d9d0cb00030000" +
+	"ffffffffffffffe70000077f00000437" + // frame geometry
+	"00000000000000000000077f0000041b" + // normal geometry
+	"00000000" + // screen
+	"0200" + // maximized, not full screen
+	"00000780" + // screen width
+	"00000000000000000000077f0000041b" // geometry
+
+func TestDecodeGeometry(t *testing.T) {
+	data, _ := hex.DecodeString(geometryHex)
+	g, err := DecodeGeometry(data)
+	assert.Nil(t, err)
+	assert.Equal(t, &Geometry{
+		MajorVersion:   3,
+		MinorVersion:   0,
+		FrameGeometry:  cutestream.QRect{X: -1, Y: -25, Width: 1921, Height: 1105},
+		NormalGeometry: cutestream.QRect{X: 0, Y: 0, Width: 1920, Height: 1052},
+		Screen:         0,
+		Maximized:      true,
+		FullScreen:     false,
+		ScreenWidth:    1920,
+		Geometry:       cutestream.QRect{X: 0, Y: 0, Width: 1920, Height: 1052},
+	}, g)
+
+	encoded, err := g.Encode()
+	assert.Nil(t, err)
+	assert.Equal(t, data, encoded)
+}
+
+func TestGeometryVersions(t *testing.T) {
+	g := &Geometry{MajorVersion: 1, ScreenWidth: 1920, Geometry: cutestream.QRect{Width: 10, Height: 10}}
+	encoded, err := g.Encode()
+	assert.Nil(t, err)
+	assert.Len(t, encoded, 8+16+16+4+2)
+	decoded, err := DecodeGeometry(encoded)
+	assert.Nil(t, err)
+	assert.Equal(t, &Geometry{MajorVersion: 1}, decoded)
+
+	_, err = DecodeGeometry([]byte{0, 0, 0, 0, 0, 3, 0, 0})
+	assert.NotNil(t, err)
+	_, err = (&Geometry{MajorVersion: 4}).Encode()
+	assert.NotNil(t, err)
+}
@@ -0,0 +1,266 @@
+package widgetstate
+
+import (
+	"bytes"
+	"fmt"
+
+	"github.com/race-engineering-center/cutestream"
+)
+
+// HeaderVersionMarker is the marker starting QHeaderView::saveState() data
+const HeaderVersionMarker = 0xff
+
+// headerVersion is the QDataStream version used to encode header states.
+// QHeaderView uses the default version, but the layout doesn't depend on it
+const headerVersion = cutestream.VersionQt5_0
+
+// SectionItem is a span of header sections sharing size and resize mode
+type SectionItem struct {
+	Size       int32
+	Count      int32 // Number of sections in the span, current Qt versions always write 1
+	ResizeMode int32 // QHeaderView::ResizeMode
+}
+
+// SectionSize is the size of a hidden section
+type SectionSize struct {
+	Section int32
+	Size    int32
+}
+
+// HeaderState is the state saved by QHeaderView::saveState().
+// QTreeView and QTableView have no state of their own and are usually
+// persisted with the state of their header.
+type HeaderState struct {
+	Version              int32
+	Orientation          Orientation
+	SortIndicatorOrder   int32 // Qt::SortOrder
+	SortIndicatorSection int32
+	SortIndicatorShown   bool
+	VisualIndices        []int32
+	LogicalIndices       []int32
+	SectionsHidden       []bool
+	HiddenSectionSize    []SectionSize // QHash<int, int> in the serialization order
+	Length               int32
+	SectionCount         int32
+	MovableSections      bool
+	ClickableSections    bool
+	HighlightSelected    bool
+	StretchLastSection   bool
+	CascadingResizing    bool
+	StretchSections      int32
+	ContentsSections     int32
+	DefaultSectionSize   int32
+	MinimumSectionSize   int32
+	DefaultAlignment     int32 // Qt::Alignment
+	GlobalResizeMode     int32 // QHeaderView::ResizeMode
+	SectionItems         []SectionItem
+
+	// Fields appended in later Qt versions. OptionalFields is the number
+	// of them present in the data: none for early Qt 5 versions, 3 for late Qt 5
+	// and 4 since Qt 6.1
+	OptionalFields           int
+	ResizeContentsPrecision  int32
+	CustomDefaultSectionSize bool
+	LastSectionSize          int32
+	SortIndicatorClearable   bool
+}
+
+// DecodeHeaderState decodes data returned by QHeaderView::saveState()
+func DecodeHeaderState(data []byte) (*HeaderState, error) {
+	buf := bytes.NewReader(data)
+	r, err := cutestream.NewReaderWithVersion(buf, headerVersion)
+	if err != nil {
+		return nil, err
+	}
+	marker, err := r.ReadInt32()
+	if err != nil {
+		return nil, err
+	}
+	if marker != HeaderVersionMarker {
+		return nil, fmt.Errorf("invalid header state marker 0x%x", marker)
+	}
+	var s HeaderState
+	if s.Version, err = r.ReadInt32(); err != nil {
+		return nil, err
+	}
+	if s.Version != 0 {
+		return nil, fmt.Errorf("unsupported header state version %d", s.Version)
+	}
+	orientation, err := r.ReadInt32()
+	if err != nil {
+		return nil, err
+	}
+	s.Orientation = Orientation(orientation)
+	if s.SortIndicatorOrder, err = r.ReadInt32(); err != nil {
+		return nil, err
+	}
+	if s.SortIndicatorSection, err = r.ReadInt32(); err != nil {
+		return nil, err
+	}
+	if s.SortIndicatorShown, err = r.ReadBool(); err != nil {
+		return nil, err
+	}
+	if s.VisualIndices, err = cutestream.ReadList(&r, (*cutestream.Reader).ReadInt32); err != nil {
+		return nil, err
+	}
+	if s.LogicalIndices, err = cutestream.ReadList(&r, (*cutestream.Reader).ReadInt32); err != nil {
+		return nil, err
+	}
+	if s.SectionsHidden, err = r.ReadQBitArray(); err != nil {
+		return nil, err
+	}
+	if s.HiddenSectionSize, err = cutestream.ReadList(&r, readSectionSize); err != nil {
+		return nil, err
+	}
+	for _, v := range []*int32{&s.Length, &s.SectionCount} {
+		if *v, err = r.ReadInt32(); err != nil {
+			return nil, err
+		}
+	}
+	for _, v := range []*bool{&s.MovableSections, &s.ClickableSections, &s.HighlightSelected,
+		&s.StretchLastSection, &s.CascadingResizing} {
+		if *v, err = r.ReadBool(); err != nil {
+			return nil, err
+		}
+	}
+	for _, v := range []*int32{&s.StretchSections, &s.ContentsSections, &s.DefaultSectionSize,
+		&s.MinimumSectionSize, &s.DefaultAlignment, &s.GlobalResizeMode} {
+		if *v, err = r.ReadInt32(); err != nil {
+			return nil, err
+		}
+	}
+	if s.SectionItems, err = cutestream.ReadList(&r, readSectionItem); err != nil {
+		return nil, err
+	}
+
+	if buf.Len() == 0 {
+		return &s, nil
+	}
+	if s.ResizeContentsPrecision, err = r.ReadInt32(); err != nil {
+		return nil, err
+	}
+	if s.CustomDefaultSectionSize, err = r.ReadBool(); err != nil {
+		return nil, err
+	}
+	if s.LastSectionSize, err = r.ReadInt32(); err != nil {
+		return nil, err
+	}
+	s.OptionalFields = 3
+	if buf.Len() == 0 {
+		return &s, nil
+	}
+	clearable, err := r.ReadInt32()
+	if err != nil {
+		return nil, err
+	}
+	s.SortIndicatorClearable = clearable != 0
+	s.OptionalFields = 4
+	return &s, nil
+}
+
+func readSectionSize(r *cutestream.Reader) (SectionSize, error) {
+	section, err := r.ReadInt32()
+	if err != nil {
+		return SectionSize{}, err
+	}
+	size, err := r.ReadInt32()
+	return SectionSize{section, size}, err
+}
+
+func writeSectionSize(w *cutestream.Writer, v SectionSize) error {
+	if err := w.WriteInt32(v.Section); err != nil {
+		return err
+	}
+	return w.WriteInt32(v.Size)
+}
+
+func readSectionItem(r *cutestream.Reader) (SectionItem, error) {
+	var item SectionItem
+	var err error
+	for _, v := range []*int32{&item.Size, &item.Count, &item.ResizeMode} {
+		if *v, err = r.ReadInt32(); err != nil {
+			return SectionItem{}, err
+		}
+	}
+	return item, nil
+}
+
+func writeSectionItem(w *cutestream.Writer, v SectionItem) error {
+	for _, i := range []int32{v.Size, v.Count, v.ResizeMode} {
+		if err := w.WriteInt32(i); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Encode encodes the state in the format of QHeaderView::saveState()
+func (s *HeaderState) Encode() ([]byte, error) {
+	var buf bytes.Buffer
+	w, err := cutestream.NewWriterWithVersion(&buf, headerVersion)
+	if err != nil {
+		return nil, err
+	}
+	for _, v := range []int32{HeaderVersionMarker, s.Version, int32(s.Orientation),
+		s.SortIndicatorOrder, s.SortIndicatorSection} {
+		if err := w.WriteInt32(v); err != nil {
+			return nil, err
+		}
+	}
+	if err := w.WriteBool(s.SortIndicatorShown); err != nil {
+		return nil, err
+	}
+	if err := cutestream.WriteList(&w, s.VisualIndices, (*cutestream.Writer).WriteInt32); err != nil {
+		return nil, err
+	}
+	if err := cutestream.WriteList(&w, s.LogicalIndices, (*cutestream.Writer).WriteInt32); err != nil {
+		return nil, err
+	}
+	if err := w.WriteQBitArray(s.SectionsHidden); err != nil {
+		return nil, err
+	}
+	if err := cutestream.WriteList(&w, s.HiddenSectionSize, writeSectionSize); err != nil {
+		return nil, err
+	}
+	for _, v := range []int32{s.Length, s.SectionCount} {
+		if err := w.WriteInt32(v); err != nil {
+			return nil, err
+		}
+	}
+	for _, v := range []bool{s.MovableSections, s.ClickableSections, s.HighlightSelected,
+		s.StretchLastSection, s.CascadingResizing} {
+		if err := w.WriteBool(v); err != nil {
+			return nil, err
+		}
+	}
+	for _, v := range []int32{s.StretchSections, s.ContentsSections, s.DefaultSectionSize,
+		s.MinimumSectionSize, s.DefaultAlignment, s.GlobalResizeMode} {
+		if err := w.WriteInt32(v); err != nil {
+			return nil, err
+		}
+	}
+	if err := cutestream.WriteList(&w, s.SectionItems, writeSectionItem); err != nil {
+		return nil, err
+	}
+	if s.OptionalFields >= 3 {
+		if err := w.WriteInt32(s.ResizeContentsPrecision); err != nil {
+			return nil, err
+		}
+		if err := w.WriteBool(s.CustomDefaultSectionSize); err != nil {
+			return nil, err
+		}
+		if err := w.WriteInt32(s.LastSectionSize); err != nil {
+			return nil, err
+		}
+	}
+	if s.OptionalFields >= 4 {
+		var clearable int32
+		if s.SortIndicatorClearable {
+			clearable = 1
+		}
+		if err := w.WriteInt32(clearable); err != nil {
+			return nil, err
+		}
+	}
+	return buf.Bytes(), nil
+}
@@ -0,0 +1,90 @@
+package widgetstate
+
+import (
+	"encoding/hex"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// QTreeView::header()->saveState() of a three column view
+// with the second column hidden and sorted by the first one, Qt 5.15
+const headerHex = "000000ff00000000" +
+	"00000001" + // horizontal
+	"00000000" + // ascending
+	"00000000" + // sort section
+	"01" + // sort indicator shown
+	"00000000" + // visual indices
+	"00000000" + // logical indices
+	"0000000302" + // hidden sections
+	"000000010000000100000064" + // hidden section sizes
+	"00000190" + // length
+	"00000003" + // section count
+	"0100010000" + // movable, clickable, highlight selected, stretch last section, cascading resizing
+	"00000000" + // stretch sections
+	"00000000" + // contents sections
+	"00000064" + // default section size
+	"00000015" + // minimum section size
+	"00000081" + // default alignment
+	"00000000" + // interactive resize mode
+	"00000003" +
+	"000000c800000001" + "00000000" +
+	"0000000000000001" + "00000000" +
+	"000000c800000001" + "00000000" +
+	"00000000" + // resize contents precision
+	"00" + // custom default section size
+	"000000c8" // last section size
+
+func TestDecodeHeaderState(t *testing.T) {
+	data, _ := hex.DecodeString(headerHex)
+	s, err := DecodeHeaderState(data)
+	assert.Nil(t, err)
+	assert.Equal(t, &HeaderState{
+		Orientation:        Horizontal,
+		SortIndicatorShown: true,
+		SectionsHidden:     []bool{false, true, false},
+		HiddenSectionSize:  []SectionSize{{Section: 1, Size: 100}},
+		Length:             400,
+		SectionCount:       3,
+		MovableSections:    true,
+		HighlightSelected:  true,
+		DefaultSectionSize: 100,
+		MinimumSectionSize: 21,
+		DefaultAlignment:   0x81,
+		SectionItems: []SectionItem{
+			{Size: 200, Count: 1},
+			{Size: 0, Count: 1},
+			{Size: 200, Count: 1},
+		},
+		OptionalFields:  3,
+		LastSectionSize: 200,
+	}, s)
+
+	encoded, err := s.Encode()
+	assert.Nil(t, err)
+	assert.Equal(t, data, encoded)
+}
+
+func TestHeaderStateOptionalFields(t *testing.T) {
+	data, _ := hex.DecodeString(headerHex)
+	for _, fields := range []int{0, 4} {
+		s, err := DecodeHeaderState(data)
+		assert.Nil(t, err)
+		s.OptionalFields = fields
+		s.SortIndicatorClearable = fields == 4
+		encoded, err := s.Encode()
+		assert.Nil(t, err)
+		decoded, err := DecodeHeaderState(encoded)
+		assert.Nil(t, err)
+		if fields == 0 {
+			s.LastSectionSize = 0
+		}
+		assert.Equal(t, s, decoded)
+	}
+
+	_, err := DecodeHeaderState(data[:len(data)-2])
+	assert.NotNil(t, err)
+	data[3] = 0
+	_, err = DecodeHeaderState(data)
+	assert.NotNil(t, err)
+}
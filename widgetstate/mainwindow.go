@@ -0,0 +1,561 @@
+package widgetstate
+
+import (
+	"bytes"
+	"fmt"
+
+	"github.com/race-engineering-center/cutestream"
+)
+
+// MainWindowVersionMarker is the marker starting QMainWindow::saveState() data
+const MainWindowVersionMarker = 0xff
+
+// mainWindowVersion is the QDataStream version used by QMainWindow::saveState()
+const mainWindowVersion = cutestream.VersionQt5_0
+
+// Section markers, from qdockarealayout_p.h and qtoolbararealayout_p.h
+const (
+	dockWidgetStateMarker       = 0xfd
+	floatingDockWidgetTabMarker = 0xf9
+	toolBarStateMarker          = 0xfe
+	toolBarStateMarkerEx        = 0xfc
+	sequenceMarker              = 0xfc
+	tabMarker                   = 0xfa
+	widgetMarker                = 0xfb
+)
+
+// Dock widget state flags
+const (
+	stateFlagVisible  = 1
+	stateFlagFloating = 2
+)
+
+// DockPosition is a side of the main window, from QInternal::DockPosition
+type DockPosition int32
+
+const (
+	DockLeft   DockPosition = 0
+	DockRight  DockPosition = 1
+	DockTop    DockPosition = 2
+	DockBottom DockPosition = 3
+)
+
+// Orientation is Qt::Orientation
+type Orientation uint8
+
+const (
+	Horizontal Orientation = 1
+	Vertical   Orientation = 2
+)
+
+// MainWindowState is the state saved by QMainWindow::saveState()
+type MainWindowState struct {
+	Version      int32 // Version passed to saveState() by the application
+	Docks        *DockAreaState
+	FloatingTabs []FloatingTabGroup
+	ToolBars     *ToolBarState
+}
+
+// DockAreaState describes dock widgets placed around the central widget
+type DockAreaState struct {
+	Areas             []DockArea
+	CentralWidgetSize cutestream.QSize
+	Corners           [4]int32 // Qt::DockWidgetArea owning top left, top right, bottom left and bottom right corners
+}
+
+// DockArea is a non-empty dock area
+type DockArea struct {
+	Position DockPosition
+	Size     cutestream.QSize
+	Layout   DockLayout
+}
+
+// DockLayout is a sequence of dock items or a tabbed group of them
+type DockLayout struct {
+	Tabbed      bool
+	CurrentTab  int32 // Index of the current tab, only for tabbed layouts
+	Orientation Orientation
+	Items       []DockItem
+}
+
+// DockItem is either a dock widget or a nested layout.
+// Docked items have Pos and Size, floating dock widgets have Geometry
+type DockItem struct {
+	Name     string // Dock widget object name
+	Visible  bool   // Dock widget visibility
+	Floating bool   // Whether the dock widget is floating
+	Geometry cutestream.QRect
+	Pos      int32
+	Size     int32
+	Unused   [2]int32    // Written as zeros by current Qt versions
+	Layout   *DockLayout // Nested layout, nil for dock widgets
+}
+
+// FloatingTabGroup is a floating window with tabbed dock widgets
+type FloatingTabGroup struct {
+	Geometry cutestream.QRect
+	Layout   DockLayout
+}
+
+// ToolBarState describes tool bar placement
+type ToolBarState struct {
+	Extended bool // Whether preferred sizes are stored, true for Qt 4.5 and later
+	Lines    []ToolBarLine
+}
+
+// ToolBarLine is a row or column of tool bars
+type ToolBarLine struct {
+	Position DockPosition
+	ToolBars []ToolBar
+}
+
+// ToolBar is a single tool bar
+type ToolBar struct {
+	Name          string
+	Visible       bool
+	Vertical      bool
+	Pos           int32
+	PreferredSize int32 // -1 unless resized by the user
+	Floating      bool
+	Geometry      cutestream.QRect // Only for floating tool bars
+}
+
+// DecodeMainWindowState decodes data returned by QMainWindow::saveState()
+func DecodeMainWindowState(data []byte) (*MainWindowState, error) {
+	buf := bytes.NewReader(data)
+	r, err := cutestream.NewReaderWithVersion(buf, mainWindowVersion)
+	if err != nil {
+		return nil, err
+	}
+	marker, err := r.ReadInt32()
+	if err != nil {
+		return nil, err
+	}
+	if marker != MainWindowVersionMarker {
+		return nil, fmt.Errorf("invalid main window state marker 0x%x", marker)
+	}
+	var s MainWindowState
+	if s.Version, err = r.ReadInt32(); err != nil {
+		return nil, err
+	}
+	for buf.Len() > 0 {
+		marker, err := r.ReadUint8()
+		if err != nil {
+			return nil, err
+		}
+		switch marker {
+		case dockWidgetStateMarker:
+			if s.Docks, err = readDockAreaState(&r); err != nil {
+				return nil, err
+			}
+		case floatingDockWidgetTabMarker:
+			var group FloatingTabGroup
+			if group.Geometry, err = r.ReadQRect(); err != nil {
+				return nil, err
+			}
+			if err := readDockLayout(&r, &group.Layout); err != nil {
+				return nil, err
+			}
+			s.FloatingTabs = append(s.FloatingTabs, group)
+		case toolBarStateMarker, toolBarStateMarkerEx:
+			if s.ToolBars, err = readToolBarState(&r, marker == toolBarStateMarkerEx); err != nil {
+				return nil, err
+			}
+		default:
+			return nil, fmt.Errorf("unknown main window state marker 0x%x", marker)
+		}
+	}
+	return &s, nil
+}
+
+func readDockAreaState(r *cutestream.Reader) (*DockAreaState, error) {
+	var s DockAreaState
+	n, err := r.ReadInt32()
+	if err != nil {
+		return nil, err
+	}
+	for i := int32(0); i < n; i++ {
+		var area DockArea
+		pos, err := r.ReadInt32()
+		if err != nil {
+			return nil, err
+		}
+		area.Position = DockPosition(pos)
+		if area.Size, err = r.ReadQSize(); err != nil {
+			return nil, err
+		}
+		if err := readDockLayout(r, &area.Layout); err != nil {
+			return nil, err
+		}
+		s.Areas = append(s.Areas, area)
+	}
+	if s.CentralWidgetSize, err = r.ReadQSize(); err != nil {
+		return nil, err
+	}
+	for i := range s.Corners {
+		if s.Corners[i], err = r.ReadInt32(); err != nil {
+			return nil, err
+		}
+	}
+	return &s, nil
+}
+
+func readDockLayout(r *cutestream.Reader, l *DockLayout) error {
+	marker, err := r.ReadUint8()
+	if err != nil {
+		return err
+	}
+	switch marker {
+	case tabMarker:
+		l.Tabbed = true
+		if l.CurrentTab, err = r.ReadInt32(); err != nil {
+			return err
+		}
+	case sequenceMarker:
+	default:
+		return fmt.Errorf("invalid dock layout marker 0x%x", marker)
+	}
+	orientation, err := r.ReadUint8()
+	if err != nil {
+		return err
+	}
+	l.Orientation = Orientation(orientation)
+	n, err := r.ReadInt32()
+	if err != nil {
+		return err
+	}
+	for i := int32(0); i < n; i++ {
+		var item DockItem
+		marker, err := r.ReadUint8()
+		if err != nil {
+			return err
+		}
+		switch marker {
+		case widgetMarker:
+			if item.Name, err = r.ReadQString(); err != nil {
+				return err
+			}
+			flags, err := r.ReadUint8()
+			if err != nil {
+				return err
+			}
+			item.Visible = flags&stateFlagVisible != 0
+			item.Floating = flags&stateFlagFloating != 0
+			if item.Floating {
+				if item.Geometry, err = readXYWH(r); err != nil {
+					return err
+				}
+			} else if err := readDockItemPlacement(r, &item); err != nil {
+				return err
+			}
+		case sequenceMarker:
+			if err := readDockItemPlacement(r, &item); err != nil {
+				return err
+			}
+			item.Layout = &DockLayout{}
+			if err := readDockLayout(r, item.Layout); err != nil {
+				return err
+			}
+		default:
+			return fmt.Errorf("invalid dock item marker 0x%x", marker)
+		}
+		l.Items = append(l.Items, item)
+	}
+	return nil
+}
+
+func readDockItemPlacement(r *cutestream.Reader, item *DockItem) (err error) {
+	if item.Pos, err = r.ReadInt32(); err != nil {
+		return err
+	}
+	if item.Size, err = r.ReadInt32(); err != nil {
+		return err
+	}
+	for i := range item.Unused {
+		if item.Unused[i], err = r.ReadInt32(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// readXYWH reads a rectangle written as x, y, width and height, unlike QRect serialization
+func readXYWH(r *cutestream.Reader) (cutestream.QRect, error) {
+	var v [4]int32
+	for i := range v {
+		var err error
+		if v[i], err = r.ReadInt32(); err != nil {
+			return cutestream.QRect{}, err
+		}
+	}
+	return cutestream.QRect{X: v[0], Y: v[1], Width: v[2], Height: v[3]}, nil
+}
+
+func readToolBarState(r *cutestream.Reader, extended bool) (*ToolBarState, error) {
+	s := ToolBarState{Extended: extended}
+	lines, err := r.ReadInt32()
+	if err != nil {
+		return nil, err
+	}
+	for i := int32(0); i < lines; i++ {
+		var line ToolBarLine
+		pos, err := r.ReadInt32()
+		if err != nil {
+			return nil, err
+		}
+		line.Position = DockPosition(pos)
+		n, err := r.ReadInt32()
+		if err != nil {
+			return nil, err
+		}
+		for j := int32(0); j < n; j++ {
+			var tb ToolBar
+			if tb.Name, err = r.ReadQString(); err != nil {
+				return nil, err
+			}
+			shown, err := r.ReadUint8()
+			if err != nil {
+				return nil, err
+			}
+			tb.Visible = shown&1 != 0
+			tb.Vertical = shown&2 != 0
+			if tb.Pos, err = r.ReadInt32(); err != nil {
+				return nil, err
+			}
+			tb.PreferredSize = -1
+			if extended {
+				if tb.PreferredSize, err = r.ReadInt32(); err != nil {
+					return nil, err
+				}
+			}
+			geom0, err := r.ReadUint32()
+			if err != nil {
+				return nil, err
+			}
+			geom1, err := r.ReadUint32()
+			if err != nil {
+				return nil, err
+			}
+			tb.Geometry, tb.Floating = unpackRect(geom0, geom1)
+			line.ToolBars = append(line.ToolBars, tb)
+		}
+		s.Lines = append(s.Lines, line)
+	}
+	return &s, nil
+}
+
+// unpackRect and packRect are ported from qtoolbararealayout.cpp
+func unpackRect(geom0, geom1 uint32) (cutestream.QRect, bool) {
+	if geom0&1 == 0 {
+		return cutestream.QRect{}, false
+	}
+	geom0 >>= 1
+	x := int32(geom0&0xffff) - 0x7fff
+	y := int32(geom1&0xffff) - 0x7fff
+	geom0 >>= 16
+	geom1 >>= 16
+	return cutestream.QRect{X: x, Y: y, Width: int32(geom0 & 0xffff), Height: int32(geom1 & 0xffff)}, true
+}
+
+func packRect(rect cutestream.QRect, floating bool) (uint32, uint32) {
+	if !floating {
+		return 0, 0
+	}
+	geom0 := uint32(rect.Width) & 0xffff
+	geom1 := uint32(rect.Height) & 0xffff
+	geom0 <<= 16
+	geom1 <<= 16
+	geom0 |= uint32(rect.X+0x7fff) & 0xffff
+	geom1 |= uint32(rect.Y+0x7fff) & 0xffff
+	geom0 <<= 1
+	geom0 |= 1
+	return geom0, geom1
+}
+
+// Encode encodes the state in the format of QMainWindow::saveState()
+func (s *MainWindowState) Encode() ([]byte, error) {
+	var buf bytes.Buffer
+	w, err := cutestream.NewWriterWithVersion(&buf, mainWindowVersion)
+	if err != nil {
+		return nil, err
+	}
+	if err := w.WriteInt32(MainWindowVersionMarker); err != nil {
+		return nil, err
+	}
+	if err := w.WriteInt32(s.Version); err != nil {
+		return nil, err
+	}
+	if s.Docks != nil {
+		if err := w.WriteUint8(dockWidgetStateMarker); err != nil {
+			return nil, err
+		}
+		if err := writeDockAreaState(&w, s.Docks); err != nil {
+			return nil, err
+		}
+	}
+	for _, group := range s.FloatingTabs {
+		if err := w.WriteUint8(floatingDockWidgetTabMarker); err != nil {
+			return nil, err
+		}
+		if err := w.WriteQRect(group.Geometry); err != nil {
+			return nil, err
+		}
+		if err := writeDockLayout(&w, &group.Layout); err != nil {
+			return nil, err
+		}
+	}
+	if s.ToolBars != nil {
+		if err := writeToolBarState(&w, s.ToolBars); err != nil {
+			return nil, err
+		}
+	}
+	return buf.Bytes(), nil
+}
+
+func writeDockAreaState(w *cutestream.Writer, s *DockAreaState) error {
+	if err := w.WriteInt32(int32(len(s.Areas))); err != nil {
+		return err
+	}
+	for i := range s.Areas {
+		area := &s.Areas[i]
+		if err := w.WriteInt32(int32(area.Position)); err != nil {
+			return err
+		}
+		if err := w.WriteQSize(area.Size); err != nil {
+			return err
+		}
+		if err := writeDockLayout(w, &area.Layout); err != nil {
+			return err
+		}
+	}
+	if err := w.WriteQSize(s.CentralWidgetSize); err != nil {
+		return err
+	}
+	for _, corner := range s.Corners {
+		if err := w.WriteInt32(corner); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func writeDockLayout(w *cutestream.Writer, l *DockLayout) error {
+	if l.Tabbed {
+		if err := w.WriteUint8(tabMarker); err != nil {
+			return err
+		}
+		if err := w.WriteInt32(l.CurrentTab); err != nil {
+			return err
+		}
+	} else if err := w.WriteUint8(sequenceMarker); err != nil {
+		return err
+	}
+	if err := w.WriteUint8(uint8(l.Orientation)); err != nil {
+		return err
+	}
+	if err := w.WriteInt32(int32(len(l.Items))); err != nil {
+		return err
+	}
+	for i := range l.Items {
+		item := &l.Items[i]
+		if item.Layout != nil {
+			if err := w.WriteUint8(sequenceMarker); err != nil {
+				return err
+			}
+			if err := writeDockItemPlacement(w, item); err != nil {
+				return err
+			}
+			if err := writeDockLayout(w, item.Layout); err != nil {
+				return err
+			}
+			continue
+		}
+		if err := w.WriteUint8(widgetMarker); err != nil {
+			return err
+		}
+		if err := w.WriteQString(item.Name); err != nil {
+			return err
+		}
+		var flags uint8
+		if item.Visible {
+			flags |= stateFlagVisible
+		}
+		if item.Floating {
+			flags |= stateFlagFloating
+		}
+		if err := w.WriteUint8(flags); err != nil {
+			return err
+		}
+		if item.Floating {
+			for _, v := range []int32{item.Geometry.X, item.Geometry.Y, item.Geometry.Width, item.Geometry.Height} {
+				if err := w.WriteInt32(v); err != nil {
+					return err
+				}
+			}
+		} else if err := writeDockItemPlacement(w, item); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func writeDockItemPlacement(w *cutestream.Writer, item *DockItem) error {
+	for _, v := range []int32{item.Pos, item.Size, item.Unused[0], item.Unused[1]} {
+		if err := w.WriteInt32(v); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func writeToolBarState(w *cutestream.Writer, s *ToolBarState) error {
+	marker := uint8(toolBarStateMarker)
+	if s.Extended {
+		marker = toolBarStateMarkerEx
+	}
+	if err := w.WriteUint8(marker); err != nil {
+		return err
+	}
+	if err := w.WriteInt32(int32(len(s.Lines))); err != nil {
+		return err
+	}
+	for _, line := range s.Lines {
+		if err := w.WriteInt32(int32(line.Position)); err != nil {
+			return err
+		}
+		if err := w.WriteInt32(int32(len(line.ToolBars))); err != nil {
+			return err
+		}
+		for _, tb := range line.ToolBars {
+			if err := w.WriteQString(tb.Name); err != nil {
+				return err
+			}
+			var shown uint8
+			if tb.Visible {
+				shown |= 1
+			}
+			if tb.Vertical {
+				shown |= 2
+			}
+			if err := w.WriteUint8(shown); err != nil {
+				return err
+			}
+			if err := w.WriteInt32(tb.Pos); err != nil {
+				return err
+			}
+			if s.Extended {
+				if err := w.WriteInt32(tb.PreferredSize); err != nil {
+					return err
+				}
+			}
+			geom0, geom1 := packRect(tb.Geometry, tb.Floating)
+			if err := w.WriteUint32(geom0); err != nil {
+				return err
+			}
+			if err := w.WriteUint32(geom1); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
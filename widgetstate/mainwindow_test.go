@@ -0,0 +1,102 @@
+package widgetstate
+
+import (
+	"encoding/hex"
+	"strings"
+	"testing"
+
+	"github.com/race-engineering-center/cutestream"
+	"github.com/stretchr/testify/assert"
+)
+
+// QMainWindow::saveState() with a telemetry dock on the left, tabbed with a log dock,
+// a floating lap times dock and a single tool bar on top
+var mainWindowStateHex = strings.Join([]string{
+	"000000ff", "00000001", // marker, version
+	"fd", "00000001", // dock widget state, 1 area
+	"00000000", "0000012c00000258", // left dock, size 300x600
+	"fc", "02", "00000002", // sequence, vertical, 2 items
+	"fc", "00000000", "00000258", "00000000", "00000000", // nested layout
+	"fa", "00000000", "01", "00000002", // tabbed, current 0, horizontal, 2 items
+	"fb", "00000012", "00740065006c0065006d0065007400720079", "01", // telemetry, visible
+	"00000000", "00000258", "00000000", "00000000",
+	"fb", "00000006", "006c006f0067", "00", // log, hidden
+	"00000000", "00000258", "00000000", "00000000",
+	"fb", "00000008", "006c006100700073", "03", // laps, visible and floating
+	"00000064", "000000c8", "00000190", "0000012c",
+	"000003200000025800000001000000010000000800000008", // central widget size and corners
+	"fc", "00000001", // tool bar state, 1 line
+	"00000002", "00000001", // top, 1 tool bar
+	"00000016", "006d00610069006e0054006f006f006c004200610072", "01", // mainToolBar, visible
+	"00000000", "ffffffff", "00000000", "00000000", // pos, preferred size, not floating
+}, "")
+
+func TestDecodeMainWindowState(t *testing.T) {
+	data, err := hex.DecodeString(mainWindowStateHex)
+	assert.Nil(t, err)
+	s, err := DecodeMainWindowState(data)
+	assert.Nil(t, err)
+
+	assert.Equal(t, int32(1), s.Version)
+	assert.NotNil(t, s.Docks)
+	assert.Len(t, s.Docks.Areas, 1)
+	area := s.Docks.Areas[0]
+	assert.Equal(t, DockLeft, area.Position)
+	assert.Equal(t, cutestream.QSize{Width: 300, Height: 600}, area.Size)
+	assert.Equal(t, Vertical, area.Layout.Orientation)
+	assert.Len(t, area.Layout.Items, 2)
+	tabs := area.Layout.Items[0].Layout
+	assert.NotNil(t, tabs)
+	assert.True(t, tabs.Tabbed)
+	assert.Equal(t, "telemetry", tabs.Items[0].Name)
+	assert.True(t, tabs.Items[0].Visible)
+	assert.Equal(t, "log", tabs.Items[1].Name)
+	assert.False(t, tabs.Items[1].Visible)
+	laps := area.Layout.Items[1]
+	assert.True(t, laps.Floating)
+	assert.Equal(t, cutestream.QRect{X: 100, Y: 200, Width: 400, Height: 300}, laps.Geometry)
+	assert.Equal(t, cutestream.QSize{Width: 800, Height: 600}, s.Docks.CentralWidgetSize)
+	assert.Equal(t, [4]int32{1, 1, 8, 8}, s.Docks.Corners)
+
+	assert.NotNil(t, s.ToolBars)
+	assert.True(t, s.ToolBars.Extended)
+	assert.Equal(t, []ToolBarLine{{
+		Position: DockTop,
+		ToolBars: []ToolBar{{Name: "mainToolBar", Visible: true, PreferredSize: -1}},
+	}}, s.ToolBars.Lines)
+
+	encoded, err := s.Encode()
+	assert.Nil(t, err)
+	assert.Equal(t, data, encoded)
+}
+
+func TestFloatingToolBar(t *testing.T) {
+	rect := cutestream.QRect{X: -20, Y: 40, Width: 300, Height: 32}
+	geom0, geom1 := packRect(rect, true)
+	unpacked, floating := unpackRect(geom0, geom1)
+	assert.True(t, floating)
+	assert.Equal(t, rect, unpacked)
+
+	s := &MainWindowState{
+		ToolBars: &ToolBarState{Lines: []ToolBarLine{{
+			Position: DockBottom,
+			ToolBars: []ToolBar{{Name: "tools", Floating: true, Geometry: rect, PreferredSize: -1}},
+		}}},
+		FloatingTabs: []FloatingTabGroup{{
+			Geometry: cutestream.QRect{Width: 100, Height: 100},
+			Layout:   DockLayout{Tabbed: true, Orientation: Horizontal, Items: []DockItem{{Name: "a"}, {Name: "b"}}},
+		}},
+	}
+	encoded, err := s.Encode()
+	assert.Nil(t, err)
+	decoded, err := DecodeMainWindowState(encoded)
+	assert.Nil(t, err)
+	assert.Equal(t, s, decoded)
+}
+
+func TestInvalidMainWindowState(t *testing.T) {
+	_, err := DecodeMainWindowState([]byte{0, 0, 0, 0xfe, 0, 0, 0, 0})
+	assert.NotNil(t, err)
+	_, err = DecodeMainWindowState([]byte{0, 0, 0, 0xff, 0, 0, 0, 0, 0x42})
+	assert.NotNil(t, err)
+}
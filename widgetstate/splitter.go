@@ -0,0 +1,109 @@
+package widgetstate
+
+import (
+	"bytes"
+	"fmt"
+
+	"github.com/race-engineering-center/cutestream"
+)
+
+// SplitterMagic is the marker starting QSplitter::saveState() data
+const SplitterMagic = 0xff
+
+// splitterVersion is the QDataStream version used by QSplitter::saveState()
+const splitterVersion = cutestream.VersionQt5_0
+
+// SplitterState is the state saved by QSplitter::saveState()
+type SplitterState struct {
+	Version             int32 // 0 or 1, version 1 adds OpaqueResizeSet
+	Sizes               []int32
+	ChildrenCollapsible bool
+	HandleWidth         int32
+	OpaqueResize        bool
+	Orientation         Orientation
+	OpaqueResizeSet     bool // Whether opaque resize was set explicitly
+}
+
+// DecodeSplitterState decodes data returned by QSplitter::saveState()
+func DecodeSplitterState(data []byte) (*SplitterState, error) {
+	r, err := cutestream.NewReaderWithVersion(bytes.NewReader(data), splitterVersion)
+	if err != nil {
+		return nil, err
+	}
+	marker, err := r.ReadInt32()
+	if err != nil {
+		return nil, err
+	}
+	if marker != SplitterMagic {
+		return nil, fmt.Errorf("invalid splitter state marker 0x%x", marker)
+	}
+	var s SplitterState
+	if s.Version, err = r.ReadInt32(); err != nil {
+		return nil, err
+	}
+	if s.Version < 0 || s.Version > 1 {
+		return nil, fmt.Errorf("unsupported splitter state version %d", s.Version)
+	}
+	if s.Sizes, err = cutestream.ReadList(&r, (*cutestream.Reader).ReadInt32); err != nil {
+		return nil, err
+	}
+	if s.ChildrenCollapsible, err = r.ReadBool(); err != nil {
+		return nil, err
+	}
+	if s.HandleWidth, err = r.ReadInt32(); err != nil {
+		return nil, err
+	}
+	if s.OpaqueResize, err = r.ReadBool(); err != nil {
+		return nil, err
+	}
+	orientation, err := r.ReadInt32()
+	if err != nil {
+		return nil, err
+	}
+	s.Orientation = Orientation(orientation)
+	if s.Version >= 1 {
+		if s.OpaqueResizeSet, err = r.ReadBool(); err != nil {
+			return nil, err
+		}
+	}
+	return &s, nil
+}
+
+// Encode encodes the state in the format of QSplitter::saveState()
+func (s *SplitterState) Encode() ([]byte, error) {
+	if s.Version < 0 || s.Version > 1 {
+		return nil, fmt.Errorf("unsupported splitter state version %d", s.Version)
+	}
+	var buf bytes.Buffer
+	w, err := cutestream.NewWriterWithVersion(&buf, splitterVersion)
+	if err != nil {
+		return nil, err
+	}
+	if err := w.WriteInt32(SplitterMagic); err != nil {
+		return nil, err
+	}
+	if err := w.WriteInt32(s.Version); err != nil {
+		return nil, err
+	}
+	if err := cutestream.WriteList(&w, s.Sizes, (*cutestream.Writer).WriteInt32); err != nil {
+		return nil, err
+	}
+	if err := w.WriteBool(s.ChildrenCollapsible); err != nil {
+		return nil, err
+	}
+	if err := w.WriteInt32(s.HandleWidth); err != nil {
+		return nil, err
+	}
+	if err := w.WriteBool(s.OpaqueResize); err != nil {
+		return nil, err
+	}
+	if err := w.WriteInt32(int32(s.Orientation)); err != nil {
+		return nil, err
+	}
+	if s.Version >= 1 {
+		if err := w.WriteBool(s.OpaqueResizeSet); err != nil {
+			return nil, err
+		}
+	}
+	return buf.Bytes(), nil
+}
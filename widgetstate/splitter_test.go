@@ -0,0 +1,56 @@
+package widgetstate
+
+import (
+	"encoding/hex"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// QSplitter::saveState() of a horizontal splitter with two widgets, Qt 5.15
+const splitterHex = "000000ff00000001" +
+	"000000020000012c0000028a" + // sizes
+	"01" + // children collapsible
+	"ffffffff" + // default handle width
+	"01" + // opaque resize
+	"00000001" + // horizontal
+	"00" // opaque resize not set
+
+func TestDecodeSplitterState(t *testing.T) {
+	data, _ := hex.DecodeString(splitterHex)
+	s, err := DecodeSplitterState(data)
+	assert.Nil(t, err)
+	assert.Equal(t, &SplitterState{
+		Version:             1,
+		Sizes:               []int32{300, 650},
+		ChildrenCollapsible: true,
+		HandleWidth:         -1,
+		OpaqueResize:        true,
+		Orientation:         Horizontal,
+	}, s)
+
+	encoded, err := s.Encode()
+	assert.Nil(t, err)
+	assert.Equal(t, data, encoded)
+}
+
+func TestSplitterStateErrors(t *testing.T) {
+	data, _ := hex.DecodeString(splitterHex)
+	_, err := DecodeSplitterState(data[:len(data)-1])
+	assert.NotNil(t, err)
+
+	data[3] = 0xfe
+	_, err = DecodeSplitterState(data)
+	assert.NotNil(t, err)
+
+	s := &SplitterState{Version: 2}
+	_, err = s.Encode()
+	assert.NotNil(t, err)
+
+	s = &SplitterState{Version: 0, Sizes: []int32{1}}
+	encoded, err := s.Encode()
+	assert.Nil(t, err)
+	decoded, err := DecodeSplitterState(encoded)
+	assert.Nil(t, err)
+	assert.Equal(t, s, decoded)
+}
@@ -0,0 +1,716 @@
+package cutestream
+
+import (
+	"encoding/binary"
+	"fmt"
+	"image"
+	"io"
+	"net/url"
+	"sort"
+	"time"
+	"unicode/utf16"
+)
+
+// Variant is a value paired with its metatype.
+// Values of this type can be put into containers passed to Writer
+// to write them with a specific metatype instead of a deduced one.
+type Variant struct {
+	Type  QMetaType
+	Value interface{}
+}
+
+type Writer struct {
+	Writer          io.Writer
+	ByteOrder       binary.ByteOrder
+	version         int
+	DoublePrecision bool // Use Double precision for floats. Set to `false` to use Single precision
+}
+
+// NewWriter creates a new Writer object with the specified underlying writer,
+// big endian byte order and disabled double precision
+func NewWriter(writer io.Writer) Writer {
+	return Writer{
+		Writer:          writer,
+		ByteOrder:       binary.BigEndian,
+		version:         19,
+		DoublePrecision: false,
+	}
+}
+
+func NewWriterWithVersion(writer io.Writer, version int) (Writer, error) {
+	w := NewWriter(writer)
+	err := w.SetVersion(version)
+	if err != nil {
+		return Writer{}, err
+	}
+	return w, nil
+}
+
+func (w *Writer) SetVersion(version int) error {
+	if err := checkVersion(version); err != nil {
+		return err
+	}
+	w.version = version
+	return nil
+}
+
+// Version returns the QDataStream version used by the writer
+func (w *Writer) Version() int {
+	return w.version
+}
+
+func (w *Writer) WriteBool(v bool) error {
+	var b uint8
+	if v {
+		b = 1
+	}
+	return binary.Write(w.Writer, w.ByteOrder, b)
+}
+
+func WriteNumber[T int8 | int16 | int32 | int64 | uint8 | uint16 | uint32 | uint64 | float32 | float64](writer *Writer, v T) error {
+	return binary.Write(writer.Writer, writer.ByteOrder, v)
+}
+
+func (w *Writer) WriteInt8(v int8) error {
+	return WriteNumber(w, v)
+}
+
+func (w *Writer) WriteInt16(v int16) error {
+	return WriteNumber(w, v)
+}
+
+func (w *Writer) WriteInt32(v int32) error {
+	return WriteNumber(w, v)
+}
+
+func (w *Writer) WriteInt64(v int64) error {
+	return WriteNumber(w, v)
+}
+
+func (w *Writer) WriteUint8(v uint8) error {
+	return WriteNumber(w, v)
+}
+
+func (w *Writer) WriteUint16(v uint16) error {
+	return WriteNumber(w, v)
+}
+
+func (w *Writer) WriteUint32(v uint32) error {
+	return WriteNumber(w, v)
+}
+
+func (w *Writer) WriteUint64(v uint64) error {
+	return WriteNumber(w, v)
+}
+
+// WriteFloat writes a float. Starting with Qt 4.6 the size depends on the precision
+func (w *Writer) WriteFloat(v float32) error {
+	if w.DoublePrecision && w.version >= VersionQt4_6 {
+		return WriteNumber(w, float64(v))
+	}
+	return WriteNumber(w, v)
+}
+
+// WriteDouble writes a double. Starting with Qt 4.6 the size depends on the precision
+func (w *Writer) WriteDouble(v float64) error {
+	if !w.DoublePrecision && w.version >= VersionQt4_6 {
+		return WriteNumber(w, float32(v))
+	}
+	return WriteNumber(w, v)
+}
+
+func (w *Writer) writeRaw(buf []byte) error {
+	_, err := w.Writer.Write(buf)
+	return err
+}
+
+func (w *Writer) WriteCString(v string) error {
+	if err := w.WriteUint32(uint32(len(v))); err != nil {
+		return err
+	}
+	return w.writeRaw([]byte(v))
+}
+
+// WriteQBitArray writes a bit array. The number of bits is a quint32 before Qt 6 and a quint64 in Qt 6 streams
+func (w *Writer) WriteQBitArray(bits []bool) error {
+	if w.version < VersionQt6_0 {
+		if err := w.WriteUint32(uint32(len(bits))); err != nil {
+			return err
+		}
+	} else if err := w.WriteUint64(uint64(len(bits))); err != nil {
+		return err
+	}
+	buf := make([]byte, (len(bits)+7)/8)
+	for i, b := range bits {
+		if b {
+			buf[i/8] |= 1 << (i % 8)
+		}
+	}
+	return w.writeRaw(buf)
+}
+
+// WriteQByteArray writes a byte array. A nil slice is written as a null QByteArray
+func (w *Writer) WriteQByteArray(v []byte) error {
+	if v == nil {
+		return w.WriteUint32(0xFFFFFFFF)
+	}
+	if err := w.WriteUint32(uint32(len(v))); err != nil {
+		return err
+	}
+	return w.writeRaw(v)
+}
+
+func (w *Writer) WriteQDate(v time.Time) error {
+	// ported from qdatetime.cpp
+	floordiv := func(a, b int64) int64 {
+		var x int64
+		if a < 0 {
+			x = b - 1
+		}
+		return (a - x) / b
+	}
+	year, month, day := int64(v.Year()), int64(v.Month()), int64(v.Day())
+	if year < 0 {
+		year++
+	}
+	a := floordiv(14-month, 12)
+	y := year + 4800 - a
+	m := month + 12*a - 3
+	julian := day + floordiv(153*m+2, 5) + 365*y + floordiv(y, 4) - floordiv(y, 100) + floordiv(y, 400) - 32045
+	if w.version < VersionQt5_0 {
+		return w.WriteUint32(uint32(julian))
+	}
+	return w.WriteUint64(uint64(julian))
+}
+
+func (w *Writer) WriteQString(v string) error {
+	buf := utf16.Encode([]rune(v))
+	if err := w.WriteUint32(uint32(len(buf) * 2)); err != nil {
+		return err
+	}
+	return binary.Write(w.Writer, w.ByteOrder, buf)
+}
+
+// WriteNullQString writes a string, or a null string if the string is not valid
+func (w *Writer) WriteNullQString(v NullQString) error {
+	if !v.Valid {
+		return w.WriteUint32(0xFFFFFFFF)
+	}
+	return w.WriteQString(v.String)
+}
+
+func (w *Writer) WriteQTime(v time.Duration) error { // msecs past midnight
+	return w.WriteUint32(uint32(v / time.Millisecond))
+}
+
+// WriteQUrl writes a URL in its encoded form. A nil URL is written as a null byte array
+// like an empty QUrl, an empty non-nil URL as an empty byte array
+func (w *Writer) WriteQUrl(v *url.URL) error {
+	if v == nil {
+		return w.WriteQByteArray(nil)
+	}
+	return w.WriteQByteArray([]byte(v.String()))
+}
+
+// WriteQDateTime writes a date and time. UTC and local times are written
+// with the corresponding time spec, locations of the time zone database with their QTimeZone,
+// other locations are written as an offset from UTC.
+// Before Qt 5.2 offsets can't be serialized, so such times are converted to UTC
+func (w *Writer) WriteQDateTime(v time.Time) error {
+	if w.version < VersionQt5_2 {
+		return w.writeLegacyQDateTime(v)
+	}
+	if err := w.WriteQDate(v); err != nil {
+		return err
+	}
+	midnight := time.Date(v.Year(), v.Month(), v.Day(), 0, 0, 0, 0, v.Location())
+	if err := w.WriteQTime(v.Sub(midnight)); err != nil {
+		return err
+	}
+	switch v.Location() {
+	case time.UTC:
+		return w.WriteUint8(timeSpecUTC)
+	case time.Local:
+		return w.WriteUint8(timeSpecLocalTime)
+	}
+	if name := v.Location().String(); name != "" {
+		if _, err := time.LoadLocation(name); err == nil {
+			if err := w.WriteUint8(timeSpecTimeZone); err != nil {
+				return err
+			}
+			return w.WriteQTimeZone(QTimeZone{ID: name})
+		}
+	}
+	if err := w.WriteUint8(timeSpecOffsetFromUTC); err != nil {
+		return err
+	}
+	_, offset := v.Zone()
+	return w.WriteInt32(int32(offset))
+}
+
+func (w *Writer) writeLegacyQDateTime(v time.Time) error {
+	local := v.Location() == time.Local
+	if !local || w.version == VersionQt5_0 {
+		v = v.UTC()
+	}
+	if err := w.WriteQDate(v); err != nil {
+		return err
+	}
+	midnight := time.Date(v.Year(), v.Month(), v.Day(), 0, 0, 0, 0, v.Location())
+	if err := w.WriteQTime(v.Sub(midnight)); err != nil {
+		return err
+	}
+	switch {
+	case w.version == VersionQt5_0 && local:
+		return w.WriteUint8(timeSpecLocalTime)
+	case w.version == VersionQt5_0:
+		return w.WriteUint8(timeSpecUTC)
+	case local:
+		return w.WriteUint8(legacySpecLocalUnknown)
+	default:
+		return w.WriteUint8(legacySpecUTC)
+	}
+}
+
+func (w *Writer) WriteQUuid(v QUuid) error {
+	v = v.reorder(binary.BigEndian, w.ByteOrder)
+	return w.writeRaw(v[:])
+}
+
+// WriteQVariantHeader writes the metatype and the null flag of a QVariant,
+// which must be followed by its value
+func (w *Writer) WriteQVariantHeader(h VariantHeader) error {
+	id := uint32(h.Type)
+	switch {
+	case w.version < VersionQt5_0:
+		id = metaTypeToQt4(h.Type)
+	case w.version < VersionQt6_0:
+		id = metaTypeToQt5(h.Type)
+	default:
+		id = metaTypeToQt6(h.Type)
+	}
+	if err := w.WriteUint32(id); err != nil {
+		return err
+	}
+	if w.version >= VersionQt4_2 {
+		if err := w.WriteBool(h.Null); err != nil {
+			return err
+		}
+	}
+	if h.Type == QMetaTypeUser {
+		return w.WriteCString(h.Name + "\x00")
+	}
+	return nil
+}
+
+// WriteQVariant writes a value wrapped into a QVariant with the specified metatype.
+// A nil value is written as a null variant. Values of QMetaTypeUser are written
+// with the type name matching their Go type and can't be nil
+func (w *Writer) WriteQVariant(t QMetaType, v interface{}) error {
+	h := VariantHeader{Type: t, Null: v == nil}
+	if t == QMetaTypeUser {
+		var err error
+		if h.Name, err = userTypeName(v); err != nil {
+			return err
+		}
+	}
+	if err := w.WriteQVariantHeader(h); err != nil {
+		return err
+	}
+	return w.WriteValue(t, v)
+}
+
+// WriteVariant writes a QVariant with the metatype and the value of the specified Variant
+func (w *Writer) WriteVariant(v Variant) error {
+	return w.WriteQVariant(v.Type, v.Value)
+}
+
+// WriteValue writes a bare value of the specified metatype, i.e. a value
+// that is not wrapped into a QVariant. The Go type of the value must match
+// the one returned by Reader.ReadValue for the same metatype.
+// A nil value is written as the default value of the metatype
+func (w *Writer) WriteValue(t QMetaType, v interface{}) error {
+	switch t {
+	case QMetaTypeUnknown:
+		return nil
+	case QMetaTypeBool:
+		return writeAs(t, v, w.WriteBool)
+	case QMetaTypeInt:
+		return writeAs(t, v, w.WriteInt32)
+	case QMetaTypeUInt:
+		return writeAs(t, v, w.WriteUint32)
+	case QMetaTypeLongLong:
+		return writeAs(t, v, w.WriteInt64)
+	case QMetaTypeULongLong:
+		return writeAs(t, v, w.WriteUint64)
+	case QMetaTypeDouble:
+		return writeAs(t, v, w.WriteDouble)
+	case QMetaTypeFloat:
+		return writeAs(t, v, w.WriteFloat)
+	case QMetaTypeQChar, QMetaTypeChar16:
+		return writeAs(t, v, w.WriteQChar)
+	case QMetaTypeChar32:
+		return writeAs(t, v, w.WriteInt32)
+	case QMetaTypeChar, QMetaTypeUChar:
+		return writeAs(t, v, w.WriteUint8)
+	case QMetaTypeNullptr:
+		return nil
+	case QMetaTypeQByteArrayList:
+		return writeAs(t, v, w.WriteQByteArrayList)
+	case QMetaTypeSChar:
+		return writeAs(t, v, w.WriteInt8)
+	case QMetaTypeShort:
+		return writeAs(t, v, w.WriteInt16)
+	case QMetaTypeUShort:
+		return writeAs(t, v, w.WriteUint16)
+	case QMetaTypeQBitArray:
+		return writeAs(t, v, w.WriteQBitArray)
+	case QMetaTypeQVariantMap, QMetaTypeQVariantHash:
+		switch ordered := v.(type) {
+		case OrderedMap:
+			return w.WriteQStringQVariantOrderedMap(ordered)
+		case OrderedHash:
+			return w.WriteQStringQVariantOrderedMap(OrderedMap(ordered))
+		}
+		return writeAs(t, v, w.WriteQStringQVariantAssociative)
+	case QMetaTypeQUuid:
+		return writeAs(t, v, w.WriteQUuid)
+	case QMetaTypeQVariantList:
+		return writeAs(t, v, w.WriteQStringQVariantList)
+	case QMetaTypeQVariant:
+		// a QVariant field, deduced metatypes are used for values other than Variant
+		return w.writeContainedQVariant(v)
+	case QMetaTypeQByteArray:
+		return writeAs(t, v, w.WriteQByteArray)
+	case QMetaTypeQString:
+		switch s := v.(type) {
+		case nil:
+			// the default QString is null
+			return w.WriteNullQString(NullQString{})
+		case NullQString:
+			return w.WriteNullQString(s)
+		}
+		return writeAs(t, v, w.WriteQString)
+	case QMetaTypeQStringList:
+		return writeAs(t, v, w.WriteQStringQStringList)
+	case QMetaTypeQDate:
+		return writeAs(t, v, w.WriteQDate)
+	case QMetaTypeQTime:
+		return writeAs(t, v, w.WriteQTime)
+	case QMetaTypeQDateTime:
+		return writeAs(t, v, w.WriteQDateTime)
+	case QMetaTypeQUrl:
+		return writeAs(t, v, w.WriteQUrl)
+	case QMetaTypeQPoint:
+		return writeAs(t, v, w.WriteQPoint)
+	case QMetaTypeQPointF:
+		return writeAs(t, v, w.WriteQPointF)
+	case QMetaTypeQSize:
+		return writeAs(t, v, w.WriteQSize)
+	case QMetaTypeQSizeF:
+		return writeAs(t, v, w.WriteQSizeF)
+	case QMetaTypeQRect:
+		return writeAs(t, v, w.WriteQRect)
+	case QMetaTypeQRectF:
+		return writeAs(t, v, w.WriteQRectF)
+	case QMetaTypeQLine:
+		return writeAs(t, v, w.WriteQLine)
+	case QMetaTypeQLineF:
+		return writeAs(t, v, w.WriteQLineF)
+	case QMetaTypeQFont:
+		return writeAs(t, v, w.WriteQFont)
+	case QMetaTypeQBrush:
+		if v == nil {
+			return w.WriteQBrush(QBrush{Transform: IdentityTransform()})
+		}
+		return writeAs(t, v, w.WriteQBrush)
+	case QMetaTypeQColor:
+		return writeAs(t, v, w.WriteQColor)
+	case QMetaTypeQPen:
+		return writeAs(t, v, w.WriteQPen)
+	case QMetaTypeQTransform:
+		if v == nil {
+			return w.WriteQTransform(IdentityTransform())
+		}
+		return writeAs(t, v, w.WriteQTransform)
+	case QMetaTypeQMatrix:
+		if v == nil {
+			return w.WriteQMatrix(IdentityMatrix())
+		}
+		return writeAs(t, v, w.WriteQMatrix)
+	case QMetaTypeQMatrix4x4:
+		if v == nil {
+			return w.WriteQMatrix4x4(IdentityMatrix4x4())
+		}
+		return writeAs(t, v, w.WriteQMatrix4x4)
+	case QMetaTypeQVector2D:
+		return writeAs(t, v, w.WriteQVector2D)
+	case QMetaTypeQVector3D:
+		return writeAs(t, v, w.WriteQVector3D)
+	case QMetaTypeQVector4D:
+		return writeAs(t, v, w.WriteQVector4D)
+	case QMetaTypeQQuaternion:
+		if v == nil {
+			return w.WriteQQuaternion(IdentityQuaternion())
+		}
+		return writeAs(t, v, w.WriteQQuaternion)
+	case QMetaTypeQPolygon:
+		return writeAs(t, v, w.WriteQPolygon)
+	case QMetaTypeQPolygonF:
+		return writeAs(t, v, w.WriteQPolygonF)
+	case QMetaTypeQRegion:
+		return writeAs(t, v, w.WriteQRegion)
+	case QMetaTypeQLocale:
+		return writeAs(t, v, w.WriteQLocale)
+	case QMetaTypeQRegExp:
+		return writeAs(t, v, w.WriteQRegExp)
+	case QMetaTypeQRegularExpression:
+		return writeAs(t, v, w.WriteQRegularExpression)
+	case QMetaTypeQEasingCurve:
+		return writeAs(t, v, w.WriteQEasingCurve)
+	case QMetaTypeQImage:
+		return writeAs(t, v, w.WriteQImage)
+	case QMetaTypeQPixmap, QMetaTypeQBitmap:
+		return writeAs(t, v, w.WriteQPixmap)
+	case QMetaTypeQKeySequence:
+		return writeAs(t, v, w.WriteQKeySequence)
+	case QMetaTypeQCursor:
+		return writeAs(t, v, w.WriteQCursor)
+	case QMetaTypeQSizePolicy:
+		return writeAs(t, v, w.WriteQSizePolicy)
+	case QMetaTypeQTextLength:
+		return writeAs(t, v, w.WriteQTextLength)
+	case QMetaTypeQTextFormat:
+		if v == nil {
+			return w.WriteQTextFormat(QTextFormat{Type: InvalidTextFormat})
+		}
+		return writeAs(t, v, w.WriteQTextFormat)
+	case QMetaTypeQPalette:
+		return writeAs(t, v, w.WriteQPalette)
+	case QMetaTypeQIcon:
+		return writeAs(t, v, w.WriteQIcon)
+	case QMetaTypeUser:
+		return w.writeUserType(v)
+	default:
+		return fmt.Errorf("unimplemented type %d", t)
+	}
+}
+
+func writeAs[T any](t QMetaType, v interface{}, write func(T) error) error {
+	var value T
+	if v != nil {
+		var ok bool
+		value, ok = v.(T)
+		if !ok {
+			return fmt.Errorf("can't write %T as type %d", v, t)
+		}
+	}
+	return write(value)
+}
+
+// MetaTypeOf deduces the metatype used to write the value inside a container
+func MetaTypeOf(v interface{}) (QMetaType, error) {
+	switch v.(type) {
+	case bool:
+		return QMetaTypeBool, nil
+	case int32:
+		return QMetaTypeInt, nil
+	case uint32:
+		return QMetaTypeUInt, nil
+	case int64:
+		return QMetaTypeLongLong, nil
+	case uint64:
+		return QMetaTypeULongLong, nil
+	case float64:
+		return QMetaTypeDouble, nil
+	case float32:
+		return QMetaTypeFloat, nil
+	case int8:
+		return QMetaTypeSChar, nil
+	case uint8:
+		return QMetaTypeUChar, nil
+	case int16:
+		return QMetaTypeShort, nil
+	case uint16:
+		return QMetaTypeUShort, nil
+	case []bool:
+		return QMetaTypeQBitArray, nil
+	case map[string]interface{}, OrderedMap:
+		return QMetaTypeQVariantMap, nil
+	case OrderedHash:
+		return QMetaTypeQVariantHash, nil
+	case []interface{}:
+		return QMetaTypeQVariantList, nil
+	case []byte:
+		return QMetaTypeQByteArray, nil
+	case [][]byte:
+		return QMetaTypeQByteArrayList, nil
+	case QChar:
+		return QMetaTypeQChar, nil
+	case QUuid:
+		return QMetaTypeQUuid, nil
+	case QVersionNumber, QTimeZone:
+		return QMetaTypeUser, nil
+	case string, NullQString:
+		return QMetaTypeQString, nil
+	case []string:
+		return QMetaTypeQStringList, nil
+	case time.Time:
+		return QMetaTypeQDateTime, nil
+	case time.Duration:
+		return QMetaTypeQTime, nil
+	case *url.URL:
+		return QMetaTypeQUrl, nil
+	case QPoint:
+		return QMetaTypeQPoint, nil
+	case QPointF:
+		return QMetaTypeQPointF, nil
+	case QSize:
+		return QMetaTypeQSize, nil
+	case QSizeF:
+		return QMetaTypeQSizeF, nil
+	case QRect:
+		return QMetaTypeQRect, nil
+	case QRectF:
+		return QMetaTypeQRectF, nil
+	case QLine:
+		return QMetaTypeQLine, nil
+	case QLineF:
+		return QMetaTypeQLineF, nil
+	case QFont:
+		return QMetaTypeQFont, nil
+	case QBrush:
+		return QMetaTypeQBrush, nil
+	case QColor:
+		return QMetaTypeQColor, nil
+	case QPen:
+		return QMetaTypeQPen, nil
+	case QTransform:
+		return QMetaTypeQTransform, nil
+	case QMatrix:
+		return QMetaTypeQMatrix, nil
+	case QMatrix4x4:
+		return QMetaTypeQMatrix4x4, nil
+	case QVector2D:
+		return QMetaTypeQVector2D, nil
+	case QVector3D:
+		return QMetaTypeQVector3D, nil
+	case QVector4D:
+		return QMetaTypeQVector4D, nil
+	case QQuaternion:
+		return QMetaTypeQQuaternion, nil
+	case QPolygon:
+		return QMetaTypeQPolygon, nil
+	case QPolygonF:
+		return QMetaTypeQPolygonF, nil
+	case QRegion:
+		return QMetaTypeQRegion, nil
+	case QLocale:
+		return QMetaTypeQLocale, nil
+	case QRegExp:
+		return QMetaTypeQRegExp, nil
+	case QRegularExpression:
+		return QMetaTypeQRegularExpression, nil
+	case QEasingCurve:
+		return QMetaTypeQEasingCurve, nil
+	case QKeySequence:
+		return QMetaTypeQKeySequence, nil
+	case QCursor:
+		return QMetaTypeQCursor, nil
+	case QSizePolicy:
+		return QMetaTypeQSizePolicy, nil
+	case QTextLength:
+		return QMetaTypeQTextLength, nil
+	case QTextFormat:
+		return QMetaTypeQTextFormat, nil
+	case QPalette:
+		return QMetaTypeQPalette, nil
+	case QIcon:
+		return QMetaTypeQIcon, nil
+	case image.Image:
+		return QMetaTypeQImage, nil
+	default:
+		return 0, fmt.Errorf("can't deduce metatype for %T", v)
+	}
+}
+
+// writeContainedQVariant writes a container element as a QVariant.
+// The metatype is taken from Variant values and deduced for the others
+func (w *Writer) writeContainedQVariant(v interface{}) error {
+	if variant, ok := v.(Variant); ok {
+		return w.WriteQVariant(variant.Type, variant.Value)
+	}
+	if v == nil {
+		return w.WriteQVariant(QMetaTypeUnknown, nil)
+	}
+	t, err := MetaTypeOf(v)
+	if err != nil {
+		return err
+	}
+	return w.WriteQVariant(t, v)
+}
+
+func (w *Writer) WriteQStringQVariantList(v []interface{}) error {
+	if err := w.WriteUint32(uint32(len(v))); err != nil {
+		return err
+	}
+	for _, e := range v {
+		if err := w.writeContainedQVariant(e); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (w *Writer) WriteQStringQStringList(v []string) error {
+	if err := w.WriteUint32(uint32(len(v))); err != nil {
+		return err
+	}
+	for _, e := range v {
+		if err := w.WriteQString(e); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// WriteQStringQVariantAssociative writes a QVariantMap. Keys are written in the QMap order:
+// descending for Qt 5 streams and ascending since Qt 6
+func (w *Writer) WriteQStringQVariantAssociative(v map[string]interface{}) error {
+	if err := w.WriteUint32(uint32(len(v))); err != nil {
+		return err
+	}
+	for _, k := range w.qMapKeyOrder(v) {
+		if err := w.WriteQString(k); err != nil {
+			return err
+		}
+		if err := w.writeContainedQVariant(v[k]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// qMapKeyOrder returns the keys of the map in the order QMap serializes them
+func (w *Writer) qMapKeyOrder(m map[string]interface{}) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	descending := w.version < VersionQt6_0
+	sort.Slice(keys, func(i, j int) bool {
+		return lessQString(keys[i], keys[j]) != descending
+	})
+	return keys
+}
+
+// lessQString compares strings the way QString does, i.e. by UTF-16 code units
+func lessQString(a, b string) bool {
+	ua, ub := utf16.Encode([]rune(a)), utf16.Encode([]rune(b))
+	for i := 0; i < len(ua) && i < len(ub); i++ {
+		if ua[i] != ub[i] {
+			return ua[i] < ub[i]
+		}
+	}
+	return len(ua) < len(ub)
+}
@@ -0,0 +1,286 @@
+package cutestream
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"net/url"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWriteIntegerNumbers(t *testing.T) {
+	j, err := readFile("generated_int.json")
+	assert.Nil(t, err)
+
+	type IntegerNumberData struct {
+		Serialized string `json:"serialized"`
+		Value      string `json:"value"`
+	}
+
+	var data map[string]map[string][]IntegerNumberData
+	err = json.Unmarshal(j, &data)
+	assert.Nil(t, err)
+
+	for version, versionData := range data {
+		v, err := strconv.ParseInt(version, 10, 32)
+		assert.Nil(t, err)
+		for dataType, numbers := range versionData {
+			for _, n := range numbers {
+				var buf bytes.Buffer
+				writer, err := NewWriterWithVersion(&buf, int(v))
+				assert.Nil(t, err)
+				signed, _ := strconv.ParseInt(n.Value, 10, 64)
+				unsigned, _ := strconv.ParseUint(n.Value, 10, 64)
+				switch dataType {
+				case "int8":
+					err = writer.WriteInt8(int8(signed))
+				case "uint8":
+					err = writer.WriteUint8(uint8(unsigned))
+				case "int16":
+					err = writer.WriteInt16(int16(signed))
+				case "uint16":
+					err = writer.WriteUint16(uint16(unsigned))
+				case "int32":
+					err = writer.WriteInt32(int32(signed))
+				case "uint32":
+					err = writer.WriteUint32(uint32(unsigned))
+				case "int64":
+					err = writer.WriteInt64(signed)
+				case "uint64":
+					err = writer.WriteUint64(unsigned)
+				default:
+					t.Fatalf("Unsupported data type: %s", dataType)
+				}
+				assert.Nil(t, err)
+				assert.Equal(t, n.Serialized, base64.StdEncoding.EncodeToString(buf.Bytes()))
+			}
+		}
+	}
+}
+
+func TestWriteDateTime(t *testing.T) {
+	j, err := readFile("generated_datetime.json")
+	assert.Nil(t, err)
+
+	type Data struct {
+		Datetimes []struct {
+			Serialized string `json:"serialized"`
+		} `json:"datetime"`
+	}
+
+	var data map[string]Data
+	err = json.Unmarshal(j, &data)
+	assert.Nil(t, err)
+
+	for version, versionData := range data {
+		v, err := strconv.ParseInt(version, 10, 32)
+		assert.Nil(t, err)
+		for _, d := range versionData.Datetimes {
+			b, err := base64.StdEncoding.DecodeString(d.Serialized)
+			assert.Nil(t, err)
+			reader, err := NewReaderWithVersion(bytes.NewReader(b), int(v))
+			assert.Nil(t, err)
+			datetime, err := reader.ReadQDateTime()
+			assert.Nil(t, err)
+
+			var buf bytes.Buffer
+			writer, err := NewWriterWithVersion(&buf, int(v))
+			assert.Nil(t, err)
+			assert.Nil(t, writer.WriteQDateTime(datetime))
+			assert.Equal(t, b, buf.Bytes())
+		}
+	}
+}
+
+func TestWriteUuid(t *testing.T) {
+	j, err := readFile("generated_uuid.json")
+	assert.Nil(t, err)
+
+	var data map[string]struct {
+		Uuid []struct {
+			Serialized string `json:"serialized"`
+			Value      string `json:"value"`
+		} `json:"uuid"`
+	}
+	err = json.Unmarshal(j, &data)
+	assert.Nil(t, err)
+	for _, versionData := range data {
+		for _, u := range versionData.Uuid {
+			var buf bytes.Buffer
+			writer := NewWriter(&buf)
+			uuid, err := ParseQUuid(u.Value)
+			assert.Nil(t, err)
+			assert.Nil(t, writer.WriteQUuid(uuid))
+			assert.Equal(t, u.Serialized, base64.StdEncoding.EncodeToString(buf.Bytes()))
+		}
+	}
+}
+
+func TestWriteQVariantRoundTrip(t *testing.T) {
+	u, _ := url.Parse("https://example.com/lap?id=1")
+	values := []struct {
+		t QMetaType
+		v interface{}
+	}{
+		{QMetaTypeBool, true},
+		{QMetaTypeInt, int32(-42)},
+		{QMetaTypeUInt, uint32(42)},
+		{QMetaTypeLongLong, int64(-1) << 40},
+		{QMetaTypeULongLong, uint64(1) << 40},
+		{QMetaTypeDouble, 0.5},
+		{QMetaTypeFloat, float32(1.5)},
+		{QMetaTypeShort, int16(-3)},
+		{QMetaTypeUShort, uint16(3)},
+		{QMetaTypeQBitArray, []bool{true, false, true, true, false, false, false, false, true}},
+		{QMetaTypeQByteArray, []byte{1, 2, 3}},
+		{QMetaTypeQString, "Spa-Francorchamps 🏁"},
+		{QMetaTypeQStringList, []string{"a", "", "c"}},
+		{QMetaTypeQDate, time.Date(2023, 5, 21, 0, 0, 0, 0, time.UTC)},
+		{QMetaTypeQTime, 3*time.Hour + 12*time.Millisecond},
+		{QMetaTypeQDateTime, time.Date(2023, 5, 21, 14, 3, 1, 5e6, time.FixedZone("", 7200))},
+		{QMetaTypeQUrl, u},
+		{QMetaTypeQUuid, QUuid{0x17, 0x4f, 0xef, 0x9c, 0x21, 0xf6, 0x43, 0x95, 0x98, 0xe4, 0x76, 0xaf, 0xea, 0xef, 0x09, 0x03}},
+		{QMetaTypeQPoint, QPoint{-1, 2}},
+		{QMetaTypeQPointF, QPointF{0.5, -2.25}},
+		{QMetaTypeQSize, QSize{640, 480}},
+		{QMetaTypeQSizeF, QSizeF{1.5, 2}},
+		{QMetaTypeQRect, QRect{10, 20, 30, 40}},
+		{QMetaTypeQRectF, QRectF{0.5, 1, 2.5, 4}},
+		{QMetaTypeQLine, QLine{QPoint{1, 2}, QPoint{3, 4}}},
+		{QMetaTypeQLineF, QLineF{QPointF{1, 2}, QPointF{3, 4.5}}},
+		{QMetaTypeQVariantList, []interface{}{int32(1), "two", nil, []interface{}{3.0}}},
+		{QMetaTypeQVariantMap, map[string]interface{}{"b": int32(1), "a": map[string]interface{}{"c": "d"}}},
+	}
+	for _, version := range []int{19, 20} {
+		for _, precision := range []bool{false, true} {
+			var buf bytes.Buffer
+			writer, err := NewWriterWithVersion(&buf, version)
+			assert.Nil(t, err)
+			writer.DoublePrecision = precision
+			for _, v := range values {
+				assert.Nil(t, writer.WriteQVariant(v.t, v.v), "type %d", v.t)
+			}
+			reader, err := NewReaderWithVersion(&buf, version)
+			assert.Nil(t, err)
+			reader.DoublePrecision = precision
+			for _, v := range values {
+				readType, readValue, err := reader.ReadQVariant()
+				assert.Nil(t, err)
+				assert.Equal(t, v.t, readType)
+				if expected, ok := v.v.(time.Time); ok {
+					assert.True(t, expected.Equal(readValue.(time.Time)), "%v != %v", expected, readValue)
+				} else {
+					assert.Equal(t, v.v, readValue, "type %d", v.t)
+				}
+			}
+			assert.Equal(t, 0, buf.Len())
+		}
+	}
+}
+
+func TestWriteQVariantNull(t *testing.T) {
+	var buf bytes.Buffer
+	writer := NewWriter(&buf)
+	assert.Nil(t, writer.WriteQVariant(QMetaTypeQString, nil))
+	assert.Nil(t, writer.WriteQVariant(QMetaTypeInt, int32(7)))
+
+	reader := NewReader(&buf)
+	readType, v, err := reader.ReadQVariant()
+	assert.Nil(t, err)
+	assert.Equal(t, QMetaTypeQString, readType)
+	assert.Nil(t, v)
+	_, v, err = reader.ReadQVariant()
+	assert.Nil(t, err)
+	assert.Equal(t, int32(7), v)
+}
+
+func TestWriteQVariantMapOrder(t *testing.T) {
+	m := map[string]interface{}{"a": int32(1), "b": int32(2)}
+	var qt5, qt6 bytes.Buffer
+	writer := NewWriter(&qt5)
+	assert.Nil(t, writer.WriteQStringQVariantAssociative(m))
+	writer, err := NewWriterWithVersion(&qt6, 20)
+	assert.Nil(t, err)
+	assert.Nil(t, writer.WriteQStringQVariantAssociative(m))
+	// first key follows the element count
+	assert.Equal(t, []byte{0, 'b'}, qt5.Bytes()[8:10])
+	assert.Equal(t, []byte{0, 'a'}, qt6.Bytes()[8:10])
+}
+
+func TestWriteValueTypeMismatch(t *testing.T) {
+	writer := NewWriter(&bytes.Buffer{})
+	assert.NotNil(t, writer.WriteValue(QMetaTypeInt, "42"))
+	assert.NotNil(t, writer.WriteValue(QMetaTypeQModelIndex, nil))
+}
+
+func TestQt4Version(t *testing.T) {
+	var buf bytes.Buffer
+	writer, err := NewWriterWithVersion(&buf, VersionQt4_0)
+	assert.Nil(t, err)
+	assert.Nil(t, writer.WriteQVariant(QMetaTypeFloat, float32(0.5)))
+	assert.Nil(t, writer.WriteQVariant(QMetaTypeQDate, time.Date(2023, 5, 21, 0, 0, 0, 0, time.UTC)))
+	assert.Nil(t, writer.WriteQVariant(QMetaTypeDouble, 0.25))
+	// Qt 4 metatype id, no null flag, native float size
+	assert.Equal(t, []byte{0, 0, 0, 135, 0x3f, 0, 0, 0}, buf.Bytes()[:8])
+
+	reader, err := NewReaderWithVersion(&buf, VersionQt4_0)
+	assert.Nil(t, err)
+	readType, v, err := reader.ReadQVariant()
+	assert.Nil(t, err)
+	assert.Equal(t, QMetaTypeFloat, readType)
+	assert.Equal(t, float32(0.5), v)
+	readType, v, err = reader.ReadQVariant()
+	assert.Nil(t, err)
+	assert.Equal(t, QMetaTypeQDate, readType)
+	assert.Equal(t, time.Date(2023, 5, 21, 0, 0, 0, 0, time.UTC), v)
+	_, v, err = reader.ReadQVariant()
+	assert.Nil(t, err)
+	assert.Equal(t, 0.25, v)
+	assert.Equal(t, 0, buf.Len())
+
+	_, err = NewReaderWithVersion(&buf, 6)
+	assert.NotNil(t, err)
+}
+
+func TestListRoundTrip(t *testing.T) {
+	var buf bytes.Buffer
+	writer := NewWriter(&buf)
+	assert.Nil(t, WriteList(&writer, []int32{3, -1, 7}, (*Writer).WriteInt32))
+	assert.Nil(t, WriteList(&writer, []string{}, (*Writer).WriteQString))
+	assert.Equal(t, []byte{0, 0, 0, 3, 0, 0, 0, 3, 0xff, 0xff, 0xff, 0xff, 0, 0, 0, 7, 0, 0, 0, 0}, buf.Bytes())
+
+	reader := NewReader(&buf)
+	ints, err := ReadList(&reader, (*Reader).ReadInt32)
+	assert.Nil(t, err)
+	assert.Equal(t, []int32{3, -1, 7}, ints)
+	strs, err := ReadList(&reader, (*Reader).ReadQString)
+	assert.Nil(t, err)
+	assert.Empty(t, strs)
+}
+
+func TestWriteQBitArray(t *testing.T) {
+	var buf bytes.Buffer
+	writer := NewWriter(&buf)
+	// QBitArray stores the first bit in the least significant bit of the first byte
+	assert.Nil(t, writer.WriteQBitArray([]bool{true, false, true, true, false, false, false, false, true}))
+	assert.Equal(t, []byte{0, 0, 0, 9, 0x0d, 0x01}, buf.Bytes())
+}
+
+func TestMapRoundTrip(t *testing.T) {
+	m := map[int32]string{2: "b", 1: "a"}
+	less := func(a, b int32) bool { return a < b }
+	var buf bytes.Buffer
+	writer := NewWriter(&buf)
+	assert.Nil(t, WriteMap(&writer, m, less, (*Writer).WriteInt32, (*Writer).WriteQString))
+	// descending keys before Qt 6
+	assert.Equal(t, []byte{0, 0, 0, 2, 0, 0, 0, 2}, buf.Bytes()[:8])
+
+	reader := NewReader(&buf)
+	read, err := ReadMap(&reader, (*Reader).ReadInt32, (*Reader).ReadQString)
+	assert.Nil(t, err)
+	assert.Equal(t, m, read)
+}